@@ -0,0 +1,59 @@
+package jsonlog
+
+import "errors"
+
+// ErrAssertionFailed is the error of the messages of failed assertions which
+// have no error of their own.
+var ErrAssertionFailed = errors.New("jsonlog: assertion failed")
+
+// WithAssertPanics returns a new Logger whose failed assertions panic after
+// being logged, so that invariant violations cannot go unnoticed during
+// development. Development enables it.
+func (l Logger) WithAssertPanics(enabled bool) Logger {
+	l.assertPanics = enabled
+	return l
+}
+
+// AssertTrue reports an invariant violation if `cond' is false: it logs
+// `str' at the error level with ErrAssertionFailed, the caller and the stack,
+// then panics if the Logger was built WithAssertPanics. It returns `cond', so
+// that the caller can bail out:
+//
+//	if !logger.AssertTrue(len(items) > 0, "empty order", order) {
+//		return
+//	}
+func (l Logger) AssertTrue(cond bool, str string, data interface{}) bool {
+	if !cond {
+		l.failAssertion(ErrAssertionFailed, str, data)
+	}
+	return cond
+}
+
+// AssertNoError is like AssertTrue for an error which should never happen:
+// the violation is reported with `err' as the error of the message. It
+// returns whether `err' is nil.
+func (l Logger) AssertNoError(err error, str string, data interface{}) bool {
+	if err != nil {
+		l.failAssertion(err, str, data)
+	}
+	return err == nil
+}
+
+// AssertTrue is a shorthand for asserting on default logger.
+func AssertTrue(cond bool, str string, data interface{}) bool {
+	return checkedDefaultLogger().AssertTrue(cond, str, data)
+}
+
+// AssertNoError is a shorthand for asserting on default logger.
+func AssertNoError(err error, str string, data interface{}) bool {
+	return checkedDefaultLogger().AssertNoError(err, str, data)
+}
+
+// failAssertion logs a failed assertion and panics if required.
+func (l Logger) failAssertion(err error, str string, data interface{}) {
+	l.WithError(err).WithCaller(true).WithStackTrace(true).Error(str, data)
+	if l.assertPanics {
+		l.flush()
+		panic(str)
+	}
+}
@@ -0,0 +1,59 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestAssertTrue tests that failed assertions are logged with their error,
+// caller and stack, and that passed ones log nothing.
+func TestAssertTrue(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer)
+	if !logger.AssertTrue(true, "holds", nil) || !logger.AssertNoError(nil, "no error", nil) {
+		t.Errorf("Passed assertions should return true.")
+	}
+	if buffer.Len() > 0 {
+		t.Errorf("Passed assertions should log nothing, got '%s'.", buffer.String())
+	}
+	if logger.AssertTrue(false, "empty order", map[string]int{"items": 0}) {
+		t.Errorf("A failed assertion should return false.")
+	}
+	var record struct {
+		Level   string                 `json:"level"`
+		Context map[string]interface{} `json:"context"`
+		Error   ErrorInfo              `json:"error"`
+		Stack   string                 `json:"stack"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+		t.Fatalf("Decoding '%s' errored with '%s'.", buffer.String(), err.Error())
+	}
+	caller, _ := record.Context[callerKey].(string)
+	if record.Level != "error" || record.Error.Message != ErrAssertionFailed.Error() || !strings.Contains(caller, "assert_test.go") {
+		t.Errorf("Record '%s' should have the error level, error and caller.", buffer.String())
+	}
+	if !strings.Contains(record.Stack, "TestAssertTrue") {
+		t.Errorf("The stack '%s' should start at the test.", record.Stack)
+	}
+	buffer.Reset()
+	if logger.AssertNoError(errors.New("unreachable"), "impossible", nil) || !strings.Contains(buffer.String(), "unreachable") {
+		t.Errorf("AssertNoError should log its error, got '%s'.", buffer.String())
+	}
+}
+
+// TestAssertPanics tests that failed assertions panic when required.
+func TestAssertPanics(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	defer func() {
+		if recovered := recover(); recovered != "broken invariant" {
+			t.Errorf("The assertion should have panicked with its message, got %v.", recovered)
+		}
+		if !strings.Contains(buffer.String(), "broken invariant") {
+			t.Errorf("The assertion should have been logged before panicking.")
+		}
+	}()
+	DefaultLogger.WithWriter(buffer).WithAssertPanics(true).AssertTrue(false, "broken invariant", nil)
+}
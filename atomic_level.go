@@ -0,0 +1,78 @@
+package jsonlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// AtomicLevel is a log level which can safely be changed while Loggers bound
+// to it are in use, letting operators raise or lower verbosity at runtime.
+// Bind a Logger to it with WithAtomicLevel.
+type AtomicLevel struct {
+	logLevel uint64
+}
+
+// atomicLevelPayload is the JSON document AtomicLevel's HTTP handler reads and
+// writes.
+type atomicLevelPayload struct {
+	Level string `json:"level"`
+}
+
+// atomicLevelError is the JSON document AtomicLevel's HTTP handler writes when
+// a request is rejected.
+type atomicLevelError struct {
+	Error string `json:"error"`
+}
+
+// NewAtomicLevel creates an AtomicLevel initially set to `logLevel'.
+func NewAtomicLevel(logLevel LogLevel) *AtomicLevel {
+	a := &AtomicLevel{}
+	a.SetLevel(logLevel)
+	return a
+}
+
+// Level returns the current log level.
+func (a *AtomicLevel) Level() LogLevel {
+	return LogLevel(atomic.LoadUint64(&a.logLevel))
+}
+
+// SetLevel changes the log level. The change is seen immediately by all bound
+// Loggers.
+func (a *AtomicLevel) SetLevel(logLevel LogLevel) {
+	atomic.StoreUint64(&a.logLevel, uint64(logLevel))
+}
+
+// ServeHTTP lets the AtomicLevel be mounted as an http.Handler. A GET request
+// returns the current level as {"level":"info"}; a PUT request with a body of
+// the same shape changes it and returns the new level.
+func (a *AtomicLevel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var payload atomicLevelPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeAtomicLevelError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logLevel, err := ParseLogLevel(payload.Level)
+		if err != nil {
+			writeAtomicLevelError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		a.SetLevel(logLevel)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeAtomicLevelError(w, http.StatusMethodNotAllowed, "only GET and PUT are supported")
+		return
+	}
+	json.NewEncoder(w).Encode(atomicLevelPayload{logLevelNames[a.Level()]})
+}
+
+// writeAtomicLevelError writes an error response for AtomicLevel's HTTP
+// handler.
+func writeAtomicLevelError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(atomicLevelError{message})
+}
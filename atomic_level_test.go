@@ -0,0 +1,71 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestAtomicLevel tests that a bound Logger follows changes to its
+// AtomicLevel.
+func TestAtomicLevel(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	atomicLevel := NewAtomicLevel(LogLevelWarning)
+	logger := DefaultLogger.WithWriter(buffer).WithAtomicLevel(atomicLevel)
+	logger.Info("filtered", nil)
+	if buffer.Len() != 0 {
+		t.Errorf("Info message should have been filtered out.")
+	}
+	atomicLevel.SetLevel(LogLevelDebug)
+	logger.Debug("logged", nil)
+	if buffer.Len() == 0 {
+		t.Errorf("Debug message should have been logged after lowering the level.")
+	}
+	if logger.WithLogLevel(LogLevelError).atomicLevel != nil {
+		t.Errorf("WithLogLevel should unbind the AtomicLevel.")
+	}
+}
+
+type testAtomicLevelHTTPExample struct {
+	method   string
+	body     string
+	status   int
+	expected LogLevel
+}
+
+// TestAtomicLevelHTTP tests reading and changing an AtomicLevel over HTTP.
+func TestAtomicLevelHTTP(t *testing.T) {
+	examples := []testAtomicLevelHTTPExample{
+		testAtomicLevelHTTPExample{http.MethodGet, "", http.StatusOK, LogLevelInfo},
+		testAtomicLevelHTTPExample{http.MethodPut, `{"level":"debug"}`, http.StatusOK, LogLevelDebug},
+		testAtomicLevelHTTPExample{http.MethodPut, `{"level":"WARN"}`, http.StatusOK, LogLevelWarning},
+		testAtomicLevelHTTPExample{http.MethodPut, `{"level":"debug"}`, http.StatusOK, LogLevelDebug},
+		testAtomicLevelHTTPExample{http.MethodPut, `{"level":"verbose"}`, http.StatusBadRequest, LogLevelDebug},
+		testAtomicLevelHTTPExample{http.MethodPut, `not json`, http.StatusBadRequest, LogLevelDebug},
+		testAtomicLevelHTTPExample{http.MethodDelete, "", http.StatusMethodNotAllowed, LogLevelDebug},
+	}
+	atomicLevel := NewAtomicLevel(LogLevelInfo)
+	for _, example := range examples {
+		request := httptest.NewRequest(example.method, "/level", strings.NewReader(example.body))
+		recorder := httptest.NewRecorder()
+		atomicLevel.ServeHTTP(recorder, request)
+		if recorder.Code != example.status {
+			t.Errorf("%s %s returned status %d but should be %d.", example.method, example.body, recorder.Code, example.status)
+		}
+		if atomicLevel.Level() != example.expected {
+			t.Errorf("Level is %v but should be %v.", atomicLevel.Level(), example.expected)
+		}
+		if recorder.Code == http.StatusOK {
+			output := atomicLevelPayload{}
+			err := json.Unmarshal(recorder.Body.Bytes(), &output)
+			if err != nil {
+				t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+			} else if output.Level != logLevelNames[example.expected] {
+				t.Errorf("Output level '%s' should be '%s'.", output.Level, logLevelNames[example.expected])
+			}
+		}
+	}
+}
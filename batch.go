@@ -0,0 +1,167 @@
+package jsonlog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrSinkClosed is returned when writing to a sink which was closed.
+var ErrSinkClosed = errors.New("jsonlog: sink closed")
+
+// BatchingSink accumulates records and writes them to an underlying writer in
+// batches, so that sinks with a high cost per write, such as network bulk
+// endpoints, receive fewer and larger writes. A batch is written when adding
+// a record would make it exceed the size threshold, when the flush interval
+// elapses, and on Flush and Close. Records are kept whole: a writer
+// implementing BatchWriter, such as a PipelinedSink or an HTTPSink, receives
+// the records of each batch, other writers receive them newline-delimited.
+//
+// Since it implements `Flush() error', a BatchingSink is flushed by Fatal
+// before the process exits and by a WriterChain it is part of.
+//
+// A BatchingSink is safe for concurrent use.
+type BatchingSink struct {
+	w        io.Writer
+	maxBytes int
+	mutex    sync.Mutex
+	// records are those of the current batch, without their newline, and
+	// size is the size of the batch once newline-delimited.
+	records [][]byte
+	size    int
+	// err is the error of the last background flush, returned by the next
+	// call to Flush or Close.
+	err    error
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewBatchingSink creates a BatchingSink writing batches of at most
+// `maxBytes' to `w', or single records if they are larger than that, at least
+// every `interval'. A non-positive `maxBytes' disables the size threshold and
+// a non-positive `interval' disables periodic flushes.
+func NewBatchingSink(w io.Writer, maxBytes int, interval time.Duration) *BatchingSink {
+	s := &BatchingSink{
+		w:        w,
+		maxBytes: maxBytes,
+	}
+	if interval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.flushPeriodically(interval)
+	}
+	return s
+}
+
+// Write adds `p' to the current batch as a single record, without its
+// trailing newline.
+func (s *BatchingSink) Write(p []byte) (int, error) {
+	if err := s.WriteRecord(bytes.TrimSuffix(p, []byte("\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteRecord adds a copy of `record' to the current batch, implementing
+// RecordWriter. The error of the flush it may trigger is returned; the records
+// of a batch which failed to be written are dropped.
+func (s *BatchingSink) WriteRecord(record []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+	size := len(record) + 1
+	if s.maxBytes > 0 && s.size > 0 && s.size+size > s.maxBytes {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	if s.maxBytes > 0 && size >= s.maxBytes {
+		return writeBatch(s.w, [][]byte{record})
+	}
+	s.records = append(s.records, append([]byte(nil), record...))
+	s.size += size
+	return nil
+}
+
+// Flush writes the current batch, then flushes the underlying writer if it
+// implements `Flush() error', such as a PipelinedSink. It also returns the
+// error of a failed periodic flush if there was one since the last call.
+func (s *BatchingSink) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	err := s.flushAndReport()
+	if f, ok := s.w.(flusher); ok {
+		if flushErr := f.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	return err
+}
+
+// Close stops the periodic flushes and writes the last batch. It does not
+// close the underlying writer. Writing to a closed BatchingSink fails with
+// ErrSinkClosed.
+func (s *BatchingSink) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mutex.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.flushAndReport()
+}
+
+// flushPeriodically flushes the batch every `interval' until the sink is
+// closed.
+func (s *BatchingSink) flushPeriodically(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mutex.Lock()
+			if err := s.flush(); err != nil {
+				s.err = err
+			}
+			s.mutex.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// flushAndReport flushes the batch and returns its error, or else that of an
+// earlier periodic flush. The mutex must be held.
+func (s *BatchingSink) flushAndReport() error {
+	err := s.flush()
+	if err == nil {
+		err = s.err
+	}
+	s.err = nil
+	return err
+}
+
+// flush writes the batch to the underlying writer and empties it. The mutex
+// must be held.
+func (s *BatchingSink) flush() error {
+	if len(s.records) == 0 {
+		return nil
+	}
+	err := writeBatch(s.w, s.records)
+	s.records = nil
+	s.size = 0
+	return err
+}
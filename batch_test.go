@@ -0,0 +1,112 @@
+package jsonlog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeRecorder records every write it receives.
+type writeRecorder struct {
+	mutex  sync.Mutex
+	writes []string
+}
+
+func (r *writeRecorder) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+// records counts the records written so far.
+func (r *writeRecorder) records() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return strings.Count(strings.Join(r.writes, ""), "\n")
+}
+
+type testBatchingSinkExample struct {
+	maxBytes       int
+	records        []string
+	expectedWrites []string
+}
+
+var testBatchingSinkExamples = []testBatchingSinkExample{
+	{
+		maxBytes:       0,
+		records:        []string{"a\n", "b\n", "c\n"},
+		expectedWrites: []string{"a\nb\nc\n"},
+	},
+	{
+		maxBytes:       4,
+		records:        []string{"a\n", "b\n", "c\n"},
+		expectedWrites: []string{"a\nb\n", "c\n"},
+	},
+	{
+		maxBytes:       4,
+		records:        []string{"a\n", "large\n", "b\n"},
+		expectedWrites: []string{"a\n", "large\n", "b\n"},
+	},
+}
+
+// TestBatchingSink tests that records are grouped according to the size
+// threshold.
+func TestBatchingSink(t *testing.T) {
+	for _, example := range testBatchingSinkExamples {
+		recorder := &writeRecorder{}
+		sink := NewBatchingSink(recorder, example.maxBytes, 0)
+		for _, record := range example.records {
+			if _, err := sink.Write([]byte(record)); err != nil {
+				t.Errorf("Writing errored with '%s'.", err.Error())
+			}
+		}
+		if err := sink.Close(); err != nil {
+			t.Errorf("Closing errored with '%s'.", err.Error())
+		}
+		if strings.Join(recorder.writes, "|") != strings.Join(example.expectedWrites, "|") {
+			t.Errorf("Writes %q should be %q.", recorder.writes, example.expectedWrites)
+		}
+		if _, err := sink.Write([]byte("late\n")); err != ErrSinkClosed {
+			t.Errorf("Writing to a closed sink should fail with ErrSinkClosed, got %v.", err)
+		}
+	}
+}
+
+// TestBatchingSinkInterval tests that batches are written periodically.
+func TestBatchingSinkInterval(t *testing.T) {
+	recorder := &writeRecorder{}
+	sink := NewBatchingSink(recorder, 1024, 10*time.Millisecond)
+	defer sink.Close()
+	logger := DefaultLogger.WithWriter(sink)
+	logger.Info("first", nil)
+	logger.Info("second", nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for recorder.records() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if recorder.records() != 2 {
+		t.Errorf("Both records should have been written before the sink was closed.")
+	}
+}
+
+// TestBatchingSinkFlushError tests that the error of a periodic flush is
+// reported by the next Flush.
+func TestBatchingSinkFlushError(t *testing.T) {
+	sink := NewBatchingSink(failingWriter{}, 1024, time.Millisecond)
+	defer sink.Close()
+	sink.Write([]byte("record\n"))
+	time.Sleep(50 * time.Millisecond)
+	if err := sink.Flush(); err == nil {
+		t.Errorf("Flushing should report the failed periodic flush.")
+	}
+	if err := sink.Flush(); err != nil {
+		t.Errorf("Flushing again errored with '%s'.", err.Error())
+	}
+	var buffer bytes.Buffer
+	if err := NewBatchingSink(&buffer, 0, 0).Flush(); err != nil {
+		t.Errorf("Flushing an empty sink errored with '%s'.", err.Error())
+	}
+}
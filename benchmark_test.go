@@ -0,0 +1,102 @@
+package jsonlog
+
+import (
+	"context"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// BenchmarkLogNoData benchmarks logging a message with neither data nor
+// context.
+func BenchmarkLogNoData(b *testing.B) {
+	logger := DefaultLogger.WithWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("A log message.", nil)
+	}
+}
+
+// BenchmarkLogWithData benchmarks logging a message with a small map as
+// data.
+func BenchmarkLogWithData(b *testing.B) {
+	logger := DefaultLogger.WithWriter(io.Discard)
+	data := map[string]interface{}{"user": "foobar", "attempt": 3}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("A log message.", data)
+	}
+}
+
+// BenchmarkLogWithContext benchmarks logging a message with a value taken
+// from the context.
+func BenchmarkLogWithContext(b *testing.B) {
+	ctx := context.WithValue(context.Background(), "requestId", "3f2a9c")
+	logger := DefaultLogger.WithWriter(io.Discard).WithContext(ctx).WithContextKey("requestId", "requestId")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("A log message.", nil)
+	}
+}
+
+// BenchmarkLogFiltered benchmarks a message filtered out by the log level.
+func BenchmarkLogFiltered(b *testing.B) {
+	logger := DefaultLogger.WithWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Debug("A log message.", nil)
+	}
+}
+
+// benchmarkWalkData is a struct holding a NaN, so that logging it goes
+// through the walker.
+type benchmarkWalkData struct {
+	User    string  `json:"user"`
+	Attempt int     `json:"attempt"`
+	Ratio   float64 `json:"ratio"`
+	Note    string  `json:"note,omitempty"`
+}
+
+// BenchmarkWalkStruct benchmarks walking a struct whose layout is cached.
+func BenchmarkWalkStruct(b *testing.B) {
+	data := benchmarkWalkData{User: "foobar", Attempt: 3, Ratio: math.NaN()}
+	w := walker{float: FloatNull.replace}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.walk(data)
+	}
+}
+
+// BenchmarkWalkStructUncached benchmarks walking a struct whose layout is
+// computed each time.
+func BenchmarkWalkStructUncached(b *testing.B) {
+	data := benchmarkWalkData{User: "foobar", Attempt: 3, Ratio: math.NaN()}
+	dataType := reflect.TypeOf(data)
+	w := walker{float: FloatNull.replace}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		structFieldsCache.Delete(dataType)
+		w.walk(data)
+	}
+}
+
+// BenchmarkLogWithPooledData benchmarks logging a message with a Data built
+// for each call.
+func BenchmarkLogWithPooledData(b *testing.B) {
+	logger := DefaultLogger.WithWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("A log message.", NewData().Set("user", "foobar").Set("attempt", i))
+	}
+}
+
+// BenchmarkLogWithFreshData benchmarks logging a message with a map built
+// for each call, for comparison with BenchmarkLogWithPooledData.
+func BenchmarkLogWithFreshData(b *testing.B) {
+	logger := DefaultLogger.WithWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("A log message.", map[string]interface{}{"user": "foobar", "attempt": i})
+	}
+}
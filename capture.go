@@ -0,0 +1,159 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// lineWriter turns each line written to it into a message.
+type lineWriter struct {
+	logger   Logger
+	logLevel LogLevel
+	// passThrough is true if lines which are jsonlog records are merged
+	// instead of being logged as text.
+	passThrough bool
+	mutex       sync.Mutex
+	pending     []byte
+}
+
+// embeddedRecord is a jsonlog record found in captured output.
+type embeddedRecord struct {
+	Level    string                 `json:"level"`
+	Time     string                 `json:"time"`
+	Message  *string                `json:"message"`
+	Logger   string                 `json:"logger"`
+	Data     interface{}            `json:"data"`
+	Context  map[string]interface{} `json:"context"`
+	Error    *ErrorInfo             `json:"error"`
+	Stack    string                 `json:"stack"`
+	Repeated int                    `json:"repeated"`
+}
+
+// Writer returns an io.WriteCloser turning each line written to it into a
+// message at the given level, so that plain-text output of other code can be
+// redirected into the Logger: log.SetOutput, http.Server.ErrorLog or any
+// library writing to an io.Writer. Lines are split on "\n", a trailing "\r" is
+// removed and empty lines are ignored. An incomplete last line is kept until
+// it is completed or the writer is closed. The writer is safe for concurrent
+// use.
+//
+//	log.SetFlags(0)
+//	log.SetOutput(logger.Writer(jsonlog.LogLevelInfo))
+func (l Logger) Writer(logLevel LogLevel) io.WriteCloser {
+	return &lineWriter{
+		logger:   l,
+		logLevel: logLevel,
+	}
+}
+
+// PassThroughWriter returns an io.WriteCloser like Writer, except that lines
+// which are already records of the default JSON format, such as the output
+// of a child process using jsonlog, are merged into the Logger's output
+// instead of being wrapped as the message of a new one. Their level, time,
+// message, data, error and stack are preserved; the Logger's context values
+// are added to theirs unless they have the same key, and their logger name is nested under the Logger's.
+// Merged records go through the Logger's level, filters and hooks, but not
+// through its sampler. Other lines are logged at `logLevel' as with Writer.
+//
+//	cmd.Stdout = logger.Named("worker").PassThroughWriter(jsonlog.LogLevelInfo)
+func (l Logger) PassThroughWriter(logLevel LogLevel) io.WriteCloser {
+	return &lineWriter{
+		logger:      l,
+		logLevel:    logLevel,
+		passThrough: true,
+	}
+}
+
+// Write logs the complete lines of `p'. The error is that of the first log
+// call which failed, if any; the whole of `p' is consumed either way.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var err error
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		if lineErr := w.logLine(w.pending[:i]); err == nil {
+			err = lineErr
+		}
+		w.pending = w.pending[i+1:]
+	}
+	if len(w.pending) == 0 {
+		w.pending = nil
+	}
+	return len(p), err
+}
+
+// Close logs the incomplete last line, if any.
+func (w *lineWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	err := w.logLine(w.pending)
+	w.pending = nil
+	return err
+}
+
+// logLine logs a single line without its line terminator.
+func (w *lineWriter) logLine(line []byte) error {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 {
+		return nil
+	}
+	if w.passThrough && line[0] == '{' {
+		if ok, err := w.logRecord(line); ok {
+			return err
+		}
+	}
+	return w.logger.Log(w.logLevel, string(line), nil)
+}
+
+// logRecord merges a line holding a jsonlog record into the Logger's output.
+// The boolean is false if the line is not such a record.
+func (w *lineWriter) logRecord(line []byte) (bool, error) {
+	var record embeddedRecord
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&record); err != nil || record.Message == nil {
+		return false, nil
+	}
+	logLevel, ok := logLevelFromName(record.Level)
+	if !ok {
+		return false, nil
+	}
+	l := w.logger
+	if !l.shouldLog(logLevel, *record.Message, record.Data) {
+		return true, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, record.Time)
+	if err != nil {
+		t = l.now()
+	} else if l.location != nil {
+		t = t.In(l.location)
+	}
+	m := l.newMessage(logLevel, t, *record.Message, record.Data)
+	if len(record.Context) > 0 {
+		for key, value := range m.Context {
+			if _, ok := record.Context[key]; !ok {
+				record.Context[key] = value
+			}
+		}
+		m.Context = record.Context
+	}
+	if record.Logger != "" {
+		m.Logger = l.Named(record.Logger).name
+	}
+	if record.Error != nil {
+		m.Error = record.Error
+	}
+	if record.Stack != "" {
+		m.Stack = record.Stack
+	}
+	m.Repeated = record.Repeated
+	return true, l.doLog(&m)
+}
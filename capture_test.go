@@ -0,0 +1,100 @@
+package jsonlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+)
+
+// TestLoggerWriter tests that lines written in several chunks become one
+// message each, and that Close flushes the last incomplete line.
+func TestLoggerWriter(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	w := DefaultLogger.WithWriter(buffer).Writer(LogLevelWarning)
+	chunks := []string{"first li", "ne\r\nsecond line\n", "\n", "third", " line"}
+	for _, chunk := range chunks {
+		if n, err := w.Write([]byte(chunk)); err != nil {
+			t.Errorf("Writing errored with '%s'.", err.Error())
+		} else if n != len(chunk) {
+			t.Errorf("Writing returned %d instead of %d.", n, len(chunk))
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Closing errored with '%s'.", err.Error())
+	}
+	expected := []string{"first line", "second line", "third line"}
+	scanner := bufio.NewScanner(buffer)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		var m Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		} else if i < len(expected) && (m.Message != expected[i] || m.Level != "warning") {
+			t.Errorf("Message %d is '%s' at level %s, should be '%s' at level warning.", i, m.Message, m.Level, expected[i])
+		}
+	}
+	if i != len(expected) {
+		t.Errorf("%d messages were logged instead of %d.", i, len(expected))
+	}
+}
+
+// TestLoggerWriterStdlib tests redirecting the standard library logger.
+func TestLoggerWriterStdlib(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	stdlibLogger := log.New(DefaultLogger.WithWriter(buffer).Writer(LogLevelError), "", 0)
+	stdlibLogger.Printf("http: TLS handshake error from %s", "10.0.0.1:5000")
+	var m Message
+	if err := json.Unmarshal(buffer.Bytes(), &m); err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+	} else if m.Message != "http: TLS handshake error from 10.0.0.1:5000" || m.Level != "error" {
+		t.Errorf("Output '%s' should hold the stdlib message at level error.", buffer.String())
+	}
+}
+
+// TestPassThroughWriter tests that embedded records are merged while plain
+// lines are wrapped.
+func TestPassThroughWriter(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	ctx := context.WithValue(context.Background(), "job", "nightly")
+	logger := DefaultLogger.WithWriter(buffer).WithContext(ctx).WithContextKey("job", "job").Named("orchestrator")
+	w := logger.PassThroughWriter(LogLevelWarning)
+	fmt.Fprintln(w, `{"level":"error","time":"2017-10-05T21:07:58.115+02:00","message":"Task failed.","logger":"worker","data":{"task":12},"context":{"job":"child","pid":42},"error":{"message":"boom","type":"*errors.errorString"}}`)
+	fmt.Fprintln(w, `{"level":"debug","message":"Below the level."}`)
+	fmt.Fprintln(w, `{"not":"a record"}`)
+	fmt.Fprintln(w, `plain text`)
+	w.Close()
+	scanner := bufio.NewScanner(buffer)
+	var messages []Message
+	for scanner.Scan() {
+		var m Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		}
+		messages = append(messages, m)
+	}
+	if len(messages) != 3 {
+		t.Errorf("%d messages were logged instead of 3.", len(messages))
+		return
+	}
+	merged := messages[0]
+	if merged.Level != "error" || merged.Message != "Task failed." || merged.Logger != "orchestrator.worker" {
+		t.Errorf("Merged record is %s '%s' from '%s'.", merged.Level, merged.Message, merged.Logger)
+	}
+	if merged.Time.Format(time.RFC3339Nano) != "2017-10-05T21:07:58.115+02:00" {
+		t.Errorf("Merged record time is %s.", merged.Time)
+	}
+	if merged.Context["job"] != "child" || merged.Context["pid"] != 42.0 {
+		t.Errorf("Merged record context is %v.", merged.Context)
+	}
+	if merged.Error == nil || merged.Error.Message != "boom" {
+		t.Errorf("Merged record should keep its error.")
+	}
+	if messages[1].Message != `{"not":"a record"}` || messages[2].Message != "plain text" || messages[2].Level != "warning" {
+		t.Errorf("Other lines should be wrapped, got '%s' and '%s'.", messages[1].Message, messages[2].Message)
+	}
+}
@@ -0,0 +1,126 @@
+package jsonlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// CardinalityAction decides what a CardinalityGuard does with a field whose
+// number of distinct values exceeded the limit.
+type CardinalityAction uint
+
+const (
+	// CardinalityWarn only reports the field.
+	CardinalityWarn = CardinalityAction(iota)
+	// CardinalityHash reports the field and replaces its values with a
+	// short hash, so that they can still be correlated.
+	CardinalityHash
+)
+
+// CardinalityGuard tracks the number of distinct values of each context value
+// and top-level data field over a window of time, and reacts when it exceeds
+// a limit. It protects downstream indexes from fields which should not be
+// labels, such as raw UUIDs. Install it with Logger.WithHook(guard.Hook).
+//
+// A CardinalityGuard is safe for concurrent use and can be shared by several
+// Loggers.
+type CardinalityGuard struct {
+	window      time.Duration
+	limit       int
+	action      CardinalityAction
+	onExceeded  func(field string, distinct int)
+	mutex       sync.Mutex
+	windowStart time.Time
+	values      map[string]map[string]struct{}
+	exceeded    map[string]bool
+}
+
+// NewCardinalityGuard creates a CardinalityGuard allowing `limit' distinct
+// values per field and per window. `onExceeded' is called once per window for
+// each field exceeding the limit; it may be nil. Context values are tracked
+// under "context.<key>" and data fields under "data.<key>".
+func NewCardinalityGuard(window time.Duration, limit int, action CardinalityAction, onExceeded func(field string, distinct int)) *CardinalityGuard {
+	return &CardinalityGuard{
+		window:     window,
+		limit:      limit,
+		action:     action,
+		onExceeded: onExceeded,
+		values:     map[string]map[string]struct{}{},
+		exceeded:   map[string]bool{},
+	}
+}
+
+// Hook is the Hook to install on Loggers to guard their messages.
+func (g *CardinalityGuard) Hook(m *Message) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if m.Time.Sub(g.windowStart) >= g.window {
+		g.windowStart = m.Time
+		g.values = map[string]map[string]struct{}{}
+		g.exceeded = map[string]bool{}
+	}
+	for key, value := range m.Context {
+		if g.track("context."+key, value) && g.action == CardinalityHash {
+			m.Context[key] = hashValue(value)
+		}
+	}
+	dataValue := reflect.ValueOf(m.Data)
+	if dataValue.Kind() != reflect.Map || dataValue.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+	var hashed map[string]interface{}
+	iterator := dataValue.MapRange()
+	for iterator.Next() {
+		key := iterator.Key().String()
+		value := iterator.Value().Interface()
+		if g.track("data."+key, value) && g.action == CardinalityHash && hashed == nil {
+			hashed = make(map[string]interface{}, dataValue.Len())
+		}
+	}
+	if hashed != nil {
+		iterator = dataValue.MapRange()
+		for iterator.Next() {
+			key := iterator.Key().String()
+			value := iterator.Value().Interface()
+			if g.exceeded["data."+key] {
+				value = hashValue(value)
+			}
+			hashed[key] = value
+		}
+		m.Data = hashed
+	}
+	return nil
+}
+
+// track records a value of a field and tells whether the field exceeded the
+// limit in the current window.
+func (g *CardinalityGuard) track(field string, value interface{}) bool {
+	if g.exceeded[field] {
+		return true
+	}
+	values := g.values[field]
+	if values == nil {
+		values = map[string]struct{}{}
+		g.values[field] = values
+	}
+	values[fmt.Sprint(value)] = struct{}{}
+	if len(values) <= g.limit {
+		return false
+	}
+	g.exceeded[field] = true
+	delete(g.values, field)
+	if g.onExceeded != nil {
+		g.onExceeded(field, len(values))
+	}
+	return true
+}
+
+// hashValue replaces a value with a short, stable hash of its representation.
+func hashValue(value interface{}) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
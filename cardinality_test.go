@@ -0,0 +1,48 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCardinalityGuard tests that fields exceeding the limit are reported
+// once and hashed, while other fields are left alone.
+func TestCardinalityGuard(t *testing.T) {
+	var reported []string
+	guard := NewCardinalityGuard(time.Minute, 3, CardinalityHash, func(field string, distinct int) {
+		reported = append(reported, field)
+	})
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithHook(guard.Hook)
+	for i := 0; i < 6; i++ {
+		data := map[string]interface{}{"userId": "user-" + strconv.Itoa(i), "region": "eu"}
+		logger.Info("request", data)
+		if data["userId"] != "user-"+strconv.Itoa(i) {
+			t.Errorf("The caller's data should not be modified.")
+		}
+	}
+	if len(reported) != 1 || reported[0] != "data.userId" {
+		t.Errorf("Reported fields %v should be [data.userId].", reported)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	for i, line := range lines {
+		output := struct {
+			Data map[string]string `json:"data"`
+		}{}
+		if err := json.Unmarshal([]byte(line), &output); err != nil {
+			t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+			continue
+		}
+		hashed := strings.HasPrefix(output.Data["userId"], "sha256:")
+		if hashed != (i >= 3) {
+			t.Errorf("Message %d has userId '%s'; only messages past the limit should be hashed.", i, output.Data["userId"])
+		}
+		if output.Data["region"] != "eu" {
+			t.Errorf("Message %d region '%s' should be left alone.", i, output.Data["region"])
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package jsonlog
+
+import (
+	"compress/gzip"
+	"hash"
+	"io"
+)
+
+// WriterMiddleware wraps an io.Writer to transform or observe the bytes
+// written through it, for example to compress them. Middlewares are composed
+// with NewWriterChain.
+type WriterMiddleware func(w io.Writer) io.Writer
+
+// WriterChain is an io.Writer made of middlewares stacked over a final writer.
+// Flush and Close are propagated through every layer, from the outermost to
+// the final writer, so that data buffered by a layer reaches the next one
+// before it is flushed or closed in turn.
+type WriterChain struct {
+	// layers holds the writers of the chain, outermost first and the final
+	// writer last.
+	layers []io.Writer
+}
+
+// hashWriter passes writes through while feeding them to a hash.
+type hashWriter struct {
+	w    io.Writer
+	hash hash.Hash
+}
+
+// NewWriterChain builds a WriterChain writing to `w'. Data goes through the
+// middlewares in the given order: with NewWriterChain(file, a, b), what is
+// written to the chain goes through `a', then `b', then to `file'.
+func NewWriterChain(w io.Writer, middlewares ...WriterMiddleware) *WriterChain {
+	layers := make([]io.Writer, len(middlewares)+1)
+	layers[len(middlewares)] = w
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		layers[i] = middlewares[i](layers[i+1])
+	}
+	return &WriterChain{layers}
+}
+
+// Write writes to the outermost layer of the chain.
+func (c *WriterChain) Write(p []byte) (int, error) {
+	return c.layers[0].Write(p)
+}
+
+// Flush flushes every layer implementing `Flush() error', outermost first. It
+// stops at the first error.
+func (c *WriterChain) Flush() error {
+	for _, layer := range c.layers {
+		if f, ok := layer.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes every layer implementing io.Closer, outermost first, and
+// flushes those which can only be flushed. Every layer is closed even if one
+// fails; the first error is returned. The final writer is closed too.
+func (c *WriterChain) Close() error {
+	var firstErr error
+	for _, layer := range c.layers {
+		var err error
+		if closer, ok := layer.(io.Closer); ok {
+			err = closer.Close()
+		} else if f, ok := layer.(flusher); ok {
+			err = f.Flush()
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// GzipMiddleware compresses the data with gzip at the given compression level,
+// such as gzip.BestSpeed. An invalid level uses gzip.DefaultCompression.
+func GzipMiddleware(level int) WriterMiddleware {
+	return func(w io.Writer) io.Writer {
+		gzipWriter, err := gzip.NewWriterLevel(w, level)
+		if err != nil {
+			gzipWriter = gzip.NewWriter(w)
+		}
+		return gzipWriter
+	}
+}
+
+// HashMiddleware feeds the data, as it is at this point of the chain, to `h'
+// so that a checksum of the output can be computed.
+func HashMiddleware(h hash.Hash) WriterMiddleware {
+	return func(w io.Writer) io.Writer {
+		return &hashWriter{w, h}
+	}
+}
+
+// Write hashes the bytes which were successfully written.
+func (w *hashWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.hash.Write(p[:n])
+	return n, err
+}
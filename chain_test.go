@@ -0,0 +1,54 @@
+package jsonlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"io"
+	"testing"
+)
+
+// closeRecorder is a writer recording the order in which it is closed.
+type closeRecorder struct {
+	bytes.Buffer
+	name  string
+	order *[]string
+}
+
+func (c *closeRecorder) Close() error {
+	*c.order = append(*c.order, c.name)
+	return nil
+}
+
+// TestWriterChain tests composing gzip and hashing middlewares and that Close
+// reaches every layer in order.
+func TestWriterChain(t *testing.T) {
+	var order []string
+	final := &closeRecorder{name: "final", order: &order}
+	compressedHash := sha256.New()
+	chain := NewWriterChain(final, GzipMiddleware(gzip.BestSpeed), HashMiddleware(compressedHash))
+	logger := DefaultLogger.WithWriter(chain)
+	logger.Info("first", nil)
+	logger.Info("second", nil)
+	if err := chain.Close(); err != nil {
+		t.Errorf("Closing the chain errored with '%s'.", err.Error())
+	}
+	if len(order) != 1 || order[0] != "final" {
+		t.Errorf("Final writer close order %v should be [final].", order)
+	}
+	expectedHash := sha256.Sum256(final.Bytes())
+	if !bytes.Equal(compressedHash.Sum(nil), expectedHash[:]) {
+		t.Errorf("The hash does not match the compressed output.")
+	}
+	gzipReader, err := gzip.NewReader(&final.Buffer)
+	if err != nil {
+		t.Errorf("Opening the gzip stream errored with '%s'.", err.Error())
+		return
+	}
+	decompressed, err := io.ReadAll(gzipReader)
+	if err != nil {
+		t.Errorf("Decompressing errored with '%s'.", err.Error())
+	} else if bytes.Count(decompressed, []byte("\n")) != 2 {
+		t.Errorf("Decompressed output '%s' should hold two records.", decompressed)
+	}
+}
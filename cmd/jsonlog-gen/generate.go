@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// directive starts the doc comment line describing an event.
+const directive = "//jsonlog:event "
+
+// levelConstants are the jsonlog constants of the levels an event may have.
+var levelConstants = map[string]string{
+	"debug":   "LogLevelDebug",
+	"info":    "LogLevelInfo",
+	"warning": "LogLevelWarning",
+	"warn":    "LogLevelWarning",
+	"error":   "LogLevelError",
+}
+
+// classificationConstants are the jsonlog constants of the classifications
+// other than public, by tag value.
+var classificationConstants = map[string]string{
+	"sensitive": "ClassificationSensitive",
+	"secret":    "ClassificationSecret",
+}
+
+// event is an annotated struct.
+type event struct {
+	name    string
+	level   string
+	message string
+	fields  []eventField
+}
+
+// eventField is a logged field of an event.
+type eventField struct {
+	goName         string
+	name           string
+	typ            string
+	classification string
+}
+
+// parseEvents finds the annotated structs of the given files, which must
+// belong to the same package.
+func parseEvents(paths []string) (string, []event, error) {
+	fset := token.NewFileSet()
+	var pkg string
+	var events []event
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return "", nil, err
+		}
+		if pkg != "" && file.Name.Name != pkg {
+			return "", nil, fmt.Errorf("%s: package %s, expected %s", path, file.Name.Name, pkg)
+		}
+		pkg = file.Name.Name
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				doc := typeSpec.Doc
+				if doc == nil && len(genDecl.Specs) == 1 {
+					doc = genDecl.Doc
+				}
+				e, ok, err := parseEvent(typeSpec, doc)
+				if err != nil {
+					return "", nil, fmt.Errorf("%s: %s", fset.Position(typeSpec.Pos()), err.Error())
+				}
+				if ok {
+					events = append(events, e)
+				}
+			}
+		}
+	}
+	return pkg, events, nil
+}
+
+// parseEvent reads the directive and the fields of a type, if it is an
+// event.
+func parseEvent(typeSpec *ast.TypeSpec, doc *ast.CommentGroup) (event, bool, error) {
+	if doc == nil {
+		return event{}, false, nil
+	}
+	for _, comment := range doc.List {
+		if !strings.HasPrefix(comment.Text, directive) {
+			continue
+		}
+		structType, ok := typeSpec.Type.(*ast.StructType)
+		if !ok {
+			return event{}, false, fmt.Errorf("%s is not a struct", typeSpec.Name.Name)
+		}
+		arguments := strings.TrimSpace(strings.TrimPrefix(comment.Text, directive))
+		level, quoted, _ := strings.Cut(arguments, " ")
+		if _, ok := levelConstants[level]; !ok {
+			return event{}, false, fmt.Errorf("unknown level %q", level)
+		}
+		message, err := strconv.Unquote(strings.TrimSpace(quoted))
+		if err != nil {
+			return event{}, false, fmt.Errorf("the message must be a quoted string: %s", quoted)
+		}
+		e := event{name: typeSpec.Name.Name, level: level, message: message}
+		for _, field := range structType.Fields.List {
+			for _, name := range field.Names {
+				if !name.IsExported() {
+					continue
+				}
+				f, ok, err := parseField(name.Name, field)
+				if err != nil {
+					return event{}, false, fmt.Errorf("field %s: %s", name.Name, err.Error())
+				}
+				if ok {
+					e.fields = append(e.fields, f)
+				}
+			}
+		}
+		return e, true, nil
+	}
+	return event{}, false, nil
+}
+
+// parseField reads the name and classification of a field from its tags.
+func parseField(goName string, field *ast.Field) (eventField, bool, error) {
+	f := eventField{goName: goName, name: goName, typ: types.ExprString(field.Type)}
+	if field.Tag == nil {
+		return f, true, nil
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return f, false, err
+	}
+	structTag := reflect.StructTag(tag)
+	if jsonTag := structTag.Get("json"); jsonTag == "-" {
+		return f, false, nil
+	} else if name, _, _ := strings.Cut(jsonTag, ","); name != "" {
+		f.name = name
+	}
+	switch classification := structTag.Get("jsonlog"); classification {
+	case "", "public":
+	default:
+		if _, ok := classificationConstants[classification]; !ok {
+			return f, false, fmt.Errorf("unknown classification %q", classification)
+		}
+		f.classification = classification
+	}
+	return f, true, nil
+}
+
+// generate renders and formats the code for the events.
+func generate(pkg string, events []event) ([]byte, error) {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "// Code generated by jsonlog-gen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	buffer.WriteString("import \"github.com/trackit/jsonlog\"\n\nfunc init() {\n")
+	for _, e := range events {
+		fmt.Fprintf(&buffer, "jsonlog.RegisterEvent(jsonlog.EventSchema{\nName: %q,\nLevel: jsonlog.%s,\nMessage: %q,\nFields: []jsonlog.EventField{\n",
+			e.name, levelConstants[e.level], e.message)
+		for _, f := range e.fields {
+			fmt.Fprintf(&buffer, "{Name: %q, Type: %q", f.name, f.typ)
+			if f.classification != "" {
+				fmt.Fprintf(&buffer, ", Classification: jsonlog.%s", classificationConstants[f.classification])
+			}
+			buffer.WriteString("},\n")
+		}
+		buffer.WriteString("},\n})\n")
+	}
+	buffer.WriteString("}\n")
+	for _, e := range events {
+		fmt.Fprintf(&buffer, "\n// Log%s logs a %s event.\nfunc Log%s(logger jsonlog.Logger, event %s) error {\n",
+			e.name, e.name, e.name, e.name)
+		buffer.WriteString("return logger.LogEvent(" + strconv.Quote(e.name) + ", map[string]interface{}{\n")
+		for _, f := range e.fields {
+			if f.classification == "secret" {
+				continue
+			}
+			fmt.Fprintf(&buffer, "%q: event.%s,\n", f.name, f.goName)
+		}
+		buffer.WriteString("})\n}\n")
+	}
+	return format.Source(buffer.Bytes())
+}
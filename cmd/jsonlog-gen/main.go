@@ -0,0 +1,71 @@
+// Command jsonlog-gen generates typed logging functions for the events
+// described by annotated structs, with the registration of their schemas.
+//
+// Usage:
+//
+//	jsonlog-gen [-o output] file.go...
+//
+// A struct is an event if its doc comment has a directive giving its level
+// and message:
+//
+//	//jsonlog:event info "User created."
+//	type UserCreated struct {
+//		UserID string `json:"userId"`
+//		Email  string `json:"email" jsonlog:"sensitive"`
+//		Token  string `jsonlog:"secret"`
+//	}
+//
+// For each event, the generated file registers its schema with
+// jsonlog.RegisterEvent and defines a function logging it:
+//
+//	func LogUserCreated(logger jsonlog.Logger, event UserCreated) error
+//
+// Fields are named after their json tag, or else their Go name, and are
+// skipped if tagged `json:"-"`. The jsonlog tag classifies them as public,
+// the default, sensitive or secret: sensitive values are redacted and
+// secret ones left out of records. The output defaults to jsonlog_events.go
+// in the directory of the first file. It is usually run with go:generate.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	output := flag.String("o", "", "output file, by default jsonlog_events.go next to the first input")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: jsonlog-gen [-o output] file.go...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *output == "" {
+		*output = filepath.Join(filepath.Dir(flag.Arg(0)), "jsonlog_events.go")
+	}
+	if err := run(flag.Args(), *output); err != nil {
+		fmt.Fprintf(os.Stderr, "jsonlog-gen: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// run parses the input files and writes the generated code.
+func run(paths []string, output string) error {
+	pkg, events, err := parseEvents(paths)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no //jsonlog:event struct found")
+	}
+	source, err := generate(pkg, events)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, source, 0644)
+}
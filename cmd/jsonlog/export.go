@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/trackit/jsonlog/reader"
+)
+
+// runExport implements the "export" subcommand.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	fields := flags.String("fields", "", "comma-separated list of field paths to export, e.g. time,level,data.user.id")
+	tsv := flags.Bool("tsv", false, "output tab-separated values instead of CSV")
+	null := flags.String("null", "", "value written for missing or null fields")
+	noHeader := flags.Bool("no-header", false, "do not output a header line")
+	flags.Parse(args)
+	if *fields == "" {
+		return errors.New("-fields is required")
+	}
+	input, closeInputs, err := openInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	defer closeInputs()
+	options := reader.ExportOptions{
+		Fields:   strings.Split(*fields, ","),
+		Null:     *null,
+		NoHeader: *noHeader,
+	}
+	if *tsv {
+		options.Comma = '\t'
+	}
+	return reader.ExportCSV(os.Stdout, reader.NewDecoder(input), options)
+}
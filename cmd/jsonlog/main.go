@@ -0,0 +1,85 @@
+// Command jsonlog provides tools to work with the records written by the
+// jsonlog package.
+//
+// Usage:
+//
+//	jsonlog <command> [arguments] [file...]
+//
+// Each command reads the given files in order, or the standard input if none
+// are given.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command is a subcommand of the jsonlog tool.
+type command struct {
+	run         func(args []string) error
+	description string
+}
+
+// commands lists the available subcommands by name.
+var commands = map[string]command{
+	"export": command{runExport, "export selected fields as CSV or TSV"},
+	"search": command{runSearch, "find the records containing a literal"},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "jsonlog %s: %s\n", os.Args[1], err.Error())
+		os.Exit(1)
+	}
+}
+
+// usage prints the list of subcommands.
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: jsonlog <command> [arguments] [file...]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].description)
+	}
+}
+
+// openInputs returns a reader over the concatenation of the given files, or
+// the standard input if there are none. The returned function closes the
+// files.
+func openInputs(paths []string) (io.Reader, func(), error) {
+	if len(paths) == 0 {
+		return os.Stdin, func() {}, nil
+	}
+	readers := make([]io.Reader, 0, len(paths))
+	files := make([]*os.File, 0, len(paths))
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		readers = append(readers, f)
+	}
+	return io.MultiReader(readers...), closeAll, nil
+}
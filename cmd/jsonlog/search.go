@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/trackit/jsonlog/reader"
+)
+
+// runSearch implements the "search" subcommand. Lines which cannot be decoded
+// are reported on the standard error and skipped.
+func runSearch(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	field := flags.String("field", "", "only output records where the value at this field path equals the literal")
+	flags.Parse(args)
+	if flags.NArg() < 1 {
+		return errors.New("usage: jsonlog search [-field path] <literal> [file...]")
+	}
+	literal := flags.Arg(0)
+	input, closeInputs, err := openInputs(flags.Args()[1:])
+	if err != nil {
+		return err
+	}
+	defer closeInputs()
+	output := bufio.NewWriter(os.Stdout)
+	defer output.Flush()
+	searcher := reader.NewSearcher(input, []byte(literal))
+	for {
+		record, err := searcher.Next()
+		var lineErr *reader.LineError
+		if err == io.EOF {
+			return nil
+		} else if errors.As(err, &lineErr) {
+			fmt.Fprintf(os.Stderr, "jsonlog search: skipping %s\n", lineErr.Error())
+			continue
+		} else if err != nil {
+			return err
+		}
+		if *field != "" {
+			value, ok := record.Lookup(*field)
+			if !ok || fmt.Sprint(value) != literal {
+				continue
+			}
+		}
+		output.Write(searcher.Line())
+		output.WriteByte('\n')
+	}
+}
@@ -0,0 +1,85 @@
+package jsonlog
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// PricingModel estimates what sending a volume of logs to a destination costs.
+// Cost receives the total number of bytes sent so far, so that tiered pricing
+// can be modeled.
+type PricingModel interface {
+	Cost(bytes int64) float64
+}
+
+// PricePerGigabyte is a PricingModel charging a flat price per gigabyte
+// (10^9 bytes), as most hosted log vendors do.
+type PricePerGigabyte float64
+
+// CostEstimate is the volume written through a Meter for one tag and its
+// estimated cost.
+type CostEstimate struct {
+	Bytes int64
+	Cost  float64
+}
+
+// Cost implements PricingModel.
+func (p PricePerGigabyte) Cost(bytes int64) float64 {
+	return float64(p) * float64(bytes) / 1e9
+}
+
+// SetPricing sets the pricing model of the destination of the writers wrapped
+// by `m', enabling cost estimates.
+func (m *Meter) SetPricing(pricing PricingModel) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.pricing = pricing
+}
+
+// CostEstimates returns the volume and estimated cost attributed to each tag
+// given to WrapTagged; writers created with Wrap are under the empty tag. The
+// cost of the total volume is shared between tags in proportion to their
+// volume. Costs are zero if no pricing model was set.
+func (m *Meter) CostEstimates() map[string]CostEstimate {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var totalCost float64
+	if m.pricing != nil {
+		totalCost = m.pricing.Cost(m.stats.BytesWritten)
+	}
+	estimates := make(map[string]CostEstimate, len(m.tagBytes))
+	for tag, bytes := range m.tagBytes {
+		estimate := CostEstimate{Bytes: bytes}
+		if m.stats.BytesWritten > 0 {
+			estimate.Cost = totalCost * float64(bytes) / float64(m.stats.BytesWritten)
+		}
+		estimates[tag] = estimate
+	}
+	return estimates
+}
+
+// ServeHTTP exposes the Meter's figures and cost estimates in the Prometheus
+// text exposition format, so that it can be scraped directly.
+func (m *Meter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stats := m.Stats()
+	estimates := m.CostEstimates()
+	tags := make([]string, 0, len(estimates))
+	for tag := range estimates {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# TYPE jsonlog_meter_dropped_bytes_total counter")
+	fmt.Fprintf(w, "jsonlog_meter_dropped_bytes_total %d\n", stats.BytesDropped)
+	fmt.Fprintln(w, "# TYPE jsonlog_meter_dropped_records_total counter")
+	fmt.Fprintf(w, "jsonlog_meter_dropped_records_total %d\n", stats.RecordsDropped)
+	fmt.Fprintln(w, "# TYPE jsonlog_meter_written_bytes_total counter")
+	for _, tag := range tags {
+		fmt.Fprintf(w, "jsonlog_meter_written_bytes_total{tag=%q} %d\n", tag, estimates[tag].Bytes)
+	}
+	fmt.Fprintln(w, "# TYPE jsonlog_meter_estimated_cost gauge")
+	for _, tag := range tags {
+		fmt.Fprintf(w, "jsonlog_meter_estimated_cost{tag=%q} %g\n", tag, estimates[tag].Cost)
+	}
+}
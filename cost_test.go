@@ -0,0 +1,33 @@
+package jsonlog
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCostEstimates tests attributing the cost of a sink to tags.
+func TestCostEstimates(t *testing.T) {
+	meter := NewMeter(0, QuotaDrop)
+	meter.SetPricing(PricePerGigabyte(0.5))
+	meter.WrapTagged("billing", io.Discard).Write(make([]byte, 3000))
+	meter.WrapTagged("search", io.Discard).Write(make([]byte, 1000))
+	estimates := meter.CostEstimates()
+	expected := map[string]CostEstimate{
+		"billing": CostEstimate{3000, 1.5e-6},
+		"search":  CostEstimate{1000, 0.5e-6},
+	}
+	for tag, e := range expected {
+		estimate := estimates[tag]
+		if estimate.Bytes != e.Bytes || estimate.Cost < e.Cost*0.999 || estimate.Cost > e.Cost*1.001 {
+			t.Errorf("Estimate for '%s' is %+v but should be %+v.", tag, estimate, e)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	meter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(recorder.Body.String(), `jsonlog_meter_written_bytes_total{tag="billing"} 3000`) {
+		t.Errorf("Metrics output should hold the bytes per tag:\n%s", recorder.Body.String())
+	}
+}
@@ -0,0 +1,254 @@
+package jsonlog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tokenExpiryMargin is how long before its expiry a token is renewed.
+	tokenExpiryMargin = 30 * time.Second
+	// sigV4TimeFormat is the format of the timestamps of AWS signatures.
+	sigV4TimeFormat = "20060102T150405Z"
+)
+
+// ErrNoAccessToken is returned when a token endpoint responds without an
+// access token.
+var ErrNoAccessToken = errors.New("jsonlog: no access token in token response")
+
+// Credentials authorize the requests of an HTTPSink, for example by setting
+// their Authorization header. Implementations which cache credentials may
+// also implement `Invalidate()': the sink calls it when a request is denied
+// with a 401 status, and sends the request again once.
+//
+// Implementations must be safe for concurrent use.
+type Credentials interface {
+	// Authorize adds credentials to a request whose body is `body'.
+	Authorize(request *http.Request, body []byte) error
+}
+
+// invalidator is implemented by Credentials which can be refreshed.
+type invalidator interface {
+	Invalidate()
+}
+
+// HeaderCredentials set a header to a value, such as an API key. The value is
+// obtained from Value for each request, so that a key rotated at run time,
+// for example in a file, is used without a restart.
+type HeaderCredentials struct {
+	// Header is the name of the header; it defaults to Authorization.
+	Header string
+	// Value returns the value of the header.
+	Value func() (string, error)
+}
+
+// OAuth2Credentials obtain access tokens from an OAuth2 token endpoint with
+// the client credentials grant of RFC 6749, and send them as bearer tokens.
+// Tokens are renewed shortly before they expire or when a request is denied.
+type OAuth2Credentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Client sends the token requests. It defaults to http.DefaultClient.
+	Client *http.Client
+
+	mutex   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// AWSCredentials are the keys of an AWS identity.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSSigV4Credentials sign requests with AWS Signature Version 4, for
+// endpoints such as Amazon OpenSearch Service or CloudWatch Logs.
+type AWSSigV4Credentials struct {
+	Region  string
+	Service string
+	// Credentials returns the keys to sign with. It is called for each
+	// request, so that temporary credentials can be renewed.
+	Credentials func() (AWSCredentials, error)
+	// ContentSHA256 sends the hash of the body in the X-Amz-Content-Sha256
+	// header, as required by some services such as OpenSearch Serverless.
+	ContentSHA256 bool
+
+	// now is the time of the signatures; tests replace it.
+	now func() time.Time
+}
+
+// StaticCredentials returns HeaderCredentials setting `header' to a fixed
+// `value', such as "Authorization" and "ApiKey c2VjcmV0".
+func StaticCredentials(header, value string) HeaderCredentials {
+	return HeaderCredentials{
+		Header: header,
+		Value:  func() (string, error) { return value, nil },
+	}
+}
+
+// Authorize sets the header.
+func (c HeaderCredentials) Authorize(request *http.Request, body []byte) error {
+	value, err := c.Value()
+	if err != nil {
+		return err
+	}
+	header := c.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	request.Header.Set(header, value)
+	return nil
+}
+
+// Authorize sets the bearer token, requesting a new one if needed.
+func (c *OAuth2Credentials) Authorize(request *http.Request, body []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.token == "" || !time.Now().Before(c.expires) {
+		if err := c.refresh(); err != nil {
+			return err
+		}
+	}
+	request.Header.Set("Authorization", "Bearer "+c.token)
+	return nil
+}
+
+// Invalidate discards the current token.
+func (c *OAuth2Credentials) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.token = ""
+}
+
+// refresh requests a new token. The mutex must be held.
+func (c *OAuth2Credentials) refresh() error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	request, err := http.NewRequest(http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+		return &HTTPStatusError{StatusCode: response.StatusCode, Body: string(message)}
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return err
+	}
+	if token.AccessToken == "" {
+		return ErrNoAccessToken
+	}
+	c.token = token.AccessToken
+	if token.ExpiresIn > 0 {
+		c.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpiryMargin)
+	} else {
+		c.expires = time.Now().Add(time.Hour)
+	}
+	return nil
+}
+
+// Authorize signs the request. It must be the last change to the request
+// before it is sent.
+func (c AWSSigV4Credentials) Authorize(request *http.Request, body []byte) error {
+	credentials, err := c.Credentials()
+	if err != nil {
+		return err
+	}
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+	timestamp := now().UTC().Format(sigV4TimeFormat)
+	date := timestamp[:8]
+	payloadHash := sha256.Sum256(body)
+	request.Header.Set("X-Amz-Date", timestamp)
+	if c.ContentSHA256 {
+		request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	}
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	signed := request.Header.Clone()
+	signed.Set("Host", request.URL.Host)
+	signedHeaders, canonicalHeaders := sigV4Headers(signed)
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		path,
+		strings.ReplaceAll(request.URL.Query().Encode(), "+", "%20"),
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	scope := date + "/" + c.Region + "/" + c.Service + "/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + timestamp + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, c.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return nil
+}
+
+// sigV4Headers returns the list of signed headers and the canonical headers
+// of a request, as per AWS Signature Version 4.
+func sigV4Headers(header http.Header) (string, string) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	for _, name := range names {
+		values := make([]string, 0, 1)
+		for _, value := range header.Values(name) {
+			values = append(values, strings.Join(strings.Fields(value), " "))
+		}
+		canonical.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	return strings.Join(names, ";"), canonical.String()
+}
+
+// hmacSHA256 computes the HMAC-SHA256 of `data' with `key'.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
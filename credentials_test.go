@@ -0,0 +1,131 @@
+package jsonlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rotatingAPI is an HTTP endpoint issuing OAuth2 tokens on /token and only
+// accepting the latest one on other paths.
+type rotatingAPI struct {
+	mutex    sync.Mutex
+	issued   int
+	accepted int
+}
+
+func (a *rotatingAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if r.URL.Path == "/token" {
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		a.issued++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + strconv.Itoa(a.issued),
+			"expires_in":   3600,
+		})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token-"+strconv.Itoa(a.issued) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	a.accepted++
+}
+
+// rotate revokes the current token.
+func (a *rotatingAPI) rotate() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.issued++
+}
+
+// TestOAuth2Credentials tests that tokens are cached and renewed when the
+// endpoint denies them.
+func TestOAuth2Credentials(t *testing.T) {
+	api := &rotatingAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	sink := newTestHTTPSink(t, HTTPSinkConfig{
+		URL: server.URL + "/logs",
+		Credentials: &OAuth2Credentials{
+			TokenURL:     server.URL + "/token",
+			ClientID:     "client",
+			ClientSecret: "secret",
+		},
+	})
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			api.rotate()
+		}
+		if _, err := sink.Write([]byte("{\"message\":\"authorized\"}\n")); err != nil {
+			t.Errorf("Writing batch %d errored with '%s'.", i, err.Error())
+		}
+	}
+	if api.accepted != 3 {
+		t.Errorf("The endpoint accepted %d batches instead of 3.", api.accepted)
+	}
+	if api.issued != 3 {
+		t.Errorf("%d tokens were issued instead of 2 plus the revoked one.", api.issued)
+	}
+}
+
+// TestHeaderCredentials tests that the header value is read for each request.
+func TestHeaderCredentials(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	key := 0
+	sink := newTestHTTPSink(t, HTTPSinkConfig{
+		URL: server.URL,
+		Credentials: HeaderCredentials{
+			Header: "X-Api-Key",
+			Value: func() (string, error) {
+				key++
+				return "key-" + strconv.Itoa(key), nil
+			},
+		},
+	})
+	sink.Write([]byte("{}\n"))
+	sink.Write([]byte("{}\n"))
+	if len(c.headers) != 2 || c.headers[0].Get("X-Api-Key") != "key-1" || c.headers[1].Get("X-Api-Key") != "key-2" {
+		t.Errorf("The requests were not sent with the rotated keys: %v.", c.headers)
+	}
+}
+
+// TestAWSSigV4Credentials tests the signature against the get-vanilla
+// example of the AWS Signature Version 4 test suite.
+func TestAWSSigV4Credentials(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	credentials := AWSSigV4Credentials{
+		Region:  "us-east-1",
+		Service: "service",
+		Credentials: func() (AWSCredentials, error) {
+			return AWSCredentials{
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			}, nil
+		},
+		now: func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+	if err := credentials.Authorize(request, nil); err != nil {
+		t.Errorf("Signing errored with '%s'.", err.Error())
+		return
+	}
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if authorization := request.Header.Get("Authorization"); authorization != expected {
+		t.Errorf("Expected '%s', got '%s'.", expected, authorization)
+	}
+	if request.Header.Get("Host") != "" {
+		t.Errorf("The Host header should not be added to the request.")
+	}
+}
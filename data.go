@@ -0,0 +1,46 @@
+package jsonlog
+
+import (
+	"sync"
+)
+
+// maxPooledDataSize is the number of fields above which a Data is not
+// recycled, as maps never shrink.
+const maxPooledDataSize = 64
+
+// dataPool holds the recycled Data maps.
+var dataPool = sync.Pool{
+	New: func() interface{} { return Data{} },
+}
+
+// Data is a map of fields to be used as the data of a message. Unlike other
+// maps, a Data passed to a log call is recycled once the call returns, so
+// that call sites logging at a high rate do not allocate a fresh map each
+// time. It must therefore not be used anymore after it was logged, and must
+// not be retained by hooks.
+//
+//	logger.Info("Request served.", jsonlog.NewData().Set("path", path).Set("status", status))
+type Data map[string]interface{}
+
+// NewData returns an empty Data, reusing one which was already logged if
+// possible.
+func NewData() Data {
+	return dataPool.Get().(Data)
+}
+
+// Set sets a field and returns the Data, so that calls can be chained.
+func (d Data) Set(key string, value interface{}) Data {
+	d[key] = value
+	return d
+}
+
+// release clears the Data and puts it back in the pool.
+func (d Data) release() {
+	if d == nil || len(d) > maxPooledDataSize {
+		return
+	}
+	for key := range d {
+		delete(d, key)
+	}
+	dataPool.Put(d)
+}
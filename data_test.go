@@ -0,0 +1,40 @@
+package jsonlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestData tests that Data is output like a map and recycled once logged,
+// whether the message was output or not.
+func TestData(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithLogLevel(LogLevelInfo)
+	data := NewData().Set("path", "/health").Set("status", 200)
+	if err := logger.Info("served", data); err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+	}
+	if expected := `"data":{"path":"/health","status":200}`; !strings.Contains(buffer.String(), expected) {
+		t.Errorf("Output '%s' should contain '%s'.", buffer.String(), expected)
+	}
+	if len(data) != 0 {
+		t.Errorf("Logged data should be cleared, got %v.", data)
+	}
+	filtered := NewData().Set("query", "SELECT 1")
+	logger.Debug("filtered", filtered)
+	if len(filtered) != 0 {
+		t.Errorf("Filtered data should be cleared, got %v.", filtered)
+	}
+}
+
+// TestDataWithHooksAndFormatters tests that Data goes through hooks and the
+// other formatters like a map.
+func TestDataWithHooksAndFormatters(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithFormatter(LogfmtFormatter{}).WithHook(UnitSuffixes)
+	logger.Info("sent", NewData().Set("size", ByteSize(512)))
+	if expected := "data.size_bytes=512"; !strings.Contains(buffer.String(), expected) {
+		t.Errorf("Output '%s' should contain '%s'.", buffer.String(), expected)
+	}
+}
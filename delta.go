@@ -0,0 +1,100 @@
+package jsonlog
+
+import (
+	"bytes"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// StaticRefKey is the context key under which records in delta mode
+	// reference their static fields.
+	StaticRefKey = "staticRef"
+	// StaticKeysKey is the context key under which the records carrying
+	// static fields in full list their keys, so that readers can tell them
+	// apart from other context values.
+	StaticKeysKey = "staticKeys"
+)
+
+// deltaState remembers when the static fields of each set were last output
+// in full by the Loggers sharing it.
+type deltaState struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	lastFull map[string]time.Time
+}
+
+// WithDeltaRecords returns a new Logger in delta mode: its static fields are
+// output in full only on the first message, then once every `interval', and
+// the other messages only carry a reference to them under "staticRef" in
+// their context. Messages carrying the static fields in full also list their
+// keys under "staticKeys". This saves volume when static fields are large and
+// messages numerous; reader.Decoder rehydrates the records it reads.
+//
+// Loggers derived from this one share its state, and a Logger whose static
+// fields differ gets a new reference, output in full on its first message.
+// Hooks see the static fields of every message; they are removed afterwards
+// from the messages which only reference them. Records are written in the
+// order the decision to output the fields in full is taken, so that no
+// reference reaches the writer before the fields, and the fields are output
+// in full again if the record carrying them failed to be written. Records
+// dropped by a hook may leave references unresolved until the next full
+// output. A non-positive `interval' disables delta mode.
+func (l Logger) WithDeltaRecords(interval time.Duration) Logger {
+	if interval <= 0 {
+		l.delta = nil
+		return l
+	}
+	l.delta = &deltaState{
+		interval: interval,
+		lastFull: map[string]time.Time{},
+	}
+	return l
+}
+
+// trim keeps the static fields of a message, set in full by
+// addStaticFields, if they are due at its time, listing their keys, and
+// removes them otherwise. It reports whether they were kept. `count' is the
+// number of static fields of the Logger. The mutex must be held until the
+// message is written, so that no record referencing the fields reaches the
+// writer before the record carrying them.
+func (d *deltaState) trim(ref string, m *Message, count int) bool {
+	last, ok := d.lastFull[ref]
+	if ok && m.Time.Sub(last) < d.interval && !m.Time.Before(last) {
+		for _, key := range m.staticKeys {
+			delete(m.Context, key)
+		}
+		return false
+	}
+	d.lastFull[ref] = m.Time
+	if m.Context == nil {
+		m.Context = map[string]interface{}{}
+	}
+	keys := append([]string{}, m.staticKeys...)
+	sort.Strings(keys)
+	m.Context[StaticKeysKey] = keys
+	if len(keys) < count {
+		// Context values hid some static fields: output them in full
+		// again with the next message.
+		delete(d.lastFull, ref)
+	}
+	return true
+}
+
+// staticFieldsRef computes the reference of a set of static fields: a hash of
+// their JSON representation. It is empty if they cannot be encoded.
+func staticFieldsRef(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	var buffer bytes.Buffer
+	if err := encodeMap(&buffer, fields); err != nil {
+		return ""
+	}
+	hash := fnv.New64a()
+	hash.Write(buffer.Bytes())
+	return strconv.FormatUint(hash.Sum64(), 36)
+}
@@ -0,0 +1,96 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDeltaRecords tests that static fields are output in full on the first
+// message, after the interval and when they change, and referenced otherwise.
+func TestDeltaRecords(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	logger := DefaultLogger.WithWriter(buffer).WithClock(func() time.Time { return now }).
+		WithStaticFields(map[string]interface{}{"service": "billing", "region": "eu"}).
+		WithDeltaRecords(time.Minute)
+	logger.Info("full", nil)
+	logger.Info("delta", nil)
+	logger.WithService("billing", "2").Info("changed", nil)
+	now = now.Add(time.Minute)
+	logger.Info("periodic", nil)
+	ctx := context.WithValue(context.Background(), "region", "us")
+	hidden := logger.WithContext(ctx).WithContextKey("region", "region")
+	now = now.Add(time.Minute)
+	hidden.Info("hidden", nil)
+	logger.Info("after hidden", nil)
+	expectedKeys := [][]interface{}{
+		{"region", "service"},
+		nil,
+		{"region", "service", "version"},
+		{"region", "service"},
+		{"service"},
+		{"region", "service"},
+	}
+	decoder := json.NewDecoder(buffer)
+	for i, keys := range expectedKeys {
+		var m Message
+		if err := decoder.Decode(&m); err != nil {
+			t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+			return
+		}
+		if _, ok := m.Context[StaticRefKey].(string); !ok {
+			t.Errorf("Record %d should reference its static fields.", i)
+		}
+		staticKeys, _ := m.Context[StaticKeysKey].([]interface{})
+		if len(staticKeys) != len(keys) {
+			t.Errorf("Record %d has static keys %v instead of %v.", i, staticKeys, keys)
+			continue
+		}
+		for j := range keys {
+			if staticKeys[j] != keys[j] {
+				t.Errorf("Record %d has static keys %v instead of %v.", i, staticKeys, keys)
+			}
+		}
+		if _, ok := m.Context["service"]; ok != (keys != nil) {
+			t.Errorf("Record %d should carry the static fields in full: %v.", i, keys != nil)
+		}
+	}
+}
+
+// TestDeltaRecordsOrder tests that a record referencing static fields cannot
+// reach the writer before the record carrying them, and that the fields of
+// records which failed to be written are output in full again.
+func TestDeltaRecordsOrder(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	fields := map[string]interface{}{"service": "billing"}
+	logger := DefaultLogger.WithWriter(w).WithStaticFields(fields).WithDeltaRecords(time.Minute)
+	done := make(chan struct{})
+	go func() {
+		logger.Info("first", nil)
+		close(done)
+	}()
+	<-w.entered
+	second := make(chan struct{})
+	go func() {
+		logger.Info("second", nil)
+		close(second)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(w.release)
+	<-done
+	<-second
+	if len(w.writes) != 2 || !strings.Contains(w.writes[0], `"service":"billing"`) || strings.Contains(w.writes[1], `"service":"billing"`) {
+		t.Errorf("The full static fields should be written first, got %v.", w.writes)
+	}
+	buffer := new(bytes.Buffer)
+	lost := DefaultLogger.WithWriter(failingWriter{}).WithStaticFields(fields).WithDeltaRecords(time.Minute)
+	lost.Info("lost", nil)
+	lost.WithWriter(buffer).Info("retried", nil)
+	if !strings.Contains(buffer.String(), `"service":"billing"`) {
+		t.Errorf("The static fields of a lost record should be output in full again, got '%s'.", buffer.String())
+	}
+}
@@ -0,0 +1,113 @@
+package jsonlog
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DictionaryKey is the context key under which a message defines the
+	// references to the strings of a StringDictionary it uses first.
+	DictionaryKey = "dictionary"
+	// dictionaryPrefix starts the references to dictionary strings. Strings
+	// starting with it are escaped by doubling it.
+	dictionaryPrefix = "@"
+)
+
+// StringDictionary replaces the messages and logger names which repeat in a
+// stream with short references, such as "@1b", which cuts the size of archives
+// of repetitive logs. The first message using a string defines its reference
+// under "dictionary" in its context; reader.Decoder expands the references
+// back. Messages and logger names starting with "@" are escaped as "@@".
+//
+// Readers need every definition from the start of the stream: call Reset when
+// a new file is started, such as after a rotation. Records are formatted and
+// written while the dictionary is locked, so that a record always reaches the
+// writer after the definitions of the references it uses; references defined
+// by a record which failed to be written are defined again by the next one.
+//
+// A StringDictionary is safe for concurrent use.
+type StringDictionary struct {
+	maxEntries int
+	mutex      sync.Mutex
+	refs       map[string]string
+}
+
+// NewStringDictionary creates a StringDictionary holding at most `maxEntries'
+// strings. Strings seen once it is full are output as they are.
+func NewStringDictionary(maxEntries int) *StringDictionary {
+	return &StringDictionary{
+		maxEntries: maxEntries,
+		refs:       map[string]string{},
+	}
+}
+
+// WithStringDictionary returns a new Logger shortening its messages and
+// logger name with `d'. Hooks still see the original strings. A nil
+// dictionary disables shortening.
+func (l Logger) WithStringDictionary(d *StringDictionary) Logger {
+	l.dictionary = d
+	return l
+}
+
+// Reset forgets every reference, so that strings are defined again.
+func (d *StringDictionary) Reset() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.refs = map[string]string{}
+}
+
+// shorten replaces the message and logger name of `m' with references, and
+// adds the definitions of new references to its context. It returns the
+// strings it defined. The mutex must be held until the message is written,
+// so that no record using the new references reaches the writer first.
+func (d *StringDictionary) shorten(m *Message) []string {
+	var definitions map[string]interface{}
+	var defined []string
+	shorten := func(s string) string {
+		ref, ok := d.refs[s]
+		if !ok {
+			if len(d.refs) >= d.maxEntries {
+				return escapeDictionaryString(s)
+			}
+			ref = dictionaryPrefix + strconv.FormatInt(int64(len(d.refs)), 36)
+			d.refs[s] = ref
+			defined = append(defined, s)
+			if definitions == nil {
+				definitions = map[string]interface{}{}
+			}
+			definitions[ref] = s
+		}
+		return ref
+	}
+	m.Message = shorten(m.Message)
+	if m.Logger != "" {
+		m.Logger = shorten(m.Logger)
+	}
+	if definitions != nil {
+		if m.Context == nil {
+			m.Context = map[string]interface{}{}
+		}
+		m.Context[DictionaryKey] = definitions
+	}
+	return defined
+}
+
+// forget removes the references to strings defined by a message which was
+// not written, so that the next message using them defines them again. The
+// mutex must be held.
+func (d *StringDictionary) forget(defined []string) {
+	for _, s := range defined {
+		delete(d.refs, s)
+	}
+}
+
+// escapeDictionaryString escapes a string output as is so that it cannot be
+// mistaken for a reference.
+func escapeDictionaryString(s string) string {
+	if strings.HasPrefix(s, dictionaryPrefix) {
+		return dictionaryPrefix + s
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package jsonlog
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStringDictionary tests that repeated messages and logger names are
+// replaced with references defined on first use.
+func TestStringDictionary(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	dictionary := NewStringDictionary(3)
+	var hooked []string
+	logger := DefaultLogger.WithWriter(buffer).Named("billing").WithStringDictionary(dictionary).
+		WithHook(func(m *Message) error {
+			hooked = append(hooked, m.Message)
+			return nil
+		})
+	logger.Info("Payment failed.", nil)
+	logger.Info("Payment failed.", nil)
+	logger.Info("Refund issued.", nil)
+	logger.Info("@mention", nil)
+	dictionary.Reset()
+	logger.Info("Refund issued.", nil)
+	expected := []string{
+		`"message":"@0","logger":"@1","context":{"dictionary":{"@0":"Payment failed.","@1":"billing"}}}`,
+		`"message":"@0","logger":"@1"}`,
+		`"message":"@2","logger":"@1","context":{"dictionary":{"@2":"Refund issued."}}}`,
+		`"message":"@@mention","logger":"@1"}`,
+		`"message":"@0","logger":"@1","context":{"dictionary":{"@0":"Refund issued.","@1":"billing"}}}`,
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Errorf("Output '%s' should hold %d records.", buffer.String(), len(expected))
+		return
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(lines[i], e) {
+			t.Errorf("Record '%s' should end with '%s'.", lines[i], e)
+		}
+	}
+	if hooked[0] != "Payment failed." {
+		t.Errorf("Hooks should see the original message, not '%s'.", hooked[0])
+	}
+}
+
+// blockingWriter blocks its first Write until released, and keeps the order
+// of the writes.
+type blockingWriter struct {
+	writeRecorder
+	entered chan struct{}
+	release chan struct{}
+	calls   int32
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	if atomic.AddInt32(&w.calls, 1) == 1 {
+		close(w.entered)
+		<-w.release
+	}
+	return w.writeRecorder.Write(p)
+}
+
+// TestStringDictionaryOrder tests that a record using a reference cannot
+// reach the writer before the record defining it, and that references of
+// records which failed to be written are defined again.
+func TestStringDictionaryOrder(t *testing.T) {
+	w := &blockingWriter{entered: make(chan struct{}), release: make(chan struct{})}
+	logger := DefaultLogger.WithWriter(w).WithStringDictionary(NewStringDictionary(10))
+	done := make(chan struct{})
+	go func() {
+		logger.Info("Payment failed.", nil)
+		close(done)
+	}()
+	<-w.entered
+	second := make(chan struct{})
+	go func() {
+		logger.Info("Payment failed.", nil)
+		close(second)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(w.release)
+	<-done
+	<-second
+	if len(w.writes) != 2 || !strings.Contains(w.writes[0], `"dictionary":{"@0":"Payment failed."}`) {
+		t.Errorf("The definition should be written first, got %v.", w.writes)
+	}
+	buffer := new(bytes.Buffer)
+	dictionary := NewStringDictionary(10)
+	DefaultLogger.WithWriter(failingWriter{}).WithStringDictionary(dictionary).Info("Refund issued.", nil)
+	DefaultLogger.WithWriter(buffer).WithStringDictionary(dictionary).Info("Refund issued.", nil)
+	if !strings.Contains(buffer.String(), `"dictionary":{"@0":"Refund issued."}`) {
+		t.Errorf("The reference of a lost record should be defined again, got '%s'.", buffer.String())
+	}
+}
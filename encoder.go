@@ -0,0 +1,295 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPooledBufferSize is the capacity above which buffers are not returned to
+// the pool, so that one huge message does not pin memory forever.
+const maxPooledBufferSize = 64 * 1024
+
+// hexDigits are used to escape control characters in JSON strings.
+const hexDigits = "0123456789abcdef"
+
+// maxEncodeDepth is the deepest nesting of maps encoded by hand. Deeper values
+// are left to encoding/json, which reports cyclic ones with an error instead
+// of overflowing the stack.
+const maxEncodeDepth = 100
+
+var (
+	// bufferPool holds the buffers messages are formatted into.
+	bufferPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+
+	// valueEncoderPool holds the encoders used for arbitrary values.
+	valueEncoderPool = sync.Pool{
+		New: func() interface{} {
+			e := new(valueEncoder)
+			e.encoder = json.NewEncoder(&e.buffer)
+			return e
+		},
+	}
+
+	// keysPool holds the slices map keys are sorted in.
+	keysPool = sync.Pool{
+		New: func() interface{} { return new([]string) },
+	}
+
+	// encodedLevelNames holds the level names of logLevelNames already
+	// encoded as JSON strings.
+	encodedLevelNames = map[string][]byte{}
+)
+
+func init() {
+	for _, name := range logLevelNames {
+		encodedLevelNames[name] = appendJSONString(nil, name)
+	}
+}
+
+// valueEncoder is a json.Encoder bound to its own buffer, so that both can
+// be reused across messages.
+type valueEncoder struct {
+	buffer  bytes.Buffer
+	encoder *json.Encoder
+}
+
+// getBuffer takes an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+// putBuffer returns a buffer to the pool.
+func putBuffer(buffer *bytes.Buffer) {
+	if buffer.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buffer)
+	}
+}
+
+// encodeMessage appends the JSON representation of a Message to `buffer'. It
+// produces the same output as encoding/json does for the Message struct, but
+// writes the envelope by hand and only uses encoding/json for the arbitrary
+// values, which saves most allocations.
+func encodeMessage(buffer *bytes.Buffer, m *Message) error {
+	start := buffer.Len()
+	buffer.WriteString(`{"level":`)
+	if encoded, ok := encodedLevelNames[m.Level]; ok {
+		buffer.Write(encoded)
+	} else {
+		buffer.Write(appendJSONString(buffer.AvailableBuffer(), m.Level))
+	}
+	buffer.WriteString(`,"time":"`)
+	buffer.Write(m.Time.AppendFormat(buffer.AvailableBuffer(), time.RFC3339Nano))
+	buffer.WriteString(`","message":`)
+	buffer.Write(appendJSONString(buffer.AvailableBuffer(), m.Message))
+	if m.Logger != "" {
+		buffer.WriteString(`,"logger":`)
+		buffer.Write(appendJSONString(buffer.AvailableBuffer(), m.Logger))
+	}
+	if m.Data != nil {
+		buffer.WriteString(`,"data":`)
+		if err := encodeValue(buffer, m.Data); err != nil {
+			buffer.Truncate(start)
+			return err
+		}
+	}
+	if len(m.Context) > 0 {
+		buffer.WriteString(`,"context":`)
+		if err := encodeValue(buffer, m.Context); err != nil {
+			buffer.Truncate(start)
+			return err
+		}
+	}
+	if m.Error != nil {
+		buffer.WriteString(`,"error":`)
+		if err := encodeValue(buffer, m.Error); err != nil {
+			buffer.Truncate(start)
+			return err
+		}
+	}
+	if m.Stack != "" {
+		buffer.WriteString(`,"stack":`)
+		buffer.Write(appendJSONString(buffer.AvailableBuffer(), m.Stack))
+	}
+	if m.Repeated != 0 {
+		buffer.WriteString(`,"repeated":`)
+		buffer.Write(strconv.AppendInt(buffer.AvailableBuffer(), int64(m.Repeated), 10))
+	}
+	buffer.WriteString("}\n")
+	return nil
+}
+
+// encodeValue appends the JSON representation of an arbitrary value to
+// `buffer', without a trailing newline. Scalars, Data and maps of type
+// map[string]interface{}, by far the most common data, do not go through
+// encoding/json.
+func encodeValue(buffer *bytes.Buffer, value interface{}) error {
+	return encodeNestedValue(buffer, value, 0)
+}
+
+// encodeNestedValue is encodeValue for a value nested `depth' maps deep.
+func encodeNestedValue(buffer *bytes.Buffer, value interface{}, depth int) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteString("null")
+		return nil
+	case string:
+		buffer.Write(appendJSONString(buffer.AvailableBuffer(), v))
+		return nil
+	case bool:
+		buffer.Write(strconv.AppendBool(buffer.AvailableBuffer(), v))
+		return nil
+	case int:
+		buffer.Write(strconv.AppendInt(buffer.AvailableBuffer(), int64(v), 10))
+		return nil
+	case int64:
+		buffer.Write(strconv.AppendInt(buffer.AvailableBuffer(), v, 10))
+		return nil
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			buffer.Write(appendJSONFloat(buffer.AvailableBuffer(), v))
+			return nil
+		}
+	case map[string]interface{}:
+		if depth < maxEncodeDepth {
+			return encodeNestedMap(buffer, v, depth+1)
+		}
+	case Data:
+		if depth < maxEncodeDepth {
+			return encodeNestedMap(buffer, v, depth+1)
+		}
+	case RawMessage:
+		return appendRawMessage(buffer, v)
+	}
+	return encodeReflected(buffer, value)
+}
+
+// encodeMap appends a JSON object to `buffer', with its keys sorted like
+// encoding/json does.
+func encodeMap(buffer *bytes.Buffer, object map[string]interface{}) error {
+	return encodeNestedMap(buffer, object, 1)
+}
+
+// encodeNestedMap is encodeMap for a map nested `depth' maps deep, counting
+// itself.
+func encodeNestedMap(buffer *bytes.Buffer, object map[string]interface{}, depth int) error {
+	keysPointer := keysPool.Get().(*[]string)
+	defer keysPool.Put(keysPointer)
+	keys := (*keysPointer)[:0]
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	*keysPointer = keys
+	buffer.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(appendJSONString(buffer.AvailableBuffer(), key))
+		buffer.WriteByte(':')
+		if err := encodeNestedValue(buffer, object[key], depth); err != nil {
+			return err
+		}
+	}
+	buffer.WriteByte('}')
+	return nil
+}
+
+// encodeReflected appends the JSON representation of a value using
+// encoding/json.
+func encodeReflected(buffer *bytes.Buffer, value interface{}) error {
+	e := valueEncoderPool.Get().(*valueEncoder)
+	defer func() {
+		if e.buffer.Cap() <= maxPooledBufferSize {
+			valueEncoderPool.Put(e)
+		}
+	}()
+	e.buffer.Reset()
+	if err := e.encoder.Encode(value); err != nil {
+		return err
+	}
+	buffer.Write(e.buffer.Bytes()[:e.buffer.Len()-1])
+	return nil
+}
+
+// appendJSONFloat appends a finite float64 formatted like encoding/json does.
+func appendJSONFloat(dst []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9, as encoding/json does.
+		n := len(dst) - start
+		if n >= 4 && dst[len(dst)-4] == 'e' && dst[len(dst)-3] == '-' && dst[len(dst)-2] == '0' {
+			dst[len(dst)-2] = dst[len(dst)-1]
+			dst = dst[:len(dst)-1]
+		}
+	}
+	return dst
+}
+
+// appendJSONString appends `s' as a JSON string to `dst', escaping it exactly
+// like encoding/json does with HTML escaping enabled.
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,118 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestAppendJSONString tests that strings are escaped exactly like
+// encoding/json does.
+func TestAppendJSONString(t *testing.T) {
+	examples := []string{
+		"",
+		"plain",
+		"quotes \" and \\ backslashes",
+		"control \x00\x01\b\f\n\r\t\x1f\x7f",
+		"html <script>&</script>",
+		"unicode é 日本 \U0001F600",
+		"separators   ",
+		"invalid \xff\xfe utf-8 \xe2\x82",
+	}
+	for _, example := range examples {
+		expected, _ := json.Marshal(example)
+		if got := appendJSONString(nil, example); !bytes.Equal(got, expected) {
+			t.Errorf("Encoding %q gave %s but should give %s.", example, got, expected)
+		}
+	}
+}
+
+// TestEncodeValue tests that values on the fast path are encoded exactly
+// like encoding/json does.
+func TestEncodeValue(t *testing.T) {
+	examples := []interface{}{
+		nil,
+		true,
+		-42,
+		int64(1) << 60,
+		0.0,
+		1.5,
+		-1e-7,
+		1e21,
+		123456789.125,
+		map[string]interface{}{},
+		map[string]interface{}{"b": 1, "a": "x", "<": nil, "nested": map[string]interface{}{"z": 2.5, "y": []string{"q"}}},
+	}
+	for _, example := range examples {
+		expected, _ := json.Marshal(example)
+		got := new(bytes.Buffer)
+		if err := encodeValue(got, example); err != nil {
+			t.Errorf("Encoding %#v errored with '%s'.", example, err.Error())
+		} else if !bytes.Equal(got.Bytes(), expected) {
+			t.Errorf("Encoding %#v gave %s but should give %s.", example, got.Bytes(), expected)
+		}
+	}
+}
+
+// TestEncodeMessage tests that the hand-rolled encoder produces the same
+// output as encoding/json for the Message struct.
+func TestEncodeMessage(t *testing.T) {
+	examples := []Message{
+		Message{Level: "info", Time: time.Date(2017, 10, 5, 21, 7, 58, 115210089, time.Local), Message: "simple"},
+		Message{Level: "custom", Time: time.Now(), Message: "<data>", Logger: "server.http", Data: map[string]interface{}{"a": []int{1, 2}}},
+		Message{Level: "error", Time: time.Now(), Message: "all", Data: "string data", Context: map[string]interface{}{"k": 4.5},
+			Error: newErrorInfo(errors.New("boom")), Stack: "main.main\n\tmain.go:1\n", Repeated: 3},
+	}
+	for _, example := range examples {
+		expected := new(bytes.Buffer)
+		json.NewEncoder(expected).Encode(example)
+		got := new(bytes.Buffer)
+		if err := encodeMessage(got, &example); err != nil {
+			t.Errorf("Encoding errored with '%s'.", err.Error())
+		} else if got.String() != expected.String() {
+			t.Errorf("Encoding gave %s but should give %s.", got.String(), expected.String())
+		}
+	}
+}
+
+// TestEncodeMessageError tests that a failed encoding leaves the buffer
+// untouched.
+func TestEncodeMessageError(t *testing.T) {
+	buffer := bytes.NewBufferString("previous")
+	err := encodeMessage(buffer, &Message{Level: "info", Data: func() {}})
+	if err == nil {
+		t.Errorf("Encoding a function should error.")
+	}
+	if buffer.String() != "previous" {
+		t.Errorf("Buffer '%s' should be left untouched.", buffer.String())
+	}
+}
+
+// TestEncodeCyclicMap tests that cyclic maps, in the data or in static
+// fields, fail to encode instead of overflowing the stack, and that deep but
+// finite ones are encoded like encoding/json does.
+func TestEncodeCyclicMap(t *testing.T) {
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+	var unsupported *json.UnsupportedValueError
+	if err := DefaultLogger.WithWriter(new(bytes.Buffer)).Info("cyclic", cyclic); !errors.As(err, &unsupported) {
+		t.Errorf("Logging a cyclic map should fail with an UnsupportedValueError, got %v.", err)
+	}
+	static := DefaultLogger.WithWriter(new(bytes.Buffer)).WithStaticFields(map[string]interface{}{"cyclic": cyclic}).WithDeltaRecords(time.Minute)
+	if err := static.Info("cyclic", nil); !errors.As(err, &unsupported) {
+		t.Errorf("Logging with cyclic static fields should fail with an UnsupportedValueError, got %v.", err)
+	}
+	deep := map[string]interface{}{}
+	for i := 0; i < 2*maxEncodeDepth; i++ {
+		deep = map[string]interface{}{"next": deep}
+	}
+	expected, _ := json.Marshal(deep)
+	got := new(bytes.Buffer)
+	if err := encodeValue(got, deep); err != nil {
+		t.Errorf("Encoding a deep map errored with '%s'.", err.Error())
+	} else if got.String() != string(expected) {
+		t.Errorf("Encoding a deep map should give the output of encoding/json.")
+	}
+}
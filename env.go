@@ -0,0 +1,163 @@
+package jsonlog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	// EnvLevel is the environment variable holding the name of the log
+	// level, such as "debug".
+	EnvLevel = "JSONLOG_LEVEL"
+	// EnvFormat is the environment variable holding the name of the
+	// output format: "json", "logfmt" or "ecs".
+	EnvFormat = "JSONLOG_FORMAT"
+	// EnvCorrelationID is the environment variable holding the
+	// correlation ID shared by a process and its children.
+	EnvCorrelationID = "JSONLOG_CORRELATION_ID"
+	// EnvOutput is the environment variable holding the destination of
+	// the records: "stdout", "stderr" or the path of a file to append to.
+	EnvOutput = "JSONLOG_OUTPUT"
+
+	// correlationIDKey is the context key the correlation ID is output
+	// under.
+	correlationIDKey = "correlationId"
+)
+
+// formatterNames maps the names of the output formats to their formatter.
+var formatterNames = map[string]Formatter{
+	"json":   JSONFormatter{},
+	"logfmt": LogfmtFormatter{},
+	"ecs":    ECSFormatter,
+}
+
+// WithCorrelationID returns a new Logger whose messages carry `id' under
+// the "correlationId" context key, so that the records of cooperating
+// processes can be joined. An empty ID removes it.
+func (l Logger) WithCorrelationID(id string) Logger {
+	l.correlationID = id
+	return l
+}
+
+// CorrelationID returns the correlation ID of the Logger, if any.
+func (l Logger) CorrelationID() string {
+	return l.correlationID
+}
+
+// ChildEnv returns the environment variables passing the Logger's level,
+// format and correlation ID to a child process, in the "KEY=value" form of
+// os.Environ. A child created with NewFromEnv, or calling WithParentEnv, then
+// logs consistently with its parent. The level is passed by name, or by value
+// if it has none. The format is only passed for the default JSONFormatter,
+// the default LogfmtFormatter and ECSFormatter.
+//
+//	cmd := exec.Command("./worker")
+//	cmd.Env = append(os.Environ(), logger.ChildEnv()...)
+func (l Logger) ChildEnv() []string {
+	level := l.logLevel
+	if l.atomicLevel != nil {
+		level = l.atomicLevel.Level()
+	}
+	env := []string{EnvLevel + "=" + envLevel(level)}
+	if name, ok := formatterName(l.formatter); ok {
+		env = append(env, EnvFormat+"="+name)
+	}
+	if l.correlationID != "" {
+		env = append(env, EnvCorrelationID+"="+l.correlationID)
+	}
+	return env
+}
+
+// WithParentEnv returns a new Logger adopting the level, format and
+// correlation ID passed by a parent process with ChildEnv. Missing or
+// invalid variables leave the corresponding setting unchanged, so that the
+// process behaves the same when it is not started by a jsonlog parent.
+//
+//	logger := jsonlog.DefaultLogger.WithParentEnv()
+func (l Logger) WithParentEnv() Logger {
+	if level, err := parseEnvLevel(os.Getenv(EnvLevel)); err == nil {
+		l = l.WithLogLevel(level)
+	}
+	if formatter, ok := formatterNames[os.Getenv(EnvFormat)]; ok {
+		l.formatter = formatter
+	}
+	if id := os.Getenv(EnvCorrelationID); id != "" {
+		l.correlationID = id
+	}
+	return l
+}
+
+// envLevel returns the name of a log level, or its value if it has none, as
+// passed in EnvLevel.
+func envLevel(logLevel LogLevel) string {
+	if _, ok := logLevelNames[logLevel]; ok {
+		return logLevel.String()
+	}
+	return strconv.FormatUint(uint64(logLevel), 10)
+}
+
+// parseEnvLevel parses a log level passed in EnvLevel, by name or by value.
+func parseEnvLevel(s string) (LogLevel, error) {
+	if value, err := strconv.ParseUint(s, 10, 0); err == nil {
+		return LogLevel(value), nil
+	}
+	return ParseLogLevel(s)
+}
+
+// formatterName returns the name of a formatter of formatterNames.
+func formatterName(formatter Formatter) (string, bool) {
+	switch f := formatter.(type) {
+	case JSONFormatter:
+		if f == (JSONFormatter{}) {
+			return "json", true
+		} else if f == ECSFormatter {
+			return "ecs", true
+		}
+	case LogfmtFormatter:
+		if f == (LogfmtFormatter{}) {
+			return "logfmt", true
+		}
+	}
+	return "", false
+}
+
+// NewFromEnv creates a Logger from DefaultLogger configured by the
+// environment, so that services can configure logging at deploy time and
+// child processes adopt the settings passed by ChildEnv: EnvLevel sets the
+// level, EnvFormat the format, EnvCorrelationID the correlation ID and
+// EnvOutput the output. Unset variables keep the defaults; invalid ones make
+// it fail. A file output is opened in append mode and stays open for the life
+// of the process.
+func NewFromEnv() (Logger, error) {
+	l := DefaultLogger
+	if name := os.Getenv(EnvLevel); name != "" {
+		level, err := parseEnvLevel(name)
+		if err != nil {
+			return l, err
+		}
+		l = l.WithLogLevel(level)
+	}
+	if name := os.Getenv(EnvFormat); name != "" {
+		formatter, ok := formatterNames[name]
+		if !ok {
+			return l, fmt.Errorf("jsonlog: unknown format %q", name)
+		}
+		l.formatter = formatter
+	}
+	if id := os.Getenv(EnvCorrelationID); id != "" {
+		l.correlationID = id
+	}
+	switch output := os.Getenv(EnvOutput); output {
+	case "", "stdout":
+	case "stderr":
+		l.writer = os.Stderr
+	default:
+		file, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return l, err
+		}
+		l.writer = file
+	}
+	return l, nil
+}
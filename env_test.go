@@ -0,0 +1,124 @@
+package jsonlog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestChildEnv tests the variables passed to child processes.
+func TestChildEnv(t *testing.T) {
+	parent := DefaultLogger.WithLogLevel(LogLevelDebug).WithFormatter(LogfmtFormatter{}).WithCorrelationID("job-42")
+	env := parent.ChildEnv()
+	sort.Strings(env)
+	expected := []string{"JSONLOG_CORRELATION_ID=job-42", "JSONLOG_FORMAT=logfmt", "JSONLOG_LEVEL=debug"}
+	if strings.Join(env, " ") != strings.Join(expected, " ") {
+		t.Errorf("Child environment is %v but should be %v.", env, expected)
+	}
+	custom := DefaultLogger.WithFormatter(JSONFormatter{TimeLayout: TimeLayoutEpochMillis}).ChildEnv()
+	if len(custom) != 1 {
+		t.Errorf("Custom formatters should not be passed, got %v.", custom)
+	}
+}
+
+// TestWithParentEnv tests that a child adopts its parent's settings.
+func TestWithParentEnv(t *testing.T) {
+	for _, variable := range DefaultLogger.WithLogLevel(LogLevelWarning).WithFormatter(ECSFormatter).WithCorrelationID("job-42").ChildEnv() {
+		parts := strings.SplitN(variable, "=", 2)
+		t.Setenv(parts[0], parts[1])
+	}
+	buffer := new(bytes.Buffer)
+	child := DefaultLogger.WithWriter(buffer).WithParentEnv()
+	child.Info("below the level", nil)
+	child.Warning("adopted", nil)
+	output := buffer.String()
+	for _, expected := range []string{`"log":{"level":"warning"}`, `"labels":{"correlationId":"job-42"}`} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Output '%s' should contain '%s'.", output, expected)
+		}
+	}
+	if strings.Contains(output, "below the level") {
+		t.Errorf("Output '%s' should not contain the info message.", output)
+	}
+	if child.CorrelationID() != "job-42" {
+		t.Errorf("Correlation ID is '%s'.", child.CorrelationID())
+	}
+}
+
+// TestChildEnvLevels tests that registered and unnamed levels are passed to
+// children in a form they parse back.
+func TestChildEnvLevels(t *testing.T) {
+	for _, level := range []LogLevel{testLogLevelAudit, LogLevel(42)} {
+		for _, variable := range DefaultLogger.WithLogLevel(level).ChildEnv() {
+			parts := strings.SplitN(variable, "=", 2)
+			t.Setenv(parts[0], parts[1])
+		}
+		if child := DefaultLogger.WithParentEnv(); child.logLevel != level {
+			t.Errorf("Level %s was passed as %s.", level, child.logLevel)
+		}
+		if child, err := NewFromEnv(); err != nil || child.logLevel != level {
+			t.Errorf("Level %s was configured as %s, %v.", level, child.logLevel, err)
+		}
+	}
+}
+
+// TestWithParentEnvInvalid tests that invalid variables are ignored.
+func TestWithParentEnvInvalid(t *testing.T) {
+	t.Setenv(EnvLevel, "verbose")
+	t.Setenv(EnvFormat, "xml")
+	t.Setenv(EnvCorrelationID, "")
+	child := DefaultLogger.WithParentEnv()
+	if child.logLevel != DefaultLogger.logLevel || child.formatter != DefaultLogger.formatter || child.correlationID != "" {
+		t.Errorf("Invalid variables should leave the Logger unchanged.")
+	}
+}
+
+// TestNewFromEnv tests configuring a Logger from the environment.
+func TestNewFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	t.Setenv(EnvLevel, "debug")
+	t.Setenv(EnvFormat, "logfmt")
+	t.Setenv(EnvOutput, path)
+	t.Setenv(EnvCorrelationID, "job-42")
+	logger, err := NewFromEnv()
+	if err != nil {
+		t.Errorf("Configuring errored with '%s'.", err.Error())
+		return
+	}
+	if logger.CorrelationID() != "job-42" {
+		t.Errorf("Correlation ID is '%s'.", logger.CorrelationID())
+	}
+	logger.Debug("configured", nil)
+	logger.writer.(*os.File).Close()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("Reading the output errored with '%s'.", err.Error())
+	} else if !strings.Contains(string(content), "level=debug message=configured") {
+		t.Errorf("Output '%s' should hold the debug message in logfmt.", content)
+	}
+}
+
+type testNewFromEnvErrorExample struct {
+	variable string
+	value    string
+}
+
+// TestNewFromEnvErrors tests that invalid variables are reported.
+func TestNewFromEnvErrors(t *testing.T) {
+	examples := []testNewFromEnvErrorExample{
+		testNewFromEnvErrorExample{EnvLevel, "loud"},
+		testNewFromEnvErrorExample{EnvFormat, "xml"},
+		testNewFromEnvErrorExample{EnvOutput, filepath.Join(t.TempDir(), "missing", "service.log")},
+	}
+	for _, example := range examples {
+		t.Run(example.variable, func(t *testing.T) {
+			t.Setenv(example.variable, example.value)
+			if _, err := NewFromEnv(); err == nil {
+				t.Errorf("Configuring with %s=%s should fail.", example.variable, example.value)
+			}
+		})
+	}
+}
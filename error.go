@@ -0,0 +1,124 @@
+package jsonlog
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxStackDepth bounds the number of frames captured in a stack trace.
+	maxStackDepth = 64
+	// callerKey is the context key of the call site added by WithCaller.
+	callerKey = "caller"
+)
+
+// packagePath is the import path of this package, used to leave the
+// package's own frames out of stack traces.
+var packagePath = reflect.TypeOf(Logger{}).PkgPath()
+
+// ErrorInfo is the serialized form of an error attached to a Message.
+type ErrorInfo struct {
+	// Message is the result of the error's Error method.
+	Message string `json:"message"`
+	// Type is the dynamic type of the error, e.g. "*os.PathError".
+	Type string `json:"type"`
+	// Chain holds the messages of the errors found by repeatedly calling
+	// errors.Unwrap, outermost first.
+	Chain []string `json:"chain,omitempty"`
+}
+
+// newErrorInfo serializes an error and its unwrap chain.
+func newErrorInfo(err error) *ErrorInfo {
+	info := &ErrorInfo{
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+	}
+	for wrapped := errors.Unwrap(err); wrapped != nil; wrapped = errors.Unwrap(wrapped) {
+		info.Chain = append(info.Chain, wrapped.Error())
+	}
+	return info
+}
+
+// WithError returns a new Logger which attaches `err' to its messages, in the
+// "error" field. A nil error removes a previously attached one.
+func (l Logger) WithError(err error) Logger {
+	l.err = err
+	return l
+}
+
+// WithStackTrace returns a new Logger which captures the stack of the caller
+// in the "stack" field of its messages which have an error attached.
+func (l Logger) WithStackTrace(enabled bool) Logger {
+	l.stackTrace = enabled
+	return l
+}
+
+// WithCaller returns a new Logger which adds the file and line of the code
+// logging each message under the "caller" context key.
+func (l Logger) WithCaller(enabled bool) Logger {
+	l.caller = enabled
+	return l
+}
+
+// Err is a shorthand for logging `err' with level Error.
+func (l Logger) Err(err error, str string, data interface{}) error {
+	return l.WithError(err).Error(str, data)
+}
+
+// Err is a shorthand for logging an error on default logger.
+func Err(err error, str string, data interface{}) error {
+	return checkedDefaultLogger().Err(err, str, data)
+}
+
+// captureStack formats the stack of the goroutine, leaving out the frames
+// from this package so that the trace starts at the caller of the Logger.
+func captureStack() string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var builder strings.Builder
+	inPackage := true
+	for {
+		frame, more := frames.Next()
+		if inPackage && !isPackageFrame(frame) {
+			inPackage = false
+		}
+		if !inPackage {
+			fmt.Fprintf(&builder, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return builder.String()
+}
+
+// captureCaller returns the file, with its directory, and the line of the
+// first frame outside this package, such as "server/handler.go:42".
+func captureCaller() string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !isPackageFrame(frame) {
+			dir, file := path.Split(frame.File)
+			return path.Join(path.Base(dir), file) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// isPackageFrame tells whether a frame belongs to the code of this package or
+// of its subpackages, such as the logging adapters, as opposed to its callers.
+func isPackageFrame(frame runtime.Frame) bool {
+	return (strings.HasPrefix(frame.Function, packagePath+".") || strings.HasPrefix(frame.Function, packagePath+"/")) &&
+		!strings.HasSuffix(frame.File, "_test.go")
+}
@@ -0,0 +1,69 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestErr tests logging an error with its unwrap chain and stack trace.
+func TestErr(t *testing.T) {
+	cause := errors.New("disk full")
+	wrapped := fmt.Errorf("saving user: %w", cause)
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithStackTrace(true)
+	err := logger.Err(wrapped, "Failed to save.", nil)
+	if err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+		return
+	}
+	output := Message{}
+	err = json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		return
+	}
+	if output.Level != "error" {
+		t.Errorf("Output log level '%s' should be 'error'.", output.Level)
+	}
+	if output.Error == nil || output.Error.Message != wrapped.Error() {
+		t.Errorf("Output error %v should have message '%s'.", output.Error, wrapped.Error())
+	} else if len(output.Error.Chain) != 1 || output.Error.Chain[0] != cause.Error() {
+		t.Errorf("Output error chain %v should be ['%s'].", output.Error.Chain, cause.Error())
+	}
+	if !strings.HasPrefix(output.Stack, packagePath+".TestErr\n") {
+		t.Errorf("Output stack should start at the caller:\n%s", output.Stack)
+	}
+}
+
+// TestWithErrorNoStack tests that no stack is captured unless enabled.
+func TestWithErrorNoStack(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithError(errors.New("oops"))
+	logger.Warning("log", nil)
+	output := Message{}
+	err := json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+	} else if output.Error == nil || output.Stack != "" {
+		t.Errorf("Output should have an error %v and no stack '%s'.", output.Error, output.Stack)
+	}
+}
+
+// TestWithCaller tests that the call site is added to the context.
+func TestWithCaller(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	_, _, line, _ := runtime.Caller(0)
+	DefaultLogger.WithWriter(buffer).WithCaller(true).Info("here", nil)
+	output := Message{}
+	if err := json.Unmarshal(buffer.Bytes(), &output); err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+	} else if caller, _ := output.Context["caller"].(string); !strings.HasSuffix(caller, "/error_test.go:"+strconv.Itoa(line+1)) {
+		t.Errorf("Caller '%s' should be the line of the call.", caller)
+	}
+}
@@ -0,0 +1,111 @@
+package jsonlog
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Classification is the redaction class of a field of an event.
+type Classification int
+
+const (
+	// ClassificationPublic fields are logged as they are.
+	ClassificationPublic Classification = iota
+	// ClassificationSensitive fields are logged as RedactedValue, so that
+	// their presence is visible but not their value.
+	ClassificationSensitive
+	// ClassificationSecret fields are left out of records.
+	ClassificationSecret
+)
+
+const (
+	// RedactedValue replaces the value of sensitive fields.
+	RedactedValue = "[REDACTED]"
+	// eventKey is the context key of the name of events.
+	eventKey = "event"
+)
+
+// ErrUnknownEvent is returned when logging an event whose schema was not
+// registered.
+var ErrUnknownEvent = errors.New("jsonlog: unknown event")
+
+// EventField describes a field of an event.
+type EventField struct {
+	Name string
+	// Type is the Go type of the field, for documentation.
+	Type           string
+	Classification Classification
+}
+
+// EventSchema describes an event: its level, its message and its fields.
+// Schemas are usually registered by code generated by jsonlog-gen from
+// annotated structs, so that the events logged by a service can be listed.
+type EventSchema struct {
+	Name    string
+	Level   LogLevel
+	Message string
+	Fields  []EventField
+}
+
+var (
+	// eventSchemasMutex guards eventSchemas.
+	eventSchemasMutex sync.RWMutex
+	// eventSchemas are the registered schemas by name.
+	eventSchemas = map[string]EventSchema{}
+)
+
+// RegisterEvent registers the schema of an event, replacing any schema with
+// the same name.
+func RegisterEvent(schema EventSchema) {
+	eventSchemasMutex.Lock()
+	defer eventSchemasMutex.Unlock()
+	eventSchemas[schema.Name] = schema
+}
+
+// EventSchemas returns the registered schemas, sorted by name.
+func EventSchemas() []EventSchema {
+	eventSchemasMutex.RLock()
+	defer eventSchemasMutex.RUnlock()
+	schemas := make([]EventSchema, 0, len(eventSchemas))
+	for _, schema := range eventSchemas {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Name < schemas[j].Name })
+	return schemas
+}
+
+// LookupEvent returns the schema of the event with the given name.
+func LookupEvent(name string) (EventSchema, bool) {
+	eventSchemasMutex.RLock()
+	defer eventSchemasMutex.RUnlock()
+	schema, ok := eventSchemas[name]
+	return schema, ok
+}
+
+// LogEvent logs an event of a registered schema, with its level and message,
+// its name under the "event" context key, and `fields' as the data. Fields
+// are redacted according to their classification in the schema; fields
+// absent from the schema are logged as they are.
+func (l Logger) LogEvent(name string, fields map[string]interface{}) error {
+	schema, ok := LookupEvent(name)
+	if !ok {
+		return ErrUnknownEvent
+	}
+	data := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		data[key] = value
+	}
+	for _, field := range schema.Fields {
+		if _, ok := data[field.Name]; !ok {
+			continue
+		}
+		switch field.Classification {
+		case ClassificationSensitive:
+			data[field.Name] = RedactedValue
+		case ClassificationSecret:
+			delete(data, field.Name)
+		}
+	}
+	return l.WithStaticFields(map[string]interface{}{eventKey: name}).Log(schema.Level, schema.Message, data)
+}
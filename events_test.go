@@ -0,0 +1,56 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestLogEvent tests that events are logged with their schema and redacted
+// according to the classification of their fields.
+func TestLogEvent(t *testing.T) {
+	RegisterEvent(EventSchema{
+		Name:    "TestUserCreated",
+		Level:   LogLevelInfo,
+		Message: "User created.",
+		Fields: []EventField{
+			{Name: "userId", Type: "string"},
+			{Name: "email", Type: "string", Classification: ClassificationSensitive},
+			{Name: "token", Type: "string", Classification: ClassificationSecret},
+		},
+	})
+	if schema, ok := LookupEvent("TestUserCreated"); !ok || len(schema.Fields) != 3 {
+		t.Errorf("The schema should have been registered with its fields, got %#v.", schema)
+	}
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer)
+	fields := map[string]interface{}{"userId": "42", "email": "jane@example.com", "token": "s3cr3t"}
+	if err := logger.LogEvent("TestUserCreated", fields); err != nil {
+		t.Errorf("Logging the event errored with '%s'.", err.Error())
+	}
+	var record struct {
+		Level   string                 `json:"level"`
+		Message string                 `json:"message"`
+		Context map[string]interface{} `json:"context"`
+		Data    map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+		t.Errorf("Decoding '%s' errored with '%s'.", buffer.String(), err.Error())
+	} else {
+		if record.Level != "info" || record.Message != "User created." || record.Context[eventKey] != "TestUserCreated" {
+			t.Errorf("Record '%s' should have the level, message and name of the event.", buffer.String())
+		}
+		if record.Data["userId"] != "42" || record.Data["email"] != RedactedValue {
+			t.Errorf("Record '%s' should have the public field and the redacted sensitive one.", buffer.String())
+		}
+		if _, ok := record.Data["token"]; ok {
+			t.Errorf("Record '%s' should not have the secret field.", buffer.String())
+		}
+	}
+	if fields["email"] != "jane@example.com" {
+		t.Errorf("The fields passed to LogEvent should not be modified.")
+	}
+	if err := logger.LogEvent("TestUnknown", nil); err != ErrUnknownEvent {
+		t.Errorf("Logging an unregistered event should return ErrUnknownEvent, got %v.", err)
+	}
+}
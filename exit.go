@@ -0,0 +1,167 @@
+package jsonlog
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ExitCause is the reason a process exits, as logged by an ExitTracker.
+type ExitCause string
+
+const (
+	// ExitNormal is an exit without error.
+	ExitNormal = ExitCause("normal")
+	// ExitError is an exit because of an error.
+	ExitError = ExitCause("error")
+	// ExitSignal is an exit because of a signal.
+	ExitSignal = ExitCause("signal")
+	// ExitPanic is an exit because of a panic.
+	ExitPanic = ExitCause("panic")
+)
+
+// exitMessage is the message of the exit record.
+const exitMessage = "Process exiting."
+
+// processStart is the time the package was initialized, which is close to
+// the start of the process.
+var processStart = time.Now()
+
+// ExitTracker logs a final record when the process exits, describing the
+// cause of the exit, the exit code and the uptime, so that the reason of a
+// restart can be found in the logs alone. It is wired to the exit paths of
+// main:
+//
+//	func main() {
+//		tracker := logger.TrackExit(syscall.SIGTERM, os.Interrupt)
+//		defer tracker.RecoverPanic()
+//		tracker.Exit(run())
+//	}
+//
+// Only the first exit is logged. Normal exits are logged at the info level,
+// signals at the warning level, and errors and panics at the error level.
+type ExitTracker struct {
+	logger  Logger
+	signals chan os.Signal
+	done    chan struct{}
+	mutex   sync.Mutex
+	exited  bool
+}
+
+// exitRecord is the data of the exit record.
+type exitRecord struct {
+	Cause    ExitCause `json:"cause"`
+	Code     int       `json:"exit_code"`
+	Signal   string    `json:"signal,omitempty"`
+	Panic    string    `json:"panic,omitempty"`
+	UptimeMs float64   `json:"uptime_ms"`
+}
+
+// TrackExit returns an ExitTracker logging through `l'. If signals are given,
+// receiving one of them logs the exit and exits the process with status 128
+// plus the number of the signal, as shells report it. The process exits
+// through the exit function of the Logger, which WithExitFunc can replace.
+func (l Logger) TrackExit(signals ...os.Signal) *ExitTracker {
+	t := &ExitTracker{logger: l, done: make(chan struct{})}
+	if len(signals) > 0 {
+		t.signals = make(chan os.Signal, 1)
+		signal.Notify(t.signals, signals...)
+		go t.watchSignals()
+	}
+	return t
+}
+
+// watchSignals exits when a signal is received.
+func (t *ExitTracker) watchSignals() {
+	select {
+	case received := <-t.signals:
+		code := 1
+		if number, ok := received.(syscall.Signal); ok {
+			code = 128 + int(number)
+		}
+		t.exit(LogLevelWarning, exitRecord{Cause: ExitSignal, Code: code, Signal: received.String()}, nil)
+	case <-t.done:
+	}
+}
+
+// Exit logs the exit and exits the process: with status 0 if `err' is nil,
+// and with status 1 and `err' as the error of the record otherwise.
+func (t *ExitTracker) Exit(err error) {
+	if err != nil {
+		t.ExitCode(1, err)
+	} else {
+		t.ExitCode(0, nil)
+	}
+}
+
+// ExitCode logs the exit and exits the process with status `code'. The cause
+// is an error if `code' is not 0.
+func (t *ExitTracker) ExitCode(code int, err error) {
+	record := exitRecord{Cause: ExitNormal, Code: code}
+	logLevel := LogLevelInfo
+	if code != 0 || err != nil {
+		record.Cause = ExitError
+		logLevel = LogLevelError
+	}
+	t.exit(logLevel, record, err)
+}
+
+// RecoverPanic logs the exit if the goroutine is panicking, then resumes the
+// panic. It must be deferred directly in main.
+func (t *ExitTracker) RecoverPanic() {
+	if recovered := recover(); recovered != nil {
+		t.log(LogLevelError, exitRecord{Cause: ExitPanic, Code: 2, Panic: fmt.Sprint(recovered)}, nil)
+		panic(recovered)
+	}
+}
+
+// Stop stops watching signals, without logging anything.
+func (t *ExitTracker) Stop() {
+	if t.signals != nil {
+		signal.Stop(t.signals)
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	select {
+	case <-t.done:
+	default:
+		close(t.done)
+	}
+}
+
+// exit logs the exit and exits the process, unless the exit was already
+// logged.
+func (t *ExitTracker) exit(logLevel LogLevel, record exitRecord, err error) {
+	if !t.log(logLevel, record, err) {
+		return
+	}
+	if t.logger.exitFunc != nil {
+		t.logger.exitFunc(record.Code)
+	} else {
+		os.Exit(record.Code)
+	}
+}
+
+// log logs the exit record and flushes the writer, and reports whether this
+// is the first exit.
+func (t *ExitTracker) log(logLevel LogLevel, record exitRecord, err error) bool {
+	t.mutex.Lock()
+	first := !t.exited
+	t.exited = true
+	t.mutex.Unlock()
+	if !first {
+		return false
+	}
+	t.Stop()
+	record.UptimeMs = float64(t.logger.now().Sub(processStart)) / float64(time.Millisecond)
+	logger := t.logger
+	if err != nil {
+		logger = logger.WithError(err)
+	}
+	logger.Log(logLevel, exitMessage, record)
+	logger.flush()
+	return true
+}
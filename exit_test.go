@@ -0,0 +1,76 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// testExitExample is a way of exiting and the record it should log.
+type testExitExample struct {
+	name   string
+	exit   func(t *ExitTracker)
+	level  string
+	cause  ExitCause
+	code   int
+	error  bool
+	signal string
+}
+
+// TestExitTracker tests the record logged for each cause of exit.
+func TestExitTracker(t *testing.T) {
+	examples := []testExitExample{
+		{"normal", func(t *ExitTracker) { t.Exit(nil) }, "info", ExitNormal, 0, false, ""},
+		{"error", func(t *ExitTracker) { t.Exit(errors.New("config missing")) }, "error", ExitError, 1, true, ""},
+		{"code", func(t *ExitTracker) { t.ExitCode(3, nil) }, "error", ExitError, 3, false, ""},
+		{"signal", func(t *ExitTracker) { t.signals <- syscall.SIGTERM }, "warning", ExitSignal, 128 + int(syscall.SIGTERM), false, syscall.SIGTERM.String()},
+		{"panic", func(t *ExitTracker) {
+			defer func() { recover() }()
+			defer t.RecoverPanic()
+			panic("nil map")
+		}, "error", ExitPanic, 2, false, ""},
+	}
+	for _, example := range examples {
+		buffer := new(bytes.Buffer)
+		codes := make(chan int, 2)
+		logger := DefaultLogger.WithWriter(buffer).WithExitFunc(func(code int) { codes <- code })
+		tracker := logger.TrackExit(os.Interrupt)
+		example.exit(tracker)
+		if example.cause != ExitPanic {
+			select {
+			case code := <-codes:
+				if code != example.code {
+					t.Errorf("%s: the process exited with %d but should have with %d.", example.name, code, example.code)
+				}
+			case <-time.After(5 * time.Second):
+				t.Errorf("%s: the process should have exited.", example.name)
+			}
+		}
+		tracker.Exit(nil)
+		var record struct {
+			Level string      `json:"level"`
+			Data  exitRecord  `json:"data"`
+			Error interface{} `json:"error"`
+		}
+		if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+			t.Errorf("%s: decoding '%s' errored with '%s'.", example.name, buffer.String(), err.Error())
+			continue
+		}
+		if record.Level != example.level || record.Data.Cause != example.cause || record.Data.Code != example.code || record.Data.Signal != example.signal {
+			t.Errorf("%s: record '%s' does not describe the exit.", example.name, buffer.String())
+		}
+		if (record.Error != nil) != example.error || record.Data.UptimeMs <= 0 {
+			t.Errorf("%s: record '%s' should have the uptime and the error.", example.name, buffer.String())
+		}
+		if !bytes.Contains(buffer.Bytes(), []byte(`"exit_code":`)) {
+			t.Errorf("%s: record '%s' should have the exit code under \"exit_code\".", example.name, buffer.String())
+		}
+		if len(codes) != 0 {
+			t.Errorf("%s: only the first exit should exit the process.", example.name)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package jsonlog
+
+import (
+	"io"
+)
+
+// OnError returns a new Logger calling `handler' whenever one of its
+// messages cannot be formatted or written, for example because the disk is
+// full or a network sink is down, with the error and the message. The log
+// call still returns the error, but callers often ignore it; the handler
+// makes such failures observable. A nil handler removes it.
+func (l Logger) OnError(handler func(err error, m Message)) Logger {
+	l.errorHandler = handler
+	return l
+}
+
+// WithFallbackWriter returns a new Logger writing its records to `w', such as
+// os.Stderr, when writing them to its writer failed, so that they are not
+// lost. The log call still returns the error of the primary writer. A nil
+// writer removes the fallback.
+func (l Logger) WithFallbackWriter(w io.Writer) Logger {
+	l.fallbackWriter = w
+	return l
+}
+
+// handleWriteError writes a record which could not be written to the
+// fallback writer, and reports the failure to the error handler.
+func (l Logger) handleWriteError(err error, m *Message, record []byte) {
+	if l.fallbackWriter != nil {
+		writeRecord(l.fallbackWriter, m.logLevel, record)
+	}
+	l.handleError(err, m)
+}
+
+// handleError reports a failure to the error handler, if any.
+func (l Logger) handleError(err error, m *Message) {
+	if l.errorHandler != nil {
+		l.errorHandler(err, *m)
+	}
+}
@@ -0,0 +1,45 @@
+package jsonlog
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// TestFallbackWriter tests that records which could not be written go to
+// the fallback writer and that the failure is reported.
+func TestFallbackWriter(t *testing.T) {
+	fallback := new(bytes.Buffer)
+	var errs []error
+	var messages []Message
+	logger := DefaultLogger.WithWriter(failingWriter{}).WithFallbackWriter(fallback).OnError(func(err error, m Message) {
+		errs = append(errs, err)
+		messages = append(messages, m)
+	})
+	if err := logger.Error("Disk is full.", nil); err == nil || err.Error() != "disk full" {
+		t.Errorf("Logging should return the primary writer's error, got %v.", err)
+	}
+	if !strings.Contains(fallback.String(), `"message":"Disk is full."`) {
+		t.Errorf("Fallback output '%s' should hold the record.", fallback.String())
+	}
+	if len(errs) != 1 || messages[0].Message != "Disk is full." {
+		t.Errorf("The handler should be called once with the message, got %v.", errs)
+	}
+}
+
+// TestOnErrorEncoding tests that formatting failures are reported.
+func TestOnErrorEncoding(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	called := false
+	logger := DefaultLogger.WithWriter(buffer).WithFallbackWriter(buffer).OnError(func(err error, m Message) {
+		called = true
+	})
+	logger.Info("bad float", map[string]interface{}{"ratio": math.NaN()})
+	if !called {
+		t.Errorf("The handler should be called on formatting failures.")
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("Nothing should be written, got '%s'.", buffer.String())
+	}
+}
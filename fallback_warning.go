@@ -0,0 +1,55 @@
+package jsonlog
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// fallbackWarnings is 1 when falling back to DefaultLogger is warned
+	// about.
+	fallbackWarnings int32
+	// warnedFallbackCallers holds the call sites already warned about.
+	warnedFallbackCallers sync.Map
+)
+
+// SetFallbackWarnings enables or disables warnings when
+// LoggerFromContextOrDefault falls back to DefaultLogger because the context
+// holds no Logger, which hides missing propagation. When enabled, a warning
+// is logged with DefaultLogger once per call site, with the caller's file and
+// line under "caller" in the data. Warnings are disabled by default.
+// Disabling them forgets the call sites already warned about.
+func SetFallbackWarnings(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&fallbackWarnings, value)
+	if !enabled {
+		warnedFallbackCallers.Range(func(caller, _ interface{}) bool {
+			warnedFallbackCallers.Delete(caller)
+			return true
+		})
+	}
+}
+
+// warnFallback logs the warning about a fallback to DefaultLogger, if
+// enabled and not already done for the caller of LoggerFromContextOrDefault.
+func warnFallback() {
+	if atomic.LoadInt32(&fallbackWarnings) == 0 {
+		return
+	}
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return
+	}
+	caller := file + ":" + strconv.Itoa(line)
+	if _, warned := warnedFallbackCallers.LoadOrStore(caller, struct{}{}); warned {
+		return
+	}
+	DefaultLogger.Warning("jsonlog: no Logger in context, falling back to DefaultLogger", map[string]interface{}{
+		"caller": caller,
+	})
+}
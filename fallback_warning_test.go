@@ -0,0 +1,47 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestLoggerFromContext tests retrieving a Logger from a context.
+func TestLoggerFromContext(t *testing.T) {
+	logger := DefaultLogger.Named("request")
+	if got, ok := LoggerFromContext(ContextWithLogger(context.Background(), logger)); !ok || got.name != "request" {
+		t.Errorf("The Logger in the context should be found.")
+	}
+	if _, ok := LoggerFromContext(context.Background()); ok {
+		t.Errorf("No Logger should be found in an empty context.")
+	}
+}
+
+// TestFallbackWarnings tests that falling back to DefaultLogger is warned
+// about once per call site when enabled.
+func TestFallbackWarnings(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	previous := DefaultLogger
+	DefaultLogger = DefaultLogger.WithWriter(buffer)
+	defer func() {
+		DefaultLogger = previous
+		SetFallbackWarnings(false)
+	}()
+	LoggerFromContextOrDefault(context.Background())
+	if buffer.Len() != 0 {
+		t.Errorf("Nothing should be logged by default, got '%s'.", buffer.String())
+	}
+	SetFallbackWarnings(true)
+	for i := 0; i < 3; i++ {
+		LoggerFromContextOrDefault(context.Background())
+	}
+	LoggerFromContextOrDefault(context.Background())
+	output := buffer.String()
+	if count := strings.Count(output, "falling back to DefaultLogger"); count != 2 {
+		t.Errorf("Output '%s' should hold one warning per call site, got %d.", output, count)
+	}
+	if !strings.Contains(output, `fallback_warning_test.go:`) {
+		t.Errorf("Output '%s' should name the caller.", output)
+	}
+}
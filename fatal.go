@@ -0,0 +1,76 @@
+package jsonlog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// flusher is implemented by writers which buffer their output.
+type flusher interface {
+	Flush() error
+}
+
+// syncer is implemented by writers backed by a file, such as *os.File.
+type syncer interface {
+	Sync() error
+}
+
+// Panic logs with level Panic, bypassing sampling, then panics with `str'.
+func (l Logger) Panic(str string, data interface{}) {
+	l.Log(LogLevelPanic, str, data)
+	l.flush()
+	panic(str)
+}
+
+// Fatal logs with level Fatal, bypassing sampling, then exits the process
+// with status 1. The exit can be intercepted with WithExitFunc.
+func (l Logger) Fatal(str string, data interface{}) {
+	l.Log(LogLevelFatal, str, data)
+	l.flush()
+	if l.exitFunc != nil {
+		l.exitFunc(1)
+	} else {
+		os.Exit(1)
+	}
+}
+
+// Panic is a shorthand for panic logging on default logger.
+func Panic(str string, data interface{}) { checkedDefaultLogger().Panic(str, data) }
+
+// Fatal is a shorthand for fatal logging on default logger.
+func Fatal(str string, data interface{}) { checkedDefaultLogger().Fatal(str, data) }
+
+// WithExitFunc returns a new Logger calling `exit' instead of os.Exit from
+// Fatal. It lets tests intercept the exit; a nil function restores os.Exit.
+func (l Logger) WithExitFunc(exit func(code int)) Logger {
+	l.exitFunc = exit
+	return l
+}
+
+// Sync flushes the writer of the Logger if it buffers its output, or syncs
+// it if it is a file, as Fatal does before exiting.
+func (l Logger) Sync() error {
+	return syncWriter(l.writer)
+}
+
+// flush makes sure buffered output reaches its destination before the
+// process dies.
+func (l Logger) flush() {
+	l.Sync()
+}
+
+// syncWriter flushes `w' if it buffers its output, or syncs it if it is
+// backed by a file.
+func syncWriter(w io.Writer) error {
+	if f, ok := w.(flusher); ok {
+		return f.Flush()
+	} else if s, ok := w.(syncer); ok {
+		// Terminals and pipes cannot be synced; that is not a failure.
+		if err := s.Sync(); !errors.Is(err, syscall.EINVAL) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestFatal tests that Fatal logs, bypasses sampling and calls the exit
+// function.
+func TestFatal(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	exitCode := -1
+	sampler := NewSampler(time.Hour, map[LogLevel]SamplingPolicy{
+		LogLevelFatal: SamplingPolicy{First: 0, Thereafter: 0},
+	})
+	logger := DefaultLogger.WithWriter(buffer).WithSampler(sampler).WithExitFunc(func(code int) {
+		exitCode = code
+	})
+	logger.Fatal("fatal", nil)
+	if exitCode != 1 {
+		t.Errorf("Exit code is %d but should be 1.", exitCode)
+	}
+	output := Message{}
+	err := json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+	} else if output.Level != "fatal" {
+		t.Errorf("Output log level '%s' should be 'fatal'.", output.Level)
+	}
+}
+
+// TestPanic tests that Panic logs then panics with the message.
+func TestPanic(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer)
+	defer func() {
+		recovered := recover()
+		if recovered != "panic message" {
+			t.Errorf("Recovered '%v' but should be 'panic message'.", recovered)
+		}
+		output := Message{}
+		err := json.Unmarshal(buffer.Bytes(), &output)
+		if err != nil {
+			t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		} else if output.Level != "panic" {
+			t.Errorf("Output log level '%s' should be 'panic'.", output.Level)
+		}
+	}()
+	logger.Panic("panic message", nil)
+}
+
+// TestSync tests that Sync flushes a buffering writer.
+func TestSync(t *testing.T) {
+	recorder := &writeRecorder{}
+	sink := NewBatchingSink(recorder, 0, 0)
+	logger := DefaultLogger.WithWriter(sink)
+	logger.Info("buffered", nil)
+	if recorder.records() != 0 {
+		t.Errorf("The record should be buffered until Sync.")
+	}
+	if err := logger.Sync(); err != nil {
+		t.Errorf("Syncing errored with '%s'.", err.Error())
+	}
+	if recorder.records() != 1 {
+		t.Errorf("Sync should have flushed the record.")
+	}
+}
@@ -0,0 +1,119 @@
+package jsonlog
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Named returns a new Logger for the named component, such as "db" or "http".
+// Names nest: the name of a named Logger's child is dot-joined to its own, as
+// in "server.http.router". Messages carry the name in a "logger" field, and
+// level overrides set with WithLevelOverrides apply to it.
+func (l Logger) Named(name string) Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	l.name = name
+	return l
+}
+
+// WithLevelOverrides returns a new Logger whose messages are filtered with a
+// minimum level depending on their component, for example to silence a
+// chatty subsystem without losing debug messages elsewhere. An override
+// applies to the component and its children: "server" covers
+// "server.http.router" unless "server.http" has its own override. Components
+// without an override keep the Logger's level.
+//
+//	logger = logger.WithLevelOverrides(map[string]jsonlog.LogLevel{
+//		"db":   jsonlog.LogLevelWarning,
+//		"http": jsonlog.LogLevelDebug,
+//	})
+func (l Logger) WithLevelOverrides(overrides map[string]LogLevel) Logger {
+	l.levelOverrides = make(map[string]LogLevel, len(overrides))
+	for component, level := range overrides {
+		l.levelOverrides[component] = level
+	}
+	return l
+}
+
+// WithComponentKey returns a new Logger which, when it has no name, takes the
+// component of its messages from a context value or a top-level data field.
+// `key' is the message key of the context value, as given to WithContextKey,
+// or the key of the data field. The value must be a string.
+func (l Logger) WithComponentKey(key string) Logger {
+	l.componentKey = key
+	return l
+}
+
+// WithFilter returns a new Logger which only outputs the messages for which
+// `filter' returns true, replacing any previous filter. The filter is only
+// called for messages which pass the level checks. A nil filter removes it.
+func (l Logger) WithFilter(filter func(level LogLevel, message string, data interface{}) bool) Logger {
+	l.filter = filter
+	return l
+}
+
+// minimumLevel returns the level below which a message is not output,
+// taking the overrides of its component into account.
+func (l Logger) minimumLevel(data interface{}) LogLevel {
+	if len(l.levelOverrides) > 0 {
+		for component := l.component(data); component != ""; component = parentComponent(component) {
+			if level, ok := l.levelOverrides[component]; ok {
+				return level
+			}
+		}
+	}
+	if l.atomicLevel != nil {
+		return l.atomicLevel.Level()
+	}
+	return l.logLevel
+}
+
+// component returns the component of a message: the Logger's name, or else
+// the value found under its component key.
+func (l Logger) component(data interface{}) string {
+	if l.name != "" || l.componentKey == "" {
+		return l.name
+	}
+	for contextKey, messageKey := range l.contextKeys {
+		if messageKey == l.componentKey && l.context != nil {
+			if name, ok := componentName(l.context.Value(contextKey)); ok {
+				return name
+			}
+		}
+	}
+	if object, ok := data.(map[string]interface{}); ok {
+		name, _ := componentName(object[l.componentKey])
+		return name
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String {
+		if value := v.MapIndex(reflect.ValueOf(l.componentKey).Convert(v.Type().Key())); value.IsValid() {
+			name, _ := componentName(value.Interface())
+			return name
+		}
+	}
+	return ""
+}
+
+// parentComponent returns the name of the parent of a component, or an empty
+// string for top-level components.
+func parentComponent(component string) string {
+	if i := strings.LastIndexByte(component, '.'); i >= 0 {
+		return component[:i]
+	}
+	return ""
+}
+
+// componentName converts the value holding a component name to a string.
+func componentName(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case fmt.Stringer:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,100 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type testFilterContextKey struct{}
+
+type testFilterExample struct {
+	logger   Logger
+	level    LogLevel
+	data     interface{}
+	expected bool
+}
+
+// TestLevelOverrides tests that component levels override the Logger's
+// level, whether the component comes from the name, the context or the data.
+func TestLevelOverrides(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithLogLevel(LogLevelInfo).WithLevelOverrides(map[string]LogLevel{
+		"db":   LogLevelWarning,
+		"http": LogLevelDebug,
+	}).WithComponentKey("component")
+	ctx := context.WithValue(context.Background(), testFilterContextKey{}, "db")
+	fromContext := logger.WithContext(ctx).WithContextKey(testFilterContextKey{}, "component")
+	examples := []testFilterExample{
+		testFilterExample{logger, LogLevelDebug, nil, false},
+		testFilterExample{logger, LogLevelInfo, nil, true},
+		testFilterExample{logger.Named("db"), LogLevelInfo, nil, false},
+		testFilterExample{logger.Named("db"), LogLevelWarning, nil, true},
+		testFilterExample{logger.Named("http"), LogLevelDebug, nil, true},
+		testFilterExample{logger.Named("http"), LogLevelInfo, map[string]interface{}{"component": "db"}, true},
+		testFilterExample{logger, LogLevelDebug, map[string]interface{}{"component": "http"}, true},
+		testFilterExample{logger, LogLevelInfo, map[string]string{"component": "db"}, false},
+		testFilterExample{logger, LogLevelInfo, map[string]interface{}{"component": 42}, true},
+		testFilterExample{fromContext, LogLevelInfo, nil, false},
+		testFilterExample{fromContext, LogLevelError, nil, true},
+	}
+	for i, example := range examples {
+		buffer.Reset()
+		if err := example.logger.Log(example.level, "message", example.data); err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+		} else if logged := buffer.Len() > 0; logged != example.expected {
+			t.Errorf("Example %d logged %t but should have logged %t.", i, logged, example.expected)
+		}
+	}
+}
+
+// TestFilter tests that the predicate filter drops messages.
+func TestFilter(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithFilter(func(level LogLevel, message string, data interface{}) bool {
+		return !strings.HasPrefix(message, "health")
+	})
+	logger.Info("healthcheck ok", nil)
+	logger.Info("request served", nil)
+	logger.Debug("filtered by level first", nil)
+	if output := buffer.String(); strings.Contains(output, "health") || !strings.Contains(output, "request served") {
+		t.Errorf("Output '%s' should only contain the request message.", output)
+	}
+	buffer.Reset()
+	logger.WithFilter(nil).Info("healthcheck ok", nil)
+	if buffer.Len() == 0 {
+		t.Errorf("Removing the filter should output the message.")
+	}
+}
+
+// TestNamedHierarchy tests that names nest, are output, and that overrides
+// apply to the children of a component.
+func TestNamedHierarchy(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	server := DefaultLogger.WithWriter(buffer).WithLevelOverrides(map[string]LogLevel{
+		"server":      LogLevelWarning,
+		"server.http": LogLevelDebug,
+	}).Named("server")
+	examples := []testFilterExample{
+		testFilterExample{server, LogLevelInfo, nil, false},
+		testFilterExample{server.Named("db"), LogLevelInfo, nil, false},
+		testFilterExample{server.Named("db"), LogLevelError, nil, true},
+		testFilterExample{server.Named("http"), LogLevelDebug, nil, true},
+		testFilterExample{server.Named("http").Named("router"), LogLevelDebug, nil, true},
+		testFilterExample{server.Named("httpd"), LogLevelInfo, nil, false},
+	}
+	for i, example := range examples {
+		buffer.Reset()
+		if err := example.logger.Log(example.level, "message", example.data); err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+		} else if logged := buffer.Len() > 0; logged != example.expected {
+			t.Errorf("Example %d logged %t but should have logged %t.", i, logged, example.expected)
+		}
+	}
+	buffer.Reset()
+	server.Named("http").Named("router").Warning("message", nil)
+	if expected := `"message":"message","logger":"server.http.router"`; !strings.Contains(buffer.String(), expected) {
+		t.Errorf("Output '%s' should contain '%s'.", buffer.String(), expected)
+	}
+}
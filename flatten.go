@@ -0,0 +1,49 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// flatten calls `fn' for each scalar found in `value', with its dot-separated
+// path below `prefix'. The value goes through encoding/json first so that
+// struct tags and json.Marshaler implementations are honored just like with
+// the JSON formatter; scalars are therefore nil, bool, json.Number or string.
+// Object keys are visited in sorted order so that output is deterministic.
+func flatten(prefix string, value interface{}, fn func(key string, scalar interface{})) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return err
+	}
+	flattenGeneric(prefix, generic, fn)
+	return nil
+}
+
+// flattenGeneric walks a value as decoded by encoding/json.
+func flattenGeneric(prefix string, value interface{}, fn func(key string, scalar interface{})) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flattenGeneric(prefix+"."+k, v[k], fn)
+		}
+	case []interface{}:
+		for i, e := range v {
+			flattenGeneric(prefix+"."+strconv.Itoa(i), e, fn)
+		}
+	default:
+		fn(prefix, v)
+	}
+}
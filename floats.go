@@ -0,0 +1,110 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FloatPolicy decides what happens to the NaN and infinite floats found in
+// the data and context of a message, which JSON cannot represent.
+type FloatPolicy uint
+
+const (
+	// FloatError makes the log call fail. It is the default.
+	FloatError = FloatPolicy(iota)
+	// FloatNull outputs them as null.
+	FloatNull
+	// FloatString outputs them as the strings "NaN", "+Inf" and "-Inf".
+	FloatString
+	// FloatClamp outputs infinities as the largest finite float64 of the
+	// same sign, and NaN as null.
+	FloatClamp
+)
+
+// WithFloatPolicy returns a new Logger applying the given policy to NaN and
+// infinite floats, so that one bad value does not cost the whole record.
+func (l Logger) WithFloatPolicy(policy FloatPolicy) Logger {
+	l.floatPolicy = policy
+	return l
+}
+
+// replace returns the value output in place of a float which is not finite.
+func (p FloatPolicy) replace(f float64) interface{} {
+	switch p {
+	case FloatString:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	case FloatClamp:
+		if math.IsInf(f, 1) {
+			return math.MaxFloat64
+		} else if math.IsInf(f, -1) {
+			return -math.MaxFloat64
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// formatWithFloatPolicy formats a message, and if the formatter failed
+// because of an unsupported float, formats it again with the floats replaced
+// according to the Logger's policy. Likewise, if it failed because of a map
+// whose keys encoding/json rejects, such as booleans or structs, it formats
+// it again with the keys converted to strings, so that such maps never cost
+// the record. Other failures, such as cycles, are returned as they are.
+func (l Logger) formatWithFloatPolicy(buffer *bytes.Buffer, m *Message) error {
+	start := buffer.Len()
+	err := l.formatter.Format(buffer, m)
+	if err == nil || !l.recoverable(err) {
+		return err
+	}
+	buffer.Truncate(start)
+	w := walker{float: l.floatPolicy.replace}
+	if l.floatPolicy == FloatError {
+		// The floats are kept, so that formatting fails again if one of
+		// them caused the failure.
+		w.float = func(f float64) interface{} { return f }
+	}
+	// A cyclic value may hide behind the error which was recovered: it
+	// cannot be converted either, and the original error is kept.
+	data, walkErr := w.walk(m.Data)
+	if walkErr != nil {
+		return err
+	}
+	context, walkErr := w.walk(m.Context)
+	if walkErr != nil {
+		return err
+	}
+	if m.Data != nil {
+		m.Data = data
+	}
+	if m.Context != nil {
+		m.Context = context.(map[string]interface{})
+	}
+	return l.formatter.Format(buffer, m)
+}
+
+// recoverable tells whether a formatting error is caused by a float which is
+// not finite, and the policy replaces such floats, or by map keys
+// encoding/json rejects, which the walker converts.
+func (l Logger) recoverable(err error) bool {
+	var unsupportedType *json.UnsupportedTypeError
+	if errors.As(err, &unsupportedType) {
+		return unsupportedType.Type.Kind() == reflect.Map
+	}
+	var unsupportedValue *json.UnsupportedValueError
+	if !errors.As(err, &unsupportedValue) {
+		return false
+	}
+	switch unsupportedValue.Str {
+	case "NaN", "+Inf", "-Inf":
+		return l.floatPolicy != FloatError
+	}
+	// Depending on the version of Go, encoding/json reports unsupported
+	// map keys as unsupported types or as unsupported values.
+	return strings.Contains(unsupportedValue.Str, "object member name must be a string")
+}
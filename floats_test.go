@@ -0,0 +1,152 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+type testFloatContextKey struct{}
+
+type testFloatMetrics struct {
+	Ratio   float64  `json:"ratio"`
+	Latency *float32 `json:"latency,omitempty"`
+	hidden  float64
+}
+
+type testFloatPolicyExample struct {
+	formatter Formatter
+	policy    FloatPolicy
+	expected  []string
+}
+
+// TestFloatPolicy tests that NaN and infinite floats are replaced according
+// to the policy, in every formatter, without modifying the caller's data.
+func TestFloatPolicy(t *testing.T) {
+	examples := []testFloatPolicyExample{
+		testFloatPolicyExample{JSONFormatter{}, FloatNull, []string{
+			`"data":{"inf":null,"list":[1.5,null],"metrics":{"ratio":null}}`,
+			`"context":{"score":null}`,
+		}},
+		testFloatPolicyExample{JSONFormatter{}, FloatString, []string{
+			`"data":{"inf":"+Inf","list":[1.5,"-Inf"],"metrics":{"ratio":"NaN"}}`,
+			`"context":{"score":"NaN"}`,
+		}},
+		testFloatPolicyExample{JSONFormatter{}, FloatClamp, []string{
+			`"data":{"inf":1.7976931348623157e+308,"list":[1.5,-1.7976931348623157e+308],"metrics":{"ratio":null}}`,
+		}},
+		testFloatPolicyExample{LogfmtFormatter{}, FloatString, []string{
+			`context.score=NaN data.inf=+Inf data.list.0=1.5 data.list.1=-Inf data.metrics.ratio=NaN`,
+		}},
+		testFloatPolicyExample{GELFFormatter{Host: "test"}, FloatString, []string{
+			`"_data.inf":"+Inf"`,
+		}},
+	}
+	for _, example := range examples {
+		data := map[string]interface{}{
+			"inf":     math.Inf(1),
+			"list":    []float64{1.5, math.Inf(-1)},
+			"metrics": testFloatMetrics{Ratio: math.NaN()},
+		}
+		buffer := new(bytes.Buffer)
+		logger := DefaultLogger.WithWriter(buffer).WithFormatter(example.formatter).WithFloatPolicy(example.policy)
+		ctx := context.WithValue(context.Background(), testFloatContextKey{}, math.NaN())
+		logger = logger.WithContext(ctx).WithContextKey(testFloatContextKey{}, "score")
+		if err := logger.Info("metrics", data); err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+			continue
+		}
+		for _, expected := range example.expected {
+			if !strings.Contains(buffer.String(), expected) {
+				t.Errorf("Output '%s' should contain '%s'.", buffer.String(), expected)
+			}
+		}
+		if !math.IsInf(data["inf"].(float64), 1) {
+			t.Errorf("The caller's data should not be modified.")
+		}
+	}
+}
+
+// TestFloatPolicyError tests that the default policy fails the log call.
+func TestFloatPolicyError(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	err := DefaultLogger.WithWriter(buffer).Info("metrics", map[string]interface{}{"ratio": math.NaN()})
+	if err == nil {
+		t.Errorf("Logging a NaN should error with the default policy.")
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("Nothing should be written, got '%s'.", buffer.String())
+	}
+}
+
+// TestLocaleIndependentOutput tests that numbers and booleans are output
+// the same way by all formatters whatever the environment, with a dot as the
+// decimal separator and no digit grouping.
+func TestLocaleIndependentOutput(t *testing.T) {
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	data := map[string]interface{}{"ratio": 1234567.25, "ok": true, "small": float32(0.5)}
+	expectations := map[Formatter]string{
+		JSONFormatter{}:                `"data":{"ok":true,"ratio":1234567.25,"small":0.5}`,
+		LogfmtFormatter{}:              `data.ok=true data.ratio=1234567.25 data.small=0.5`,
+		GELFFormatter{Host: "test"}:    `"_data.ratio":1234567.25`,
+		JSONFormatter{TimeLayout: "x"}: `"data":{"ok":true,"ratio":1234567.25,"small":0.5}`,
+	}
+	for formatter, expected := range expectations {
+		buffer := new(bytes.Buffer)
+		if err := DefaultLogger.WithWriter(buffer).WithFormatter(formatter).Info("locale", data); err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+		} else if !strings.Contains(buffer.String(), expected) {
+			t.Errorf("Output '%s' should contain '%s'.", buffer.String(), expected)
+		}
+	}
+}
+
+// TestMapKeysFallback tests that maps whose keys encoding/json rejects are
+// logged with their keys converted, with every formatter, and that the
+// float policy still applies to their values.
+func TestMapKeysFallback(t *testing.T) {
+	data := map[string]interface{}{
+		"thresholds": map[float64]string{0.5: "warn", 0.9: "page"},
+		"enabled":    map[bool]int{true: 3},
+	}
+	examples := map[Formatter]string{
+		JSONFormatter{}:   `"data":{"enabled":{"true":3},"thresholds":{"0.5":"warn","0.9":"page"}}`,
+		LogfmtFormatter{}: `data.enabled.true=3 data.thresholds.0.5=warn data.thresholds.0.9=page`,
+	}
+	for formatter, expected := range examples {
+		buffer := new(bytes.Buffer)
+		if err := DefaultLogger.WithWriter(buffer).WithFormatter(formatter).Info("keys", data); err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+		} else if !strings.Contains(buffer.String(), expected) {
+			t.Errorf("Output '%s' should contain '%s'.", buffer.String(), expected)
+		}
+	}
+	if _, ok := data["thresholds"].(map[float64]string); !ok {
+		t.Errorf("The caller's data should not be modified.")
+	}
+	nan := map[float64]float64{1: math.NaN()}
+	if err := DefaultLogger.WithWriter(new(bytes.Buffer)).Info("keys", nan); err == nil {
+		t.Errorf("Logging a NaN should still error with the default policy.")
+	}
+	buffer := new(bytes.Buffer)
+	DefaultLogger.WithWriter(buffer).WithFloatPolicy(FloatString).Info("keys", nan)
+	if !strings.Contains(buffer.String(), `"data":{"1":"NaN"}`) {
+		t.Errorf("Output '%s' should have the converted key and value.", buffer.String())
+	}
+}
+
+// TestCycleNotRecovered tests that cyclic values fail with the error of
+// encoding/json, and are not retried, whatever the float policy.
+func TestCycleNotRecovered(t *testing.T) {
+	cycle := &testWalkCycle{Ratio: 1}
+	cycle.Next = cycle
+	for _, policy := range []FloatPolicy{FloatError, FloatString} {
+		err := DefaultLogger.WithWriter(new(bytes.Buffer)).WithFloatPolicy(policy).Info("cycle", cycle)
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("Logging a cyclic value should fail with a cycle error, got %v.", err)
+		}
+	}
+}
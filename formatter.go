@@ -0,0 +1,149 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Formatter turns a Message into a log record. A Logger calls Format once per
+// message and writes the resulting bytes to its io.Writer in a single call.
+// Implementations must append exactly one record to `buffer', terminated by a
+// newline.
+type Formatter interface {
+	Format(buffer *bytes.Buffer, m *Message) error
+}
+
+// JSONFormatter formats messages as single-line JSON objects. It is the
+// formatter used by DefaultLogger.
+type JSONFormatter struct {
+	// FieldNames renames the fields of the output. By default, fields
+	// are named after the json tags of Message.
+	FieldNames FieldNames
+	// TimeLayout is the layout of the time field, as understood by
+	// time.Format, or TimeLayoutEpochMillis or TimeLayoutEpochSeconds to
+	// output a number. It defaults to time.RFC3339Nano.
+	TimeLayout string
+}
+
+// FieldNames are the keys under which a JSONFormatter outputs the fields of a
+// Message. Empty names keep the default key. A name containing dots is output
+// as nested objects, so that "log.level" yields {"log":{"level":"info"}};
+// fields whose names share a prefix are merged into the same object.
+type FieldNames struct {
+	Level    string
+	Time     string
+	Message  string
+	Logger   string
+	Data     string
+	Context  string
+	Error    string
+	Stack    string
+	Repeated string
+}
+
+var (
+	// ECSFieldNames map the fields of a Message to the Elastic Common
+	// Schema, so that records can be ingested by Elasticsearch or Filebeat
+	// without a pipeline processor. Context values become labels.
+	ECSFieldNames = FieldNames{
+		Level:    "log.level",
+		Time:     "@timestamp",
+		Message:  "message",
+		Logger:   "log.logger",
+		Data:     "data",
+		Context:  "labels",
+		Error:    "error",
+		Stack:    "error.stack_trace",
+		Repeated: "event.repeated",
+	}
+
+	// ECSFormatter formats messages following the Elastic Common Schema.
+	ECSFormatter = JSONFormatter{FieldNames: ECSFieldNames}
+)
+
+// Format appends the JSON representation of `m' to `buffer'.
+func (f JSONFormatter) Format(buffer *bytes.Buffer, m *Message) error {
+	if f.FieldNames == (FieldNames{}) && f.TimeLayout == "" {
+		return encodeMessage(buffer, m)
+	}
+	names := f.FieldNames.withDefaults()
+	object := map[string]interface{}{}
+	setFieldPath(object, names.Level, m.Level)
+	setFieldPath(object, names.Time, formatTime(m.Time, f.TimeLayout))
+	setFieldPath(object, names.Message, m.Message)
+	if m.Logger != "" {
+		setFieldPath(object, names.Logger, m.Logger)
+	}
+	if m.Data != nil {
+		setFieldPath(object, names.Data, m.Data)
+	}
+	if len(m.Context) > 0 {
+		setFieldPath(object, names.Context, m.Context)
+	}
+	if m.Error != nil {
+		errorObject := map[string]interface{}{
+			"message": m.Error.Message,
+			"type":    m.Error.Type,
+		}
+		if len(m.Error.Chain) > 0 {
+			errorObject["chain"] = m.Error.Chain
+		}
+		setFieldPath(object, names.Error, errorObject)
+	}
+	if m.Stack != "" {
+		setFieldPath(object, names.Stack, m.Stack)
+	}
+	if m.Repeated > 0 {
+		setFieldPath(object, names.Repeated, m.Repeated)
+	}
+	return json.NewEncoder(buffer).Encode(object)
+}
+
+// withDefaults fills the empty names with the default keys.
+func (n FieldNames) withDefaults() FieldNames {
+	return FieldNames{
+		Level:    nameOrDefault(n.Level, "level"),
+		Time:     nameOrDefault(n.Time, "time"),
+		Message:  nameOrDefault(n.Message, "message"),
+		Logger:   nameOrDefault(n.Logger, "logger"),
+		Data:     nameOrDefault(n.Data, "data"),
+		Context:  nameOrDefault(n.Context, "context"),
+		Error:    nameOrDefault(n.Error, "error"),
+		Stack:    nameOrDefault(n.Stack, "stack"),
+		Repeated: nameOrDefault(n.Repeated, "repeated"),
+	}
+}
+
+// nameOrDefault returns `name', or `fallback' if it is empty.
+func nameOrDefault(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// setFieldPath sets a value in `object' at a dot-separated path, creating the
+// intermediate objects. Setting an object where one already exists merges
+// them.
+func setFieldPath(object map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			object[key] = child
+		}
+		object = child
+	}
+	last := keys[len(keys)-1]
+	existing, existingIsObject := object[last].(map[string]interface{})
+	newObject, newIsObject := value.(map[string]interface{})
+	if existingIsObject && newIsObject {
+		for k, v := range newObject {
+			existing[k] = v
+		}
+	} else {
+		object[last] = value
+	}
+}
@@ -0,0 +1,66 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestECSFormatter tests renaming fields to the Elastic Common Schema,
+// including nested and merged objects.
+func TestECSFormatter(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	ctx := context.WithValue(context.Background(), "service", "billing")
+	logger := DefaultLogger.WithWriter(buffer).WithFormatter(ECSFormatter).Named("billing")
+	logger = logger.WithContext(ctx).WithContextKey("service", "service")
+	logger = logger.WithStackTrace(true)
+	err := logger.Err(errors.New("boom"), "ecs", nil)
+	if err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+		return
+	}
+	output := struct {
+		Timestamp string `json:"@timestamp"`
+		Message   string `json:"message"`
+		Log       struct {
+			Level  string `json:"level"`
+			Logger string `json:"logger"`
+		} `json:"log"`
+		Labels map[string]string `json:"labels"`
+		Error  struct {
+			Message    string `json:"message"`
+			StackTrace string `json:"stack_trace"`
+		} `json:"error"`
+	}{}
+	err = json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		return
+	}
+	if output.Timestamp == "" || output.Message != "ecs" || output.Log.Level != "error" || output.Log.Logger != "billing" {
+		t.Errorf("Output '%s' is missing ECS base fields.", buffer.String())
+	}
+	if output.Labels["service"] != "billing" {
+		t.Errorf("Output labels %v should hold the context.", output.Labels)
+	}
+	if output.Error.Message != "boom" || output.Error.StackTrace == "" {
+		t.Errorf("Output error should merge the message and the stack trace: '%s'.", buffer.String())
+	}
+}
+
+// TestJSONFormatterPartialFieldNames tests that unset names keep their
+// default keys.
+func TestJSONFormatterPartialFieldNames(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	formatter := JSONFormatter{FieldNames: FieldNames{Message: "msg"}}
+	DefaultLogger.WithWriter(buffer).WithFormatter(formatter).Info("renamed", nil)
+	output := map[string]interface{}{}
+	err := json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+	} else if output["msg"] != "renamed" || output["level"] != "info" || output["message"] != nil {
+		t.Errorf("Output '%s' should only rename the message.", buffer.String())
+	}
+}
@@ -0,0 +1,294 @@
+package jsonlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// GELFCompression is the compression applied to GELF messages sent over UDP.
+type GELFCompression uint
+
+const (
+	GELFCompressionGzip = GELFCompression(iota)
+	GELFCompressionZlib
+	GELFCompressionNone
+)
+
+const (
+	// GELFChunkSize is the maximum size of the UDP datagrams sent by a
+	// GELFWriter, chosen to fit in the MTU of most networks.
+	GELFChunkSize = 1420
+	// gelfChunkHeaderSize is the size of the header of a GELF chunk.
+	gelfChunkHeaderSize = 12
+	// gelfMaxChunks is the maximum number of chunks of a GELF message.
+	gelfMaxChunks = 128
+)
+
+var (
+	// ErrGELFMessageTooLarge is returned when a message does not fit in the
+	// maximum number of GELF chunks.
+	ErrGELFMessageTooLarge = errors.New("jsonlog: message too large for GELF chunking")
+
+	// gelfInvalidKeyCharacters matches the characters not allowed in the
+	// names of GELF additional fields.
+	gelfInvalidKeyCharacters = regexp.MustCompile(`[^\w.\-]`)
+
+	// gelfHostname is the hostname GELFFormatter defaults to, read once by
+	// gelfHostnameOnce.
+	gelfHostname     string
+	gelfHostnameOnce sync.Once
+)
+
+// GELFFormatter formats messages as GELF 1.1 payloads for Graylog. The data
+// and context are flattened into additional fields named "_data.<path>" and
+// "_context.<key>", the error message goes in "_error" and the stack trace in
+// "full_message". Use it with a GELFWriter.
+type GELFFormatter struct {
+	// Host is the "host" field of messages. It defaults to the hostname,
+	// read once for the process.
+	Host string
+}
+
+// GELFWriter sends GELF payloads to a Graylog input, one per Write. Over UDP
+// messages are compressed and split in chunks if needed; over TCP they are
+// sent uncompressed and delimited by a null byte, and the connection is
+// reestablished once if a write fails.
+//
+// A GELFWriter is safe for concurrent use.
+type GELFWriter struct {
+	network     string
+	address     string
+	compression GELFCompression
+	mutex       sync.Mutex
+	conn        net.Conn
+	dialer      *networkDialer
+}
+
+// Format appends the GELF representation of `m' to `buffer'.
+func (f GELFFormatter) Format(buffer *bytes.Buffer, m *Message) error {
+	host := f.Host
+	if host == "" {
+		gelfHostnameOnce.Do(func() { gelfHostname, _ = os.Hostname() })
+		host = gelfHostname
+	}
+	severity, ok := syslogSeverities[m.logLevel]
+	if !ok {
+		severity = SyslogSeverityNotice
+	}
+	payload := map[string]interface{}{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": m.Message,
+		"timestamp":     float64(m.Time.UnixNano()/int64(1000000)) / 1000,
+		"level":         severity,
+		"_level_name":   m.Level,
+	}
+	addField := func(key string, scalar interface{}) {
+		key = "_" + gelfInvalidKeyCharacters.ReplaceAllString(key, "_")
+		switch v := scalar.(type) {
+		case nil:
+		case bool:
+			if v {
+				payload[key] = "true"
+			} else {
+				payload[key] = "false"
+			}
+		default:
+			payload[key] = v
+		}
+	}
+	if len(m.Context) > 0 {
+		if err := flatten("context", m.Context, addField); err != nil {
+			return err
+		}
+	}
+	if m.Data != nil {
+		if err := flatten("data", m.Data, addField); err != nil {
+			return err
+		}
+	}
+	if m.Logger != "" {
+		payload["_logger"] = m.Logger
+	}
+	if m.Error != nil {
+		payload["_error"] = m.Error.Message
+	}
+	if m.Stack != "" {
+		payload["full_message"] = m.Stack
+	}
+	if m.Repeated > 0 {
+		payload["_repeated"] = m.Repeated
+	}
+	return json.NewEncoder(buffer).Encode(payload)
+}
+
+// NewGELFWriter connects to a Graylog GELF input. `network' is "udp" or
+// "tcp"; compression only applies to UDP.
+func NewGELFWriter(network, address string, compression GELFCompression) (*GELFWriter, error) {
+	return NewGELFWriterWithNetwork(network, address, compression, NetworkConfig{})
+}
+
+// NewGELFWriterWithNetwork is like NewGELFWriter, connecting over TCP as
+// configured by `config', for example with TLS.
+func NewGELFWriterWithNetwork(network, address string, compression GELFCompression, config NetworkConfig) (*GELFWriter, error) {
+	dialer, err := config.dialer()
+	if err != nil {
+		return nil, err
+	}
+	w := &GELFWriter{
+		network:     network,
+		address:     address,
+		compression: compression,
+		dialer:      dialer,
+	}
+	if dialer.lazy {
+		return w, nil
+	}
+	conn, err := dialer.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+// Write sends one GELF payload, ignoring trailing newlines.
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	if err := w.WriteRecord(bytes.TrimRight(p, "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteRecord sends one GELF payload, implementing RecordWriter.
+func (w *GELFWriter) WriteRecord(payload []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if strings.HasPrefix(w.network, "udp") {
+		return w.writeUDP(payload)
+	}
+	return w.writeTCP(payload)
+}
+
+// Ping checks the connection to the Graylog input, reconnecting if it was
+// closed or never established.
+func (w *GELFWriter) Ping() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn != nil {
+		if connAlive(w.conn) {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	conn, err := w.dialer.Dial(w.network, w.address)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Close closes the connection to the Graylog input.
+func (w *GELFWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// writeTCP sends a null-delimited payload, reconnecting once on failure.
+func (w *GELFWriter) writeTCP(payload []byte) error {
+	frame := append(append([]byte{}, payload...), 0)
+	if w.conn != nil {
+		if _, err := w.conn.Write(frame); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	conn, err := w.dialer.Dial(w.network, w.address)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	_, err = w.conn.Write(frame)
+	return err
+}
+
+// writeUDP compresses a payload and sends it in as many chunks as needed.
+func (w *GELFWriter) writeUDP(payload []byte) error {
+	compressed, err := w.compress(payload)
+	if err != nil {
+		return err
+	}
+	if w.conn == nil {
+		conn, err := w.dialer.Dial(w.network, w.address)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+	if len(compressed) <= GELFChunkSize {
+		_, err := w.conn.Write(compressed)
+		return err
+	}
+	chunkDataSize := GELFChunkSize - gelfChunkHeaderSize
+	chunkCount := (len(compressed) + chunkDataSize - 1) / chunkDataSize
+	if chunkCount > gelfMaxChunks {
+		return ErrGELFMessageTooLarge
+	}
+	var messageID [8]byte
+	rand.Read(messageID[:])
+	chunk := make([]byte, 0, GELFChunkSize)
+	for i := 0; i < chunkCount; i++ {
+		end := (i + 1) * chunkDataSize
+		if end > len(compressed) {
+			end = len(compressed)
+		}
+		chunk = append(chunk[:0], 0x1e, 0x0f)
+		chunk = append(chunk, messageID[:]...)
+		chunk = append(chunk, byte(i), byte(chunkCount))
+		chunk = append(chunk, compressed[i*chunkDataSize:end]...)
+		if _, err := w.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compress applies the configured compression to a payload.
+func (w *GELFWriter) compress(payload []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	var compressor io.WriteCloser
+	switch w.compression {
+	case GELFCompressionGzip:
+		compressor = gzip.NewWriter(&buffer)
+	case GELFCompressionZlib:
+		compressor = zlib.NewWriter(&buffer)
+	default:
+		return payload, nil
+	}
+	if _, err := compressor.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
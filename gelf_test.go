@@ -0,0 +1,103 @@
+package jsonlog
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGELFFormatter tests the GELF payload of a message.
+func TestGELFFormatter(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithFormatter(GELFFormatter{Host: "test-host"})
+	logger.Warning("gelf", map[string]interface{}{"user": map[string]interface{}{"id": 42, "admin": true, "a b": nil}})
+	output := map[string]interface{}{}
+	err := json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		return
+	}
+	expected := map[string]interface{}{
+		"version":          "1.1",
+		"host":             "test-host",
+		"short_message":    "gelf",
+		"level":            float64(SyslogSeverityWarning),
+		"_level_name":      "warning",
+		"_data.user.id":    float64(42),
+		"_data.user.admin": "true",
+	}
+	for k, v := range expected {
+		if output[k] != v {
+			t.Errorf("Output field '%s' is %v but should be %v.", k, output[k], v)
+		}
+	}
+	if _, ok := output["_data.user.a_b"]; ok {
+		t.Errorf("Null values should not be output.")
+	}
+	buffer.Reset()
+	logger.WithFormatter(GELFFormatter{}).Info("gelf", nil)
+	if host, _ := os.Hostname(); !strings.Contains(buffer.String(), `"host":"`+host+`"`) {
+		t.Errorf("Output '%s' should default to the hostname '%s'.", buffer.String(), host)
+	}
+}
+
+// TestGELFWriterChunking tests that large messages are split in chunks which
+// reassemble into the compressed payload.
+func TestGELFWriterChunking(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on UDP: %s", err.Error())
+	}
+	defer conn.Close()
+	writer, err := NewGELFWriter("udp", conn.LocalAddr().String(), GELFCompressionZlib)
+	if err != nil {
+		t.Errorf("Creating the GELF writer errored with '%s'.", err.Error())
+		return
+	}
+	defer writer.Close()
+	var payload bytes.Buffer
+	for i := 0; payload.Len() < 20*GELFChunkSize; i++ {
+		payload.WriteString(time.Duration(i * 7919).String())
+	}
+	logger := DefaultLogger.WithWriter(writer).WithFormatter(GELFFormatter{})
+	if err := logger.Info("big", payload.String()); err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+		return
+	}
+	chunks := map[byte][]byte{}
+	count := byte(0)
+	datagram := make([]byte, 2*GELFChunkSize)
+	for count == 0 || len(chunks) < int(count) {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(datagram)
+		if err != nil {
+			t.Errorf("Reading a chunk errored with '%s'.", err.Error())
+			return
+		}
+		if n > GELFChunkSize || datagram[0] != 0x1e || datagram[1] != 0x0f {
+			t.Errorf("Datagram of %d bytes is not a valid chunk.", n)
+			return
+		}
+		count = datagram[11]
+		chunks[datagram[10]] = append([]byte{}, datagram[12:n]...)
+	}
+	var compressed bytes.Buffer
+	for i := byte(0); i < count; i++ {
+		compressed.Write(chunks[i])
+	}
+	zlibReader, err := zlib.NewReader(&compressed)
+	if err != nil {
+		t.Errorf("Opening the zlib stream errored with '%s'.", err.Error())
+		return
+	}
+	decompressed, _ := io.ReadAll(zlibReader)
+	if !strings.Contains(string(decompressed), payload.String()[:100]) {
+		t.Errorf("Reassembled payload does not hold the data.")
+	}
+}
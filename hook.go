@@ -0,0 +1,39 @@
+package jsonlog
+
+import (
+	"errors"
+)
+
+// Hook is run on each message a Logger is about to output, before it is
+// formatted. A Hook may modify the message, for example to enrich it or
+// scrub it, or use it to feed metrics or forward errors elsewhere. Returning
+// ErrDiscardMessage vetoes the message; any other error aborts the log call
+// and is returned to its caller. Note that m.Context is nil when the message
+// has no context values.
+type Hook func(m *Message) error
+
+// ErrDiscardMessage is returned by a Hook to drop the message silently.
+var ErrDiscardMessage = errors.New("jsonlog: message discarded by hook")
+
+// WithHook returns a new Logger which runs the given hooks on its messages,
+// after those already registered. Hooks run in registration order.
+func (l Logger) WithHook(hooks ...Hook) Logger {
+	newHooks := make([]Hook, 0, len(l.hooks)+len(hooks))
+	newHooks = append(newHooks, l.hooks...)
+	l.hooks = append(newHooks, hooks...)
+	return l
+}
+
+// runHooks runs the Logger's hooks on `m'. It returns false if the message
+// must not be output.
+func (l Logger) runHooks(m *Message) (bool, error) {
+	for _, hook := range l.hooks {
+		if err := hook(m); err == ErrDiscardMessage {
+			l.metrics.countDropped(dropHook)
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
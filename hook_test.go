@@ -0,0 +1,63 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestHooks tests that hooks run in registration order and can modify
+// messages.
+func TestHooks(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithHook(func(m *Message) error {
+		m.Message += " first"
+		return nil
+	}).WithHook(func(m *Message) error {
+		m.Message += " second"
+		m.Data = map[string]string{"host": "test"}
+		return nil
+	})
+	err := logger.Info("log", nil)
+	if err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+		return
+	}
+	output := struct {
+		Message string            `json:"message"`
+		Data    map[string]string `json:"data"`
+	}{}
+	err = json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+	} else {
+		if output.Message != "log first second" {
+			t.Errorf("Output message '%s' should be 'log first second'.", output.Message)
+		}
+		if output.Data["host"] != "test" {
+			t.Errorf("Output data '%v' should have been set by the hook.", output.Data)
+		}
+	}
+}
+
+// TestHooksVeto tests that hooks can drop messages or abort logging.
+func TestHooksVeto(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	hookError := errors.New("hook failure")
+	discard := DefaultLogger.WithWriter(buffer).WithHook(func(m *Message) error {
+		return ErrDiscardMessage
+	})
+	if err := discard.Info("log", nil); err != nil {
+		t.Errorf("Discarding errored with '%s'.", err.Error())
+	}
+	failing := DefaultLogger.WithWriter(buffer).WithHook(func(m *Message) error {
+		return hookError
+	})
+	if err := failing.Info("log", nil); err != hookError {
+		t.Errorf("Logging returned '%v' but should return the hook's error.", err)
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("Output '%s' should be empty.", buffer.String())
+	}
+}
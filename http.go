@@ -0,0 +1,106 @@
+package jsonlog
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the header HTTPMiddleware reads the request ID from, and
+// sets on the response.
+const RequestIDHeader = "X-Request-Id"
+
+// accessLog is the data of the message HTTPMiddleware logs for each request.
+type accessLog struct {
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMs float64 `json:"duration_ms"`
+	RemoteAddr string  `json:"remoteAddr"`
+}
+
+// responseRecorder wraps an http.ResponseWriter to record the status and the
+// size of the response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// HTTPMiddleware returns a net/http middleware which logs an access log entry
+// for each request once it is served. If the handler panics, the entry is
+// logged without recovering the panic, with status 500 unless the handler had
+// already written a status. Handlers can retrieve a request-scoped
+// Logger with LoggerFromContextOrDefault(r.Context()); its messages hold the
+// request ID under "requestId" in their context. The request ID is taken from
+// the X-Request-Id header if present, generated otherwise.
+func HTTPMiddleware(logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" {
+				requestID = logger.newID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+			ctx := context.WithValue(r.Context(), contextKeyRequestID, requestID)
+			requestLogger := logger.WithContext(ctx).WithContextKey(contextKeyRequestID, "requestId")
+			ctx = ContextWithLogger(ctx, requestLogger)
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				status := recorder.status
+				if !completed && !recorder.wroteHeader {
+					status = http.StatusInternalServerError
+				}
+				requestLogger.Info("HTTP request served.", accessLog{
+					Method:     r.Method,
+					Path:       r.URL.Path,
+					Status:     status,
+					Bytes:      recorder.bytes,
+					DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+					RemoteAddr: r.RemoteAddr,
+				})
+			}()
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+			completed = true
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID set by HTTPMiddleware.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(contextKeyRequestID).(string)
+	return requestID, ok
+}
+
+// WriteHeader records the status before passing it on.
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the bytes written before passing them on.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher if the wrapped ResponseWriter does.
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the wrapped ResponseWriter.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
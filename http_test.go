@@ -0,0 +1,113 @@
+package jsonlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testHTTPMiddlewareOutput struct {
+	Message string                 `json:"message"`
+	Context map[string]interface{} `json:"context"`
+	Data    accessLog              `json:"data"`
+}
+
+// TestHTTPMiddleware tests the request-scoped logger and the access log.
+func TestHTTPMiddleware(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer)
+	handler := HTTPMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LoggerFromContextOrDefault(r.Context()).Info("handling", nil)
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	}))
+	request := httptest.NewRequest(http.MethodPost, "/tea?cups=2", nil)
+	request.Header.Set(RequestIDHeader, "req-42")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Header().Get(RequestIDHeader) != "req-42" {
+		t.Errorf("Response request ID '%s' should be 'req-42'.", recorder.Header().Get(RequestIDHeader))
+	}
+	scanner := bufio.NewScanner(buffer)
+	var outputs []testHTTPMiddlewareOutput
+	for scanner.Scan() {
+		output := testHTTPMiddlewareOutput{}
+		if err := json.Unmarshal(scanner.Bytes(), &output); err != nil {
+			t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		}
+		outputs = append(outputs, output)
+	}
+	if len(outputs) != 2 {
+		t.Errorf("There are %d messages but there should be 2.", len(outputs))
+		return
+	}
+	for _, output := range outputs {
+		if output.Context["requestId"] != "req-42" {
+			t.Errorf("Message '%s' context %v should hold the request ID.", output.Message, output.Context)
+		}
+	}
+	access := outputs[1].Data
+	if access.Method != "POST" || access.Path != "/tea" || access.Status != http.StatusTeapot || access.Bytes != 15 {
+		t.Errorf("Access log %+v does not match the request.", access)
+	}
+}
+
+// TestHTTPMiddlewareGeneratesRequestID tests that a request ID is generated
+// when the request has none.
+func TestHTTPMiddlewareGeneratesRequestID(t *testing.T) {
+	var requestID string
+	handler := HTTPMiddleware(DefaultLogger.WithWriter(new(bytes.Buffer)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID, _ = RequestIDFromContext(r.Context())
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(requestID) != 32 || recorder.Header().Get(RequestIDHeader) != requestID {
+		t.Errorf("Request ID '%s' should be generated and returned.", requestID)
+	}
+}
+
+type testHTTPMiddlewarePanicExample struct {
+	status         int
+	expectedStatus int
+}
+
+var testHTTPMiddlewarePanicExamples = []testHTTPMiddlewarePanicExample{
+	testHTTPMiddlewarePanicExample{0, http.StatusInternalServerError},
+	testHTTPMiddlewarePanicExample{http.StatusNotFound, http.StatusNotFound},
+}
+
+// TestHTTPMiddlewarePanic tests that a request whose handler panics is logged
+// with status 500, or the status the handler had written, and that the panic
+// goes on.
+func TestHTTPMiddlewarePanic(t *testing.T) {
+	for _, example := range testHTTPMiddlewarePanicExamples {
+		testHTTPMiddlewarePanic(t, example)
+	}
+}
+
+func testHTTPMiddlewarePanic(t *testing.T, example testHTTPMiddlewarePanicExample) {
+	buffer := new(bytes.Buffer)
+	handler := HTTPMiddleware(DefaultLogger.WithWriter(buffer))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if example.status != 0 {
+			w.WriteHeader(example.status)
+		}
+		panic("broken handler")
+	}))
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "broken handler" {
+				t.Errorf("The panic should go on, got %v.", recovered)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	output := testHTTPMiddlewareOutput{}
+	if err := json.Unmarshal(buffer.Bytes(), &output); err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+	} else if output.Data.Status != example.expectedStatus {
+		t.Errorf("Access log %+v should have status %d.", output.Data, example.expectedStatus)
+	}
+}
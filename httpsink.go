@@ -0,0 +1,598 @@
+package jsonlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHTTPSinkTimeout is the timeout of the default client of an
+	// HTTPSink.
+	defaultHTTPSinkTimeout = 10 * time.Second
+	// defaultInitialBackoff is the delay before the first retry.
+	defaultInitialBackoff = 100 * time.Millisecond
+	// defaultMaxBackoff is the longest delay between two retries.
+	defaultMaxBackoff = 10 * time.Second
+	// maxErrorBodySize is how much of an error response is kept in an
+	// HTTPStatusError.
+	maxErrorBodySize = 512
+)
+
+var (
+	// ErrInvalidSinkURL is returned when an HTTPSink is configured without
+	// an absolute HTTP or HTTPS URL.
+	ErrInvalidSinkURL = errors.New("jsonlog: invalid HTTP sink URL")
+
+	// httpBodyFormats maps the media types an HTTPSink can negotiate to
+	// their format.
+	httpBodyFormats = map[string]HTTPBodyFormat{
+		"application/x-ndjson":  NDJSONBody,
+		"application/ndjson":    NDJSONBody,
+		"application/json":      JSONArrayBody,
+		"application/msgpack":   MessagePackBody,
+		"application/x-msgpack": MessagePackBody,
+	}
+)
+
+// HTTPBodyFormat turns a batch of records, without their newline, into the
+// body of a request and its content type.
+type HTTPBodyFormat func(records [][]byte) (body []byte, contentType string, err error)
+
+// HTTPSinkConfig configures an HTTPSink.
+type HTTPSinkConfig struct {
+	// URL is the endpoint the batches are posted to.
+	URL string
+	// Client sends the requests. It defaults to a client with a ten second
+	// timeout, configured by Network.
+	Client *http.Client
+	// Network is the TLS and proxy configuration of the default client.
+	Network NetworkConfig
+	// Header holds additional headers sent with each request, such as
+	// Authorization.
+	Header http.Header
+	// Credentials authorize each request, after Header is applied. Use them
+	// rather than Header for tokens which rotate or for signed requests.
+	Credentials Credentials
+	// Format builds the request bodies. It defaults to NDJSONBody.
+	Format HTTPBodyFormat
+	// ContentType overrides the Content-Type of the requests, for intake
+	// APIs expecting a vendor-specific media type.
+	ContentType string
+	// Accept is the Accept header of the requests, if not empty.
+	Accept string
+	// Gzip compresses the request bodies.
+	Gzip bool
+	// Negotiate lets the sink switch to another format or stop compressing
+	// when the endpoint does not accept the configured ones. Before the
+	// first batch, the sink sends an OPTIONS request and reads the media
+	// types of the Accept-Post header of the response; after a 415
+	// response, it reads its Accept-Post or Accept header and sends the
+	// batch again. The first media type the sink supports, in the order
+	// of preference of the endpoint, is chosen: NDJSON, a JSON array or
+	// MessagePack. An Accept-Encoding header without gzip disables
+	// compression.
+	Negotiate bool
+	// MaxRetries is the number of times a failed request is retried. Only
+	// network errors, 429 and 5xx responses are retried.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry, doubled at each
+	// retry up to MaxBackoff. They default to 100ms and 10s.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Overflow keeps the batches which could not be delivered after all
+	// retries, as newline-delimited records, to send them before the next
+	// batch. Without one, such batches are lost and the error is returned
+	// by WriteBatch.
+	Overflow OverflowBuffer
+}
+
+// HTTPSink posts batches of records to an HTTP endpoint, such as the
+// Elasticsearch bulk API, the Loki push API or a generic collector. Each
+// WriteBatch is one request, so an HTTPSink is usually placed behind a
+// BatchingSink, which gives it the records of its batches whole.
+//
+// While the endpoint is unreachable, batches go to the overflow buffer and
+// are sent again, in order, once a request succeeds. Requests for buffered
+// batches are not retried, so that an outage does not slow every batch down.
+//
+// An HTTPSink is safe for concurrent use, and concurrent Writes send their
+// requests concurrently, for example when placed behind a PipelinedSink.
+type HTTPSink struct {
+	config HTTPSinkConfig
+	// mutex guards the overflow buffer, so that it is drained in order.
+	mutex sync.Mutex
+	// negotiation guards format, contentType, gzip and probed.
+	negotiation sync.Mutex
+	// format, contentType and gzip are the configured ones until changed
+	// by negotiation.
+	format      HTTPBodyFormat
+	contentType string
+	gzip        bool
+	// probed is true once the endpoint answered an OPTIONS request.
+	probed bool
+	// sleep waits between retries; tests replace it.
+	sleep func(time.Duration)
+}
+
+// HTTPStatusError is returned when the endpoint of an HTTPSink responds with
+// an error status.
+type HTTPStatusError struct {
+	StatusCode int
+	// Body is the beginning of the response body.
+	Body string
+
+	header http.Header
+}
+
+// Error describes the status and the response.
+func (e *HTTPStatusError) Error() string {
+	return "jsonlog: HTTP sink got status " + strconv.Itoa(e.StatusCode) + ": " + e.Body
+}
+
+// retryable tells whether the request may succeed if sent again.
+func (e *HTTPStatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// NDJSONBody sends the records as newline-delimited JSON.
+func NDJSONBody(records [][]byte) ([]byte, string, error) {
+	return joinRecords(records), "application/x-ndjson", nil
+}
+
+// JSONArrayBody sends the records as a JSON array.
+func JSONArrayBody(records [][]byte) ([]byte, string, error) {
+	body := []byte{'['}
+	for i, record := range records {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, record...)
+	}
+	return append(body, ']'), "application/json", nil
+}
+
+// MessagePackBody sends the records as a MessagePack array of maps.
+func MessagePackBody(records [][]byte) ([]byte, string, error) {
+	values := make([]interface{}, len(records))
+	for i, record := range records {
+		decoder := json.NewDecoder(bytes.NewReader(record))
+		decoder.UseNumber()
+		if err := decoder.Decode(&values[i]); err != nil {
+			return nil, "", err
+		}
+	}
+	return appendMsgpack(nil, values), "application/msgpack", nil
+}
+
+// ElasticsearchBulkBody formats batches for the Elasticsearch bulk API,
+// indexing each record in `index'. An empty `index' uses the index of the URL,
+// as in "https://es.example.com/logs/_bulk". Note that the bulk API reports
+// the failures of single records in a successful response, which is not
+// checked.
+func ElasticsearchBulkBody(index string) HTTPBodyFormat {
+	action := []byte("{\"index\":{}}\n")
+	if index != "" {
+		action = append(appendJSONString([]byte(`{"index":{"_index":`), index), "}}\n"...)
+	}
+	return func(records [][]byte) ([]byte, string, error) {
+		var body []byte
+		for _, record := range records {
+			body = append(body, action...)
+			body = append(body, record...)
+			body = append(body, '\n')
+		}
+		return body, "application/x-ndjson", nil
+	}
+}
+
+// LokiPushBody formats batches for the Loki push API, as a single stream with
+// the given labels. Each record is a log line timestamped with its "time"
+// field, or with the time of the push if it has none.
+func LokiPushBody(labels map[string]string) HTTPBodyFormat {
+	return func(records [][]byte) ([]byte, string, error) {
+		now := time.Now()
+		values := make([][2]string, 0, len(records))
+		for _, line := range records {
+			var record struct {
+				Time time.Time `json:"time"`
+			}
+			timestamp := now
+			if json.Unmarshal(line, &record) == nil && !record.Time.IsZero() {
+				timestamp = record.Time
+			}
+			values = append(values, [2]string{strconv.FormatInt(timestamp.UnixNano(), 10), string(line)})
+		}
+		body, err := json.Marshal(map[string]interface{}{
+			"streams": []interface{}{
+				map[string]interface{}{
+					"stream": labels,
+					"values": values,
+				},
+			},
+		})
+		return body, "application/json", err
+	}
+}
+
+// overflowRecords returns the records of a batch of the overflow buffer,
+// where they are stored newline-delimited.
+func overflowRecords(batch []byte) [][]byte {
+	var records [][]byte
+	for _, record := range bytes.Split(batch, []byte("\n")) {
+		if len(record) > 0 {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// NewHTTPSink creates an HTTPSink from `config'.
+func NewHTTPSink(config HTTPSinkConfig) (*HTTPSink, error) {
+	endpoint, err := url.Parse(config.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, ErrInvalidSinkURL
+	}
+	if config.Client == nil {
+		config.Client, err = config.Network.HTTPClient(defaultHTTPSinkTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.Format == nil {
+		config.Format = NDJSONBody
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	return &HTTPSink{
+		config:      config,
+		format:      config.Format,
+		contentType: config.ContentType,
+		gzip:        config.Gzip,
+		sleep:       time.Sleep,
+	}, nil
+}
+
+// Write posts `p' as a batch of a single record, without its trailing
+// newline.
+func (s *HTTPSink) Write(p []byte) (int, error) {
+	if err := s.WriteRecord(bytes.TrimSuffix(p, []byte("\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteRecord posts `record' as a batch of its own, implementing
+// RecordWriter.
+func (s *HTTPSink) WriteRecord(record []byte) error {
+	return s.WriteBatch([][]byte{record})
+}
+
+// WriteBatch posts `records' as one batch, after the batches waiting in the
+// overflow buffer, implementing BatchWriter. If it cannot be delivered but the
+// overflow buffer takes it, WriteBatch succeeds. Batches rejected with a
+// status which is not retried are dropped and WriteBatch returns an
+// HTTPStatusError.
+func (s *HTTPSink) WriteBatch(records [][]byte) error {
+	s.mutex.Lock()
+	if delivered, err := s.drainOverflow(); err != nil {
+		s.mutex.Unlock()
+		return err
+	} else if !delivered {
+		defer s.mutex.Unlock()
+		return s.config.Overflow.Push(joinRecords(records))
+	}
+	s.mutex.Unlock()
+	err := s.postWithRetries(records)
+	if err == nil {
+		return nil
+	}
+	if statusErr, ok := err.(*HTTPStatusError); ok && !statusErr.retryable() {
+		return err
+	}
+	if s.config.Overflow == nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.config.Overflow.Push(joinRecords(records))
+}
+
+// Ping checks that the endpoint is reachable and accepts the credentials, with
+// an OPTIONS request. Responses with a 401, 403 or 5xx status are returned as
+// an HTTPStatusError.
+func (s *HTTPSink) Ping() error {
+	request, err := http.NewRequest(http.MethodOptions, s.config.URL, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range s.config.Header {
+		request.Header[key] = values
+	}
+	if s.config.Credentials != nil {
+		if err := s.config.Credentials.Authorize(request, nil); err != nil {
+			return err
+		}
+	}
+	response, err := s.config.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	switch {
+	case response.StatusCode == http.StatusUnauthorized, response.StatusCode == http.StatusForbidden, response.StatusCode >= 500:
+		message, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+		return &HTTPStatusError{StatusCode: response.StatusCode, Body: string(message), header: response.Header}
+	default:
+		io.Copy(io.Discard, response.Body)
+		return nil
+	}
+}
+
+// Close tries once to deliver the batches of the overflow buffer. It returns
+// an error if some could not be.
+func (s *HTTPSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delivered, err := s.drainOverflow()
+	if err == nil && !delivered {
+		err = errors.New("jsonlog: HTTP sink closed with undelivered batches")
+	}
+	return err
+}
+
+// drainOverflow sends the batches of the overflow buffer, oldest first, each
+// with a single request. It returns false if the endpoint is still failing.
+// Batches rejected for good are dropped. The mutex must be held.
+func (s *HTTPSink) drainOverflow() (bool, error) {
+	if s.config.Overflow == nil {
+		return true, nil
+	}
+	for {
+		batch, err := s.config.Overflow.Front()
+		if err != nil {
+			return false, err
+		}
+		if batch == nil {
+			return true, nil
+		}
+		if err := s.post(overflowRecords(batch)); err != nil {
+			if statusErr, ok := err.(*HTTPStatusError); !ok || statusErr.retryable() {
+				return false, nil
+			}
+		}
+		if err := s.config.Overflow.Drop(); err != nil {
+			return false, err
+		}
+	}
+}
+
+// postWithRetries posts a batch, retrying with exponential backoff.
+func (s *HTTPSink) postWithRetries(records [][]byte) error {
+	backoff := s.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.post(records)
+		if err == nil {
+			return nil
+		}
+		if statusErr, ok := err.(*HTTPStatusError); ok && !statusErr.retryable() {
+			return err
+		}
+		if attempt >= s.config.MaxRetries {
+			return err
+		}
+		s.sleep(backoff)
+		if backoff *= 2; backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// post sends a single request for a batch. With negotiation, the endpoint is
+// probed first, and the request is sent again after a 415 response if the
+// format was changed. The request is also sent again after a 401 response if
+// the credentials can be refreshed.
+func (s *HTTPSink) post(records [][]byte) error {
+	if s.config.Negotiate {
+		s.negotiation.Lock()
+		if !s.probed {
+			s.probe()
+		}
+		s.negotiation.Unlock()
+	}
+	err := s.postOnce(records)
+	if statusErr, ok := err.(*HTTPStatusError); ok && statusErr.StatusCode == http.StatusUnauthorized {
+		if credentials, ok := s.config.Credentials.(invalidator); ok {
+			credentials.Invalidate()
+			err = s.postOnce(records)
+		}
+	}
+	if statusErr, ok := err.(*HTTPStatusError); ok && statusErr.StatusCode == http.StatusUnsupportedMediaType && s.config.Negotiate {
+		s.negotiation.Lock()
+		changed := s.adapt(statusErr.header)
+		s.negotiation.Unlock()
+		if changed {
+			return s.postOnce(records)
+		}
+	}
+	return err
+}
+
+// postOnce sends a single request for a batch with the current format.
+func (s *HTTPSink) postOnce(records [][]byte) error {
+	s.negotiation.Lock()
+	format, configuredType, compress := s.format, s.contentType, s.gzip
+	s.negotiation.Unlock()
+	body, contentType, err := format(records)
+	if err != nil {
+		return err
+	}
+	if configuredType != "" {
+		contentType = configuredType
+	}
+	if compress {
+		var compressed bytes.Buffer
+		gzipWriter := gzip.NewWriter(&compressed)
+		gzipWriter.Write(body)
+		if err := gzipWriter.Close(); err != nil {
+			return err
+		}
+		body = compressed.Bytes()
+	}
+	request, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range s.config.Header {
+		request.Header[key] = values
+	}
+	request.Header.Set("Content-Type", contentType)
+	if compress {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	if s.config.Accept != "" {
+		request.Header.Set("Accept", s.config.Accept)
+	}
+	if s.config.Credentials != nil {
+		if err := s.config.Credentials.Authorize(request, body); err != nil {
+			return err
+		}
+	}
+	response, err := s.config.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		io.Copy(io.Discard, response.Body)
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+	return &HTTPStatusError{
+		StatusCode: response.StatusCode,
+		Body:       string(message),
+		header:     response.Header,
+	}
+}
+
+// probe asks the endpoint which media types it accepts with an OPTIONS
+// request. Failures are ignored: the configured format is kept and the
+// endpoint probed again before the next batch. The negotiation mutex must be
+// held.
+func (s *HTTPSink) probe() {
+	request, err := http.NewRequest(http.MethodOptions, s.config.URL, nil)
+	if err != nil {
+		return
+	}
+	for key, values := range s.config.Header {
+		request.Header[key] = values
+	}
+	if s.config.Credentials != nil {
+		if err := s.config.Credentials.Authorize(request, nil); err != nil {
+			return
+		}
+	}
+	response, err := s.config.Client.Do(request)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	if response.StatusCode < 500 {
+		s.probed = true
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		s.adapt(response.Header)
+	}
+}
+
+// adapt chooses the format and compression from the headers of a response.
+// It returns true if either changed. The negotiation mutex must be held.
+func (s *HTTPSink) adapt(header http.Header) bool {
+	changed := false
+	if values := header.Values("Accept-Encoding"); len(values) > 0 && s.gzip {
+		if !acceptsCoding(values, "gzip") {
+			s.gzip = false
+			changed = true
+		}
+	}
+	accepted := header.Values("Accept-Post")
+	if len(accepted) == 0 {
+		accepted = header.Values("Accept")
+	}
+	current := s.contentType
+	if current == "" {
+		_, current, _ = s.format(nil)
+	}
+	for _, mediaType := range preferredMediaTypes(accepted) {
+		if mediaType == current {
+			break
+		}
+		if format, ok := httpBodyFormats[mediaType]; ok {
+			s.format = format
+			s.contentType = mediaType
+			changed = true
+			break
+		}
+	}
+	return changed
+}
+
+// preferredMediaTypes parses a list of media types, such as the values of an
+// Accept header, sorted by decreasing quality.
+func preferredMediaTypes(values []string) []string {
+	type weighted struct {
+		mediaType string
+		quality   float64
+	}
+	var types []weighted
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(element)
+			if err != nil {
+				continue
+			}
+			quality := 1.0
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+				quality = q
+			}
+			if quality > 0 {
+				types = append(types, weighted{mediaType, quality})
+			}
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].quality > types[j].quality })
+	mediaTypes := make([]string, len(types))
+	for i, t := range types {
+		mediaTypes[i] = t.mediaType
+	}
+	return mediaTypes
+}
+
+// acceptsCoding tells whether the values of an Accept-Encoding header
+// accept `coding'.
+func acceptsCoding(values []string, coding string) bool {
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(element), ";")
+			if strings.EqualFold(strings.TrimSpace(name), coding) && strings.TrimSpace(params) != "q=0" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package jsonlog
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is an HTTP endpoint which fails while `failures' is positive and
+// records the bodies it accepts.
+type collector struct {
+	mutex    sync.Mutex
+	failures int
+	status   int
+	bodies   []string
+	headers  []http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.failures > 0 {
+		c.failures--
+		w.WriteHeader(c.status)
+		return
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = gzipReader
+	}
+	content, _ := io.ReadAll(body)
+	c.bodies = append(c.bodies, string(content))
+	c.headers = append(c.headers, r.Header)
+}
+
+// newTestHTTPSink creates an HTTPSink which does not wait between retries.
+func newTestHTTPSink(t *testing.T, config HTTPSinkConfig) *HTTPSink {
+	sink, err := NewHTTPSink(config)
+	if err != nil {
+		t.Fatalf("Creating the sink errored with '%s'.", err.Error())
+	}
+	sink.sleep = func(time.Duration) {}
+	return sink
+}
+
+// TestHTTPSinkRetries tests that failed requests are retried and that the
+// bodies are compressed.
+func TestHTTPSinkRetries(t *testing.T) {
+	c := &collector{failures: 2, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	sink := newTestHTTPSink(t, HTTPSinkConfig{
+		URL:        server.URL,
+		Gzip:       true,
+		MaxRetries: 2,
+		Header:     http.Header{"Authorization": {"Bearer token"}},
+	})
+	logger := DefaultLogger.WithWriter(sink)
+	if err := logger.Info("Shipped.", nil); err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+	}
+	if len(c.bodies) != 1 || !strings.Contains(c.bodies[0], `"message":"Shipped."`) {
+		t.Errorf("Bodies %q should hold the record.", c.bodies)
+	} else if c.headers[0].Get("Authorization") != "Bearer token" || c.headers[0].Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Headers %v are missing the configured ones.", c.headers[0])
+	}
+}
+
+// TestHTTPSinkOverflow tests that batches are kept during an outage and sent
+// in order once the endpoint recovers.
+func TestHTTPSinkOverflow(t *testing.T) {
+	c := &collector{failures: 4, status: http.StatusBadGateway}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	overflow := NewMemoryOverflow(1024)
+	sink := newTestHTTPSink(t, HTTPSinkConfig{
+		URL:        server.URL,
+		MaxRetries: 1,
+		Overflow:   overflow,
+	})
+	for _, batch := range []string{"1\n", "2\n", "3\n"} {
+		if _, err := sink.Write([]byte(batch)); err != nil {
+			t.Errorf("Writing errored with '%s'.", err.Error())
+		}
+	}
+	if overflow.Len() != 3 {
+		t.Errorf("The overflow should hold 3 batches, not %d.", overflow.Len())
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Closing errored with '%s'.", err.Error())
+	}
+	if strings.Join(c.bodies, "") != "1\n2\n3\n" {
+		t.Errorf("Bodies %q should be the batches in order.", c.bodies)
+	}
+}
+
+// TestHTTPSinkRejected tests that batches rejected for good are neither
+// retried nor buffered.
+func TestHTTPSinkRejected(t *testing.T) {
+	c := &collector{failures: 1, status: http.StatusBadRequest}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	overflow := NewMemoryOverflow(1024)
+	sink := newTestHTTPSink(t, HTTPSinkConfig{
+		URL:        server.URL,
+		MaxRetries: 3,
+		Overflow:   overflow,
+	})
+	_, err := sink.Write([]byte("invalid\n"))
+	if statusErr, ok := err.(*HTTPStatusError); !ok || statusErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Writing should fail with the status, got %v.", err)
+	}
+	if overflow.Len() != 0 || c.failures != 0 || len(c.bodies) != 0 {
+		t.Errorf("The rejected batch should be dropped.")
+	}
+	if _, err := NewHTTPSink(HTTPSinkConfig{URL: "collector:8080"}); err != ErrInvalidSinkURL {
+		t.Errorf("A URL without scheme should be rejected, got %v.", err)
+	}
+}
+
+// TestHTTPBodyFormats tests the Elasticsearch and Loki body formats.
+func TestHTTPBodyFormats(t *testing.T) {
+	batch := [][]byte{[]byte("{\"time\":\"2020-01-02T03:04:05Z\"}"), []byte("{\"message\":\"x\"}")}
+	body, _, _ := ElasticsearchBulkBody("logs")(batch)
+	expected := "{\"index\":{\"_index\":\"logs\"}}\n{\"time\":\"2020-01-02T03:04:05Z\"}\n{\"index\":{\"_index\":\"logs\"}}\n{\"message\":\"x\"}\n"
+	if string(body) != expected {
+		t.Errorf("Bulk body '%s' should be '%s'.", body, expected)
+	}
+	body, contentType, err := LokiPushBody(map[string]string{"app": "api"})(batch)
+	if err != nil {
+		t.Errorf("Formatting for Loki errored with '%s'.", err.Error())
+	}
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(body, &push); err != nil {
+		t.Errorf("Decoding the Loki body errored with '%s'.", err.Error())
+	} else if contentType != "application/json" || len(push.Streams) != 1 || push.Streams[0].Stream["app"] != "api" || len(push.Streams[0].Values) != 2 {
+		t.Errorf("Loki body '%s' is invalid.", body)
+	} else if push.Streams[0].Values[0][0] != "1577934245000000000" {
+		t.Errorf("Loki timestamp %s should be the record's time.", push.Streams[0].Values[0][0])
+	}
+}
+
+// TestHTTPSinkWholeRecords tests that records reach the body format of an
+// HTTPSink whole through a BatchingSink and a PipelinedSink, even when they
+// span several lines.
+func TestHTTPSinkWholeRecords(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	httpSink := newTestHTTPSink(t, HTTPSinkConfig{URL: server.URL, Format: JSONArrayBody})
+	pipeline, _ := NewPipelinedSink(httpSink, PipelineConfig{})
+	sink := NewBatchingSink(pipeline, 0, 0)
+	sink.WriteRecord([]byte("{\n\"message\": \"indented\"\n}"))
+	DefaultLogger.WithWriter(sink).Info("Shipped.", nil)
+	if err := sink.Flush(); err != nil {
+		t.Errorf("Flushing errored with '%s'.", err.Error())
+	}
+	pipeline.Close()
+	var records []map[string]interface{}
+	if len(c.bodies) != 1 {
+		t.Errorf("The batch should be sent in one request, not %d.", len(c.bodies))
+	} else if err := json.Unmarshal([]byte(c.bodies[0]), &records); err != nil {
+		t.Errorf("Decoding the body '%s' errored with '%s'.", c.bodies[0], err.Error())
+	} else if len(records) != 2 || records[0]["message"] != "indented" || records[1]["message"] != "Shipped." {
+		t.Errorf("Body '%s' should hold both records.", c.bodies[0])
+	}
+}
+
+// negotiatingCollector accepts a single media type, without compression. It
+// advertises it on OPTIONS requests if `advertise' is set, and in 415
+// responses otherwise.
+type negotiatingCollector struct {
+	mediaType    string
+	advertise    bool
+	contentTypes []string
+}
+
+func (c *negotiatingCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		if c.advertise {
+			w.Header().Set("Accept-Post", "application/x-protobuf, "+c.mediaType+";q=0.9, application/x-ndjson;q=0.1")
+			w.Header().Set("Accept-Encoding", "identity")
+		}
+		return
+	}
+	c.contentTypes = append(c.contentTypes, r.Header.Get("Content-Type"))
+	if r.Header.Get("Content-Type") != c.mediaType || r.Header.Get("Content-Encoding") != "" {
+		w.Header().Set("Accept", c.mediaType)
+		w.Header().Set("Accept-Encoding", "identity")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	}
+}
+
+type testHTTPSinkNegotiationExample struct {
+	mediaType            string
+	advertise            bool
+	expectedContentTypes []string
+}
+
+var testHTTPSinkNegotiationExamples = []testHTTPSinkNegotiationExample{
+	{
+		mediaType:            "application/json",
+		advertise:            true,
+		expectedContentTypes: []string{"application/json", "application/json"},
+	},
+	{
+		mediaType:            "application/msgpack",
+		expectedContentTypes: []string{"application/x-ndjson", "application/msgpack", "application/msgpack"},
+	},
+}
+
+// TestHTTPSinkNegotiation tests switching formats and compression according
+// to what the endpoint accepts.
+func TestHTTPSinkNegotiation(t *testing.T) {
+	for _, example := range testHTTPSinkNegotiationExamples {
+		collector := &negotiatingCollector{mediaType: example.mediaType, advertise: example.advertise}
+		server := httptest.NewServer(collector)
+		sink := newTestHTTPSink(t, HTTPSinkConfig{
+			URL:       server.URL,
+			Gzip:      true,
+			Negotiate: true,
+		})
+		for i := 0; i < 2; i++ {
+			if _, err := sink.Write([]byte("{\"message\":\"negotiated\"}\n")); err != nil {
+				t.Errorf("Writing errored with '%s'.", err.Error())
+			}
+		}
+		server.Close()
+		if strings.Join(collector.contentTypes, ",") != strings.Join(example.expectedContentTypes, ",") {
+			t.Errorf("Content types %v should be %v.", collector.contentTypes, example.expectedContentTypes)
+		}
+	}
+}
+
+// TestHTTPSinkPing tests that Ping reports unreachable endpoints and denied
+// credentials.
+func TestHTTPSinkPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions || r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	sink := newTestHTTPSink(t, HTTPSinkConfig{URL: server.URL, Credentials: StaticCredentials("Authorization", "Bearer valid")})
+	if err := sink.Ping(); err != nil {
+		t.Errorf("Ping errored with '%s'.", err.Error())
+	}
+	denied := newTestHTTPSink(t, HTTPSinkConfig{URL: server.URL, Credentials: StaticCredentials("Authorization", "Bearer expired")})
+	if err, ok := denied.Ping().(*HTTPStatusError); !ok || err.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Ping should report denied credentials, got %v.", err)
+	}
+	server.Close()
+	if err := sink.Ping(); err == nil {
+		t.Errorf("Ping should fail once the endpoint is down.")
+	}
+}
@@ -0,0 +1,268 @@
+package jsonlog
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// recordIDKey is the context key record IDs are output under.
+	recordIDKey = "recordId"
+	// crockfordAlphabet is the base32 alphabet of ULIDs.
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// SnowflakeEpoch is the origin of the timestamps of snowflake IDs, in
+	// milliseconds since the Unix epoch: the one used by Twitter.
+	SnowflakeEpoch = int64(1288834974657)
+	// snowflakeNodeBits and snowflakeSequenceBits are the widths of the
+	// node ID and sequence number of snowflake IDs.
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	// MaxSnowflakeNode is the largest node ID of a SnowflakeGenerator.
+	MaxSnowflakeNode = 1<<snowflakeNodeBits - 1
+)
+
+var (
+	// ErrInvalidSnowflakeNode is returned when a snowflake node ID is out
+	// of range.
+	ErrInvalidSnowflakeNode = errors.New("jsonlog: snowflake node out of range")
+
+	// recordIDGenerator generates the record IDs of Loggers without an
+	// IDGenerator, so that they are ordered across the process.
+	recordIDGenerator = &MonotonicULIDGenerator{}
+)
+
+// IDGenerator generates the record IDs and correlation IDs of a Logger and
+// the request IDs of HTTPMiddleware. Implementations must be safe for
+// concurrent use.
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc is a function used as an IDGenerator.
+type IDGeneratorFunc func() string
+
+// RandomHexGenerator generates 32 random hexadecimal digits. It is the
+// default generator.
+type RandomHexGenerator struct{}
+
+// UUIDv4Generator generates random UUIDs, as per RFC 9562.
+type UUIDv4Generator struct{}
+
+// UUIDv7Generator generates UUIDs starting with a millisecond timestamp, as
+// per RFC 9562, so that they sort by creation time across processes.
+type UUIDv7Generator struct{}
+
+// ULIDGenerator generates ULIDs: 26 characters which sort by creation time,
+// to the millisecond, followed by 80 random bits.
+type ULIDGenerator struct{}
+
+// MonotonicULIDGenerator generates ULIDs which are strictly increasing within
+// the process: within a millisecond, the random part of each ULID is that of
+// the previous one plus one. If the clock goes backwards, the last
+// millisecond is reused. Its zero value is ready to use.
+type MonotonicULIDGenerator struct {
+	mutex  sync.Mutex
+	last   int64
+	random [10]byte
+}
+
+// SnowflakeGenerator generates 64-bit snowflake IDs made of a millisecond
+// timestamp since SnowflakeEpoch, a node ID and a sequence number, output in
+// decimal. They are compact and sorted, and unique as long as each process
+// generating them has its own node ID.
+type SnowflakeGenerator struct {
+	node     int64
+	mutex    sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// NewID calls `f'.
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// NewID generates a random hexadecimal ID.
+func (RandomHexGenerator) NewID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// NewID generates a version 4 UUID.
+func (UUIDv4Generator) NewID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return formatUUID(id)
+}
+
+// NewID generates a version 7 UUID.
+func (UUIDv7Generator) NewID() string {
+	var id [16]byte
+	rand.Read(id[6:])
+	putMilliseconds(id[:6], time.Now())
+	id[6] = id[6]&0x0f | 0x70
+	id[8] = id[8]&0x3f | 0x80
+	return formatUUID(id)
+}
+
+// NewID generates a ULID.
+func (ULIDGenerator) NewID() string {
+	var id [16]byte
+	rand.Read(id[6:])
+	putMilliseconds(id[:6], time.Now())
+	return formatULID(id)
+}
+
+// NewID generates the next ULID. If the random part overflows within a
+// millisecond, it waits for the next one.
+func (g *MonotonicULIDGenerator) NewID() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	now := time.Now().UnixMilli()
+	if now <= g.last && incrementBytes(g.random[:]) {
+		now = g.last
+	} else {
+		for now <= g.last {
+			time.Sleep(100 * time.Microsecond)
+			now = time.Now().UnixMilli()
+		}
+		rand.Read(g.random[:])
+	}
+	g.last = now
+	var id [16]byte
+	putMilliseconds(id[:6], time.UnixMilli(now))
+	copy(id[6:], g.random[:])
+	return formatULID(id)
+}
+
+// incrementBytes adds one to a big-endian number. It returns false if it
+// overflowed.
+func incrementBytes(number []byte) bool {
+	for i := len(number) - 1; i >= 0; i-- {
+		number[i]++
+		if number[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSnowflakeGenerator creates a SnowflakeGenerator for the given node, from
+// 0 to MaxSnowflakeNode.
+func NewSnowflakeGenerator(node int64) (*SnowflakeGenerator, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, ErrInvalidSnowflakeNode
+	}
+	return &SnowflakeGenerator{node: node}, nil
+}
+
+// NewID generates a snowflake ID. If the sequence of the current millisecond
+// is exhausted, it waits for the next one.
+func (g *SnowflakeGenerator) NewID() string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	now := time.Now().UnixMilli() - SnowflakeEpoch
+	if now < g.last {
+		now = g.last
+	}
+	if now == g.last {
+		g.sequence = (g.sequence + 1) & (1<<snowflakeSequenceBits - 1)
+		if g.sequence == 0 {
+			for now <= g.last {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Now().UnixMilli() - SnowflakeEpoch
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.last = now
+	id := now<<(snowflakeNodeBits+snowflakeSequenceBits) | g.node<<snowflakeSequenceBits | g.sequence
+	return strconv.FormatInt(id, 10)
+}
+
+// WithIDGenerator returns a new Logger generating its record IDs and new
+// correlation IDs with `g'. HTTPMiddleware generates request IDs with the
+// generator of its Logger. A nil generator restores the defaults: a
+// MonotonicULIDGenerator shared by the process for record IDs and
+// RandomHexGenerator for the others.
+func (l Logger) WithIDGenerator(g IDGenerator) Logger {
+	l.idGenerator = g
+	return l
+}
+
+// WithRecordIDs returns a new Logger whose messages carry a unique ID under
+// the "recordId" context key, so that duplicates can be detected downstream.
+// By default these IDs are ULIDs strictly ordered by emission within the
+// process.
+func (l Logger) WithRecordIDs() Logger {
+	l.recordIDs = true
+	return l
+}
+
+// WithNewCorrelationID returns a new Logger with a correlation ID generated
+// by its IDGenerator.
+func (l Logger) WithNewCorrelationID() Logger {
+	return l.WithCorrelationID(l.newID())
+}
+
+// newID generates an ID with the Logger's IDGenerator.
+func (l Logger) newID() string {
+	if l.idGenerator == nil {
+		return RandomHexGenerator{}.NewID()
+	}
+	return l.idGenerator.NewID()
+}
+
+// newRecordID generates a record ID with the Logger's IDGenerator.
+func (l Logger) newRecordID() string {
+	if l.idGenerator == nil {
+		return recordIDGenerator.NewID()
+	}
+	return l.idGenerator.NewID()
+}
+
+// putMilliseconds writes the 48-bit big-endian Unix time of `t' in
+// milliseconds to `dst'.
+func putMilliseconds(dst []byte, t time.Time) {
+	var buffer [8]byte
+	binary.BigEndian.PutUint64(buffer[:], uint64(t.UnixMilli()))
+	copy(dst, buffer[2:])
+}
+
+// formatUUID formats a UUID in its canonical, hyphenated form.
+func formatUUID(id [16]byte) string {
+	var text [36]byte
+	hex.Encode(text[0:8], id[0:4])
+	text[8] = '-'
+	hex.Encode(text[9:13], id[4:6])
+	text[13] = '-'
+	hex.Encode(text[14:18], id[6:8])
+	text[18] = '-'
+	hex.Encode(text[19:23], id[8:10])
+	text[23] = '-'
+	hex.Encode(text[24:], id[10:])
+	return string(text[:])
+}
+
+// formatULID encodes 128 bits in Crockford's base32, five bits at a time
+// starting with the two most significant.
+func formatULID(id [16]byte) string {
+	high := binary.BigEndian.Uint64(id[:8])
+	low := binary.BigEndian.Uint64(id[8:])
+	var text [26]byte
+	for i := 25; i >= 0; i-- {
+		text[i] = crockfordAlphabet[low&0x1f]
+		low = low>>5 | high<<59
+		high >>= 5
+	}
+	return string(text[:])
+}
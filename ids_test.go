@@ -0,0 +1,120 @@
+package jsonlog
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testIDGeneratorExample struct {
+	generator IDGenerator
+	pattern   *regexp.Regexp
+}
+
+var testIDGeneratorExamples = []testIDGeneratorExample{
+	{RandomHexGenerator{}, regexp.MustCompile(`^[0-9a-f]{32}$`)},
+	{UUIDv4Generator{}, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+	{UUIDv7Generator{}, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)},
+	{ULIDGenerator{}, regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)},
+	{IDGeneratorFunc(func() string { return "fixed" }), regexp.MustCompile(`^fixed$`)},
+}
+
+// TestIDGenerators tests the format of the generated IDs.
+func TestIDGenerators(t *testing.T) {
+	for _, example := range testIDGeneratorExamples {
+		id := example.generator.NewID()
+		if !example.pattern.MatchString(id) {
+			t.Errorf("ID '%s' should match %s.", id, example.pattern)
+		}
+	}
+}
+
+// TestSnowflakeGenerator tests that snowflake IDs increase and carry the node
+// ID.
+func TestSnowflakeGenerator(t *testing.T) {
+	if _, err := NewSnowflakeGenerator(MaxSnowflakeNode + 1); err != ErrInvalidSnowflakeNode {
+		t.Errorf("An out of range node should be rejected, got %v.", err)
+	}
+	generator, err := NewSnowflakeGenerator(42)
+	if err != nil {
+		t.Errorf("Creating the generator errored with '%s'.", err.Error())
+		return
+	}
+	previous := int64(0)
+	for i := 0; i < 10000; i++ {
+		id, err := strconv.ParseInt(generator.NewID(), 10, 64)
+		if err != nil || id <= previous {
+			t.Errorf("ID %d should be greater than %d.", id, previous)
+			return
+		}
+		if node := id >> snowflakeSequenceBits & MaxSnowflakeNode; node != 42 {
+			t.Errorf("ID %d should have node 42, not %d.", id, node)
+			return
+		}
+		previous = id
+	}
+}
+
+// TestFormatULID tests the base32 encoding of ULIDs.
+func TestFormatULID(t *testing.T) {
+	var id [16]byte
+	for i := range id {
+		id[i] = 0xff
+	}
+	if ulid := formatULID(id); ulid != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("ULID '%s' should be the largest one.", ulid)
+	}
+	id = [16]byte{15: 1}
+	if ulid := formatULID(id); ulid != "00000000000000000000000001" {
+		t.Errorf("ULID '%s' should be one.", ulid)
+	}
+}
+
+// TestRecordIDs tests that each record gets an ID from the Logger's
+// generator, and that correlation IDs can be generated with it.
+func TestRecordIDs(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	count := 0
+	logger := DefaultLogger.WithWriter(buffer).WithRecordIDs().WithIDGenerator(IDGeneratorFunc(func() string {
+		count++
+		return "id" + strconv.Itoa(count)
+	})).WithNewCorrelationID()
+	logger.Info("first", nil)
+	logger.Info("second", nil)
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Errorf("Output '%s' should hold two records.", buffer.String())
+		return
+	}
+	for i, expected := range []string{
+		`"context":{"correlationId":"id1","recordId":"id2"}`,
+		`"context":{"correlationId":"id1","recordId":"id3"}`,
+	} {
+		if !strings.Contains(lines[i], expected) {
+			t.Errorf("Record '%s' should contain '%s'.", lines[i], expected)
+		}
+	}
+}
+
+// TestMonotonicULIDGenerator tests that ULIDs generated within the same
+// millisecond are strictly increasing.
+func TestMonotonicULIDGenerator(t *testing.T) {
+	generator := &MonotonicULIDGenerator{}
+	previous := generator.NewID()
+	for i := 0; i < 10000; i++ {
+		id := generator.NewID()
+		if id <= previous {
+			t.Errorf("ULID '%s' should be greater than '%s'.", id, previous)
+			return
+		}
+		previous = id
+	}
+	generator.random = [10]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	generator.last = time.Now().UnixMilli()
+	if id := generator.NewID(); id <= previous {
+		t.Errorf("ULID '%s' after an overflow should be greater than '%s'.", id, previous)
+	}
+}
@@ -3,8 +3,8 @@
 package jsonlog
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"io"
 	"os"
 	"time"
@@ -19,21 +19,58 @@ type LogLevel uint
 type contextKey uint
 
 // Logger logs messages to an io.Writer in JSON format, possibly extracting
-// values from its Context.
+// values from its Context. The output format can be changed with
+// WithFormatter.
 type Logger struct {
-	encoder     *json.Encoder
-	logLevel    LogLevel
-	contextKeys map[interface{}]string
-	context     context.Context
+	writer         io.Writer
+	formatter      Formatter
+	logLevel       LogLevel
+	atomicLevel    *AtomicLevel
+	sampler        *Sampler
+	hooks          []Hook
+	err            error
+	stackTrace     bool
+	exitFunc       func(int)
+	clock          func() time.Time
+	location       *time.Location
+	contextKeys    map[interface{}]string
+	context        context.Context
+	floatPolicy    FloatPolicy
+	name           string
+	levelOverrides map[string]LogLevel
+	componentKey   string
+	filter         func(level LogLevel, message string, data interface{}) bool
+	metrics        *Metrics
+	errorHandler   func(err error, m Message)
+	fallbackWriter io.Writer
+	correlationID  string
+	staticFields   map[string]interface{}
+	idGenerator    IDGenerator
+	recordIDs      bool
+	delta          *deltaState
+	staticRef      string
+	dictionary     *StringDictionary
+	caller         bool
+	assertPanics   bool
 }
 
-// message represents a single messaged logged by a Logger.
-type message struct {
-	Level   string                 `json:"level"`
-	Time    time.Time              `json:"time"`
-	Message string                 `json:"message"`
-	Data    interface{}            `json:"data,omitempty"`
-	Context map[string]interface{} `json:"context,omitempty"`
+// Message represents a single messaged logged by a Logger. It is what a
+// Formatter receives to produce a log record.
+type Message struct {
+	Level    string                 `json:"level"`
+	Time     time.Time              `json:"time"`
+	Message  string                 `json:"message"`
+	Logger   string                 `json:"logger,omitempty"`
+	Data     interface{}            `json:"data,omitempty"`
+	Context  map[string]interface{} `json:"context,omitempty"`
+	Error    *ErrorInfo             `json:"error,omitempty"`
+	Stack    string                 `json:"stack,omitempty"`
+	Repeated int                    `json:"repeated,omitempty"`
+
+	logLevel LogLevel
+	// staticKeys are the keys of the static fields set in delta mode,
+	// removed before writing unless they are due in full.
+	staticKeys []string
 }
 
 const (
@@ -41,30 +78,48 @@ const (
 	LogLevelInfo
 	LogLevelWarning
 	LogLevelError
+	LogLevelPanic
+	LogLevelFatal
+)
 
+const (
 	contextKeyLogger = contextKey(iota)
+	contextKeyRequestID
 )
 
 var (
-	// logLevelNames maps predefined log levels to their string
-	// representations.
+	// logLevelNames maps predefined and registered log levels to their
+	// string representations.
 	logLevelNames = map[LogLevel]string{
 		LogLevelDebug:   "debug",
 		LogLevelInfo:    "info",
 		LogLevelWarning: "warning",
 		LogLevelError:   "error",
+		LogLevelPanic:   "panic",
+		LogLevelFatal:   "fatal",
 	}
 
 	// DefaultLogger logs to the standard output, filtering out debug
 	// messages, and uses the background context.
 	DefaultLogger = Logger{
-		encoder:     json.NewEncoder(os.Stdout),
+		writer:      os.Stdout,
+		formatter:   JSONFormatter{},
 		logLevel:    LogLevelInfo,
 		contextKeys: nil,
 		context:     context.Background(),
 	}
 )
 
+// logLevelFromName finds the predefined log level with the given name.
+func logLevelFromName(name string) (LogLevel, bool) {
+	for logLevel, logLevelName := range logLevelNames {
+		if logLevelName == name {
+			return logLevel, true
+		}
+	}
+	return 0, false
+}
+
 // Debug is a shorthand for logging with level Debug.
 func (l Logger) Debug(str string, data interface{}) error { return l.Log(LogLevelDebug, str, data) }
 
@@ -78,48 +133,180 @@ func (l Logger) Warning(str string, data interface{}) error { return l.Log(LogLe
 func (l Logger) Error(str string, data interface{}) error { return l.Log(LogLevelError, str, data) }
 
 // Debug is a shorthand for debug logging on default logger.
-func Debug(str string, data interface{}) error { return DefaultLogger.Debug(str, data) }
+func Debug(str string, data interface{}) error { return checkedDefaultLogger().Debug(str, data) }
 
 // Info is a shorthand for info logging on default logger.
-func Info(str string, data interface{}) error { return DefaultLogger.Info(str, data) }
+func Info(str string, data interface{}) error { return checkedDefaultLogger().Info(str, data) }
 
 // Warning is a shorthand for warning logging on default logger.
-func Warning(str string, data interface{}) error { return DefaultLogger.Warning(str, data) }
+func Warning(str string, data interface{}) error { return checkedDefaultLogger().Warning(str, data) }
 
 // Error is a shorthand for error logging on default logger.
-func Error(str string, data interface{}) error { return DefaultLogger.Error(str, data) }
+func Error(str string, data interface{}) error { return checkedDefaultLogger().Error(str, data) }
 
 // Log is a shorthand for logging on default logger.
 func Log(logLevel LogLevel, str string, data interface{}) error {
-	return DefaultLogger.Log(logLevel, str, data)
+	return checkedDefaultLogger().Log(logLevel, str, data)
 }
 
-// Log logs a message as specified by the Logger. Each message is output as a
-// JSON object with `str' in the "message" field, `data' in the "data" field
-// (if not nil) and values from the context in "context".
+// Log logs a message as specified by the Logger. With the default JSON
+// formatter each message is output as a JSON object with `str' in the
+// "message" field, `data' in the "data" field (if not nil) and values from the
+// context in "context".
 func (l Logger) Log(logLevel LogLevel, str string, data interface{}) error {
-	if l.shouldLog(logLevel) {
-		return l.doLog(logLevel, str, data)
-	} else {
+	return l.log(logLevel, str, data, nil)
+}
+
+// LogWithKeys is like Log, but also outputs the values of the context at the
+// keys of `extraKeys' under the names they map to, as if they had been
+// added with WithContextKey. It saves deriving a Logger, and copying its map
+// of keys, for a single call in hot paths. Keys the Logger already maps are
+// output under both names.
+func (l Logger) LogWithKeys(logLevel LogLevel, str string, data interface{}, extraKeys map[interface{}]string) error {
+	return l.log(logLevel, str, data, extraKeys)
+}
+
+// log logs a message with the values of the context at `extraKeys' on top of
+// those of the Logger.
+func (l Logger) log(logLevel LogLevel, str string, data interface{}, extraKeys map[interface{}]string) error {
+	if d, ok := data.(Data); ok {
+		defer d.release()
+	}
+	if !l.shouldLog(logLevel, str, data) {
 		return nil
 	}
+	now := l.now()
+	repeated := 0
+	if l.sampler != nil && logLevel.rank() < LogLevelPanic.rank() {
+		var sampled bool
+		sampled, repeated = l.sampler.sample(logLevel, str, now)
+		if !sampled {
+			l.metrics.countDropped(dropSampled)
+			return nil
+		}
+	}
+	m := l.newMessage(logLevel, now, str, data)
+	m.Repeated = repeated
+	for contextKey, messageKey := range extraKeys {
+		if contextValue := l.context.Value(contextKey); contextValue != nil {
+			if m.Context == nil {
+				m.Context = make(map[string]interface{}, len(extraKeys))
+			}
+			m.Context[messageKey] = contextValue
+		}
+	}
+	return l.doLog(&m)
 }
 
-// shouldLog determines whether the logger should log a given log level.
-func (l Logger) shouldLog(logLevel LogLevel) bool {
-	return logLevel >= l.logLevel
+// shouldLog determines whether the logger should log a given message,
+// according to its level, component and filter.
+func (l Logger) shouldLog(logLevel LogLevel, str string, data interface{}) bool {
+	if logLevel.rank() < l.minimumLevel(data).rank() {
+		return false
+	}
+	if l.filter != nil && !l.filter(logLevel, str, data) {
+		l.metrics.countDropped(dropFiltered)
+		return false
+	}
+	return true
 }
 
-// doLog performs the logging operation with no additional checks.
-func (l Logger) doLog(logLevel LogLevel, str string, data interface{}) error {
-	m := message{
-		Message: str,
-		Level:   logLevelNames[logLevel],
-		Time:    time.Now(),
-		Context: getMessageValuesFromContext(l),
-		Data:    data,
+// now returns the current time according to the Logger's clock, in the
+// Logger's time zone.
+func (l Logger) now() time.Time {
+	var now time.Time
+	if l.clock != nil {
+		now = l.clock()
+	} else {
+		now = time.Now()
+	}
+	if l.location != nil {
+		now = now.In(l.location)
 	}
-	return l.encoder.Encode(m)
+	return now
+}
+
+// newMessage builds the Message for a log call.
+func (l Logger) newMessage(logLevel LogLevel, now time.Time, str string, data interface{}) Message {
+	m := Message{
+		Message:  str,
+		Logger:   l.name,
+		Level:    logLevelNames[logLevel],
+		Time:     now,
+		Context:  getMessageValuesFromContext(l),
+		Data:     data,
+		logLevel: logLevel,
+	}
+	l.addStaticFields(&m)
+	if l.caller {
+		if m.Context == nil {
+			m.Context = make(map[string]interface{}, 1)
+		}
+		m.Context[callerKey] = captureCaller()
+	}
+	if l.err != nil {
+		m.Error = newErrorInfo(l.err)
+		if l.stackTrace {
+			m.Stack = captureStack()
+		}
+	}
+	return m
+}
+
+// doLog runs the hooks on a message and outputs it.
+func (l Logger) doLog(m *Message) error {
+	if ok, err := l.runHooks(m); !ok {
+		return err
+	}
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+	// The dictionary may shorten the message: profiles use the original.
+	message := m.Message
+	formatFailed, err := l.writeMessage(buffer, m)
+	if formatFailed {
+		l.metrics.countEncodeError()
+		l.handleError(err, m)
+		return err
+	} else if err != nil {
+		l.metrics.countWriteError()
+		l.handleWriteError(err, m, buffer.Bytes())
+		return err
+	}
+	l.metrics.countEmitted(m, message, buffer.Len())
+	return nil
+}
+
+// writeMessage formats a message into `buffer' and writes it, and reports
+// whether a failure happened while formatting. In delta mode and with a
+// dictionary, the message is completed, formatted and written while their
+// state is locked, so that records reach the writer in the order their
+// references were defined.
+func (l Logger) writeMessage(buffer *bytes.Buffer, m *Message) (formatFailed bool, err error) {
+	if l.delta != nil && l.staticRef != "" {
+		l.delta.mutex.Lock()
+		defer l.delta.mutex.Unlock()
+		if l.delta.trim(l.staticRef, m, len(l.staticFields)) {
+			defer func() {
+				if err != nil {
+					delete(l.delta.lastFull, l.staticRef)
+				}
+			}()
+		}
+	}
+	if l.dictionary != nil {
+		l.dictionary.mutex.Lock()
+		defer l.dictionary.mutex.Unlock()
+		defined := l.dictionary.shorten(m)
+		defer func() {
+			if err != nil {
+				l.dictionary.forget(defined)
+			}
+		}()
+	}
+	if err := l.formatWithFloatPolicy(buffer, m); err != nil {
+		return true, err
+	}
+	return false, writeRecord(l.writer, m.logLevel, buffer.Bytes())
 }
 
 // getMessageValuesFromContext builds the map of values taken from the context.
@@ -128,10 +315,13 @@ func (l Logger) doLog(logLevel LogLevel, str string, data interface{}) error {
 // will look for context value ContextKey(42) and if it exists, output it under
 // "life".
 func getMessageValuesFromContext(l Logger) map[string]interface{} {
-	output := map[string]interface{}{}
+	var output map[string]interface{}
 	for contextKey, messageKey := range l.contextKeys {
 		contextValue := l.context.Value(contextKey)
 		if contextValue != nil {
+			if output == nil {
+				output = make(map[string]interface{}, len(l.contextKeys))
+			}
 			output[messageKey] = contextValue
 		}
 	}
@@ -140,70 +330,88 @@ func getMessageValuesFromContext(l Logger) map[string]interface{} {
 
 // WithWriter returns a new Logger writing to the given Writer.
 func (l Logger) WithWriter(w io.Writer) Logger {
-	return Logger{
-		encoder:     json.NewEncoder(w),
-		logLevel:    l.logLevel,
-		contextKeys: l.contextKeys,
-		context:     l.context,
-	}
+	l.writer = w
+	return l
+}
+
+// WithFormatter returns a new Logger formatting its messages with the given
+// Formatter.
+func (l Logger) WithFormatter(f Formatter) Logger {
+	l.formatter = f
+	return l
 }
 
-// WithLogLevel returns a new Logger with the given log level.
+// WithLogLevel returns a new Logger with the given log level. The new Logger
+// is not bound to any AtomicLevel.
 func (l Logger) WithLogLevel(logLevel LogLevel) Logger {
-	return Logger{
-		encoder:     l.encoder,
-		logLevel:    logLevel,
-		contextKeys: l.contextKeys,
-		context:     l.context,
-	}
+	l.logLevel = logLevel
+	l.atomicLevel = nil
+	return l
+}
+
+// WithAtomicLevel returns a new Logger whose log level is read from `a' each
+// time a message is logged.
+func (l Logger) WithAtomicLevel(a *AtomicLevel) Logger {
+	l.atomicLevel = a
+	return l
+}
+
+// WithSampler returns a new Logger whose messages are thinned out by `s'. A
+// nil Sampler disables sampling.
+func (l Logger) WithSampler(s *Sampler) Logger {
+	l.sampler = s
+	return l
 }
 
 // WithContext returns a new Logger with the given context.
 func (l Logger) WithContext(ctx context.Context) Logger {
-	return Logger{
-		encoder:     l.encoder,
-		logLevel:    l.logLevel,
-		contextKeys: l.contextKeys,
-		context:     ctx,
-	}
+	l.context = ctx
+	return l
 }
 
 // WithContextKey returns a new Logger which will extract from the context the
 // value at `contextKey' and output it under `messageKey' in the JSON message.
 func (l Logger) WithContextKey(contextKey interface{}, messageKey string) Logger {
-	newLogger := Logger{
-		encoder:     l.encoder,
-		logLevel:    l.logLevel,
-		contextKeys: nil,
-		context:     l.context,
-	}
 	if l.contextKeys == nil {
-		newLogger.contextKeys = map[interface{}]string{
+		l.contextKeys = map[interface{}]string{
 			contextKey: messageKey,
 		}
 	} else {
-		newLogger.contextKeys = shallowCopyMap(l.contextKeys)
-		newLogger.contextKeys[contextKey] = messageKey
+		l.contextKeys = shallowCopyMap(l.contextKeys)
+		l.contextKeys[contextKey] = messageKey
 	}
-	return newLogger
+	return l
 }
 
 // ContextWithLogger creates a new context holding a given logger.
 // The logger can be retrieved with LoggerFromContextOrDefault.
 func ContextWithLogger(ctx context.Context, logger Logger) context.Context {
+	hintLoggerAvailable()
 	return context.WithValue(ctx, contextKeyLogger, logger)
 }
 
 // LoggerFromContextOrDefault gets a Logger from the current context if there
-// is one. Otherwise it returns the default logger.
+// is one. Otherwise it returns the default logger, and warns about it if
+// SetFallbackWarnings was enabled.
 func LoggerFromContextOrDefault(ctx context.Context) Logger {
-	value := ctx.Value(contextKeyLogger)
-	logger, ok := value.(Logger)
+	logger, ok := LoggerFromContext(ctx)
 	if ok {
 		return logger
 	} else {
-		return DefaultLogger
+		warnFallback()
+		return checkedDefaultLogger()
+	}
+}
+
+// LoggerFromContext gets a Logger from the current context. The boolean is
+// false if the context holds none, which usually means that a Logger was not
+// propagated where it should have been.
+func LoggerFromContext(ctx context.Context) (Logger, bool) {
+	logger, ok := ctx.Value(contextKeyLogger).(Logger)
+	if ok {
+		hintLoggerAvailable()
 	}
+	return logger, ok
 }
 
 // shallowCopyMap makes a shallow copy of a map[interface{}]string.
@@ -66,7 +66,7 @@ func TestSimpleLogs(t *testing.T) {
 		if err != nil {
 			t.Errorf("Logging errored with '%s'.", err.Error())
 		} else {
-			output := message{}
+			output := Message{}
 			err := json.Unmarshal(buffer.Bytes(), &output)
 			if err != nil {
 				t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
@@ -180,3 +180,29 @@ func TestLogsWithContextData(t *testing.T) {
 		}
 	}
 }
+
+// TestLogWithKeys tests that the extra keys of a single call are output
+// along with the keys of the Logger, without changing the Logger.
+func TestLogWithKeys(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "tenant", "acme")
+	ctx = context.WithValue(ctx, "shard", 7)
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithContext(ctx).WithContextKey("tenant", "tenant")
+	extraKeys := map[interface{}]string{"shard": "shard", "missing": "missing"}
+	if err := logger.LogWithKeys(LogLevelInfo, "log", nil, extraKeys); err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+	}
+	output := struct {
+		Context map[string]interface{} `json:"context"`
+	}{}
+	if err := json.Unmarshal(buffer.Bytes(), &output); err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+	} else if len(output.Context) != 2 || output.Context["tenant"] != "acme" || output.Context["shard"] != float64(7) {
+		t.Errorf("Context is %v but should hold the tenant and the shard.", output.Context)
+	}
+	buffer.Reset()
+	logger.Info("log", nil)
+	if bytes.Contains(buffer.Bytes(), []byte("shard")) {
+		t.Errorf("The extra keys should not be kept by the Logger, got '%s'.", buffer.String())
+	}
+}
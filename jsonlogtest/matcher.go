@@ -0,0 +1,75 @@
+package jsonlogtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/trackit/jsonlog"
+)
+
+// Matcher tells whether an entry has some property.
+type Matcher func(e Entry) bool
+
+// matchAll tells whether an entry matches all the matchers.
+func matchAll(e Entry, matchers []Matcher) bool {
+	for _, matcher := range matchers {
+		if !matcher(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// Level matches the entries at the given level.
+func Level(level jsonlog.LogLevel) Matcher {
+	return func(e Entry) bool {
+		return e.Level == level.String()
+	}
+}
+
+// MessageContains matches the entries whose message contains `substring'.
+func MessageContains(substring string) Matcher {
+	return func(e Entry) bool {
+		return strings.Contains(e.Message, substring)
+	}
+}
+
+// Named matches the entries logged by the Logger with the given name.
+func Named(name string) Matcher {
+	return FieldEquals("logger", name)
+}
+
+// HasField matches the entries which have a value at a dot-separated path
+// such as "context.requestId".
+func HasField(path string) Matcher {
+	return func(e Entry) bool {
+		_, ok := e.Field(path)
+		return ok
+	}
+}
+
+// FieldEquals matches the entries whose value at a dot-separated path equals
+// `value' once output as JSON, so that FieldEquals("data.status", 200) matches
+// a status logged as an int, an int64 or a float64.
+func FieldEquals(path string, value interface{}) Matcher {
+	expected, err := normalize(value)
+	return func(e Entry) bool {
+		actual, ok := e.Field(path)
+		return ok && err == nil && reflect.DeepEqual(actual, expected)
+	}
+}
+
+// normalize converts a value to what it decodes to once output as JSON.
+func normalize(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var normalized interface{}
+	err = decoder.Decode(&normalized)
+	return normalized, err
+}
@@ -0,0 +1,172 @@
+// Package jsonlogtest provides an in-memory sink for jsonlog and assertion
+// helpers, so that tests can check what code logged without parsing JSON
+// from a bytes.Buffer by hand.
+package jsonlogtest
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/trackit/jsonlog"
+	"github.com/trackit/jsonlog/reader"
+)
+
+// Entry is a message recorded by a Sink.
+type Entry struct {
+	Level   string
+	Message string
+	// Record holds all the fields of the message as decoded from its
+	// JSON output, with numbers as json.Number.
+	Record reader.Record
+}
+
+// Field returns the value at a dot-separated path such as "data.user.id".
+func (e Entry) Field(path string) (interface{}, bool) {
+	return e.Record.Lookup(path)
+}
+
+// Sink is an io.Writer recording the messages of a Logger using the JSON
+// formatter. It is safe for concurrent use.
+type Sink struct {
+	mutex   sync.Mutex
+	pending []byte
+	entries []Entry
+}
+
+// NewSink creates an empty Sink.
+func NewSink() *Sink {
+	return &Sink{}
+}
+
+// Logger returns a Logger writing to the Sink at the debug level, derived
+// from jsonlog.DefaultLogger.
+func (s *Sink) Logger() jsonlog.Logger {
+	return jsonlog.DefaultLogger.WithWriter(s).WithLogLevel(jsonlog.LogLevelDebug)
+}
+
+// Write decodes and records the records of `p'. A record split across
+// several writes is decoded once complete.
+func (s *Sink) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending = append(s.pending, p...)
+	for {
+		i := bytes.IndexByte(s.pending, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := s.pending[:i]
+		s.pending = s.pending[i+1:]
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		record, err := reader.NewDecoder(bytes.NewReader(line)).Decode()
+		if err != nil {
+			return len(p), err
+		}
+		s.entries = append(s.entries, newEntry(record))
+	}
+}
+
+// WriteRecord decodes and records a single record, implementing
+// jsonlog.RecordWriter.
+func (s *Sink) WriteRecord(record []byte) error {
+	decoded, err := reader.NewDecoder(bytes.NewReader(record)).Decode()
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = append(s.entries, newEntry(decoded))
+	return nil
+}
+
+// newEntry builds an Entry from a decoded record.
+func newEntry(record reader.Record) Entry {
+	level, _ := record["level"].(string)
+	message, _ := record["message"].(string)
+	return Entry{
+		Level:   level,
+		Message: message,
+		Record:  record,
+	}
+}
+
+// Entries returns the recorded entries, oldest first.
+func (s *Sink) Entries() []Entry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	entries := make([]Entry, len(s.entries))
+	copy(entries, s.entries)
+	return entries
+}
+
+// LastEntry returns the last recorded entry. The boolean is false if nothing
+// was recorded.
+func (s *Sink) LastEntry() (Entry, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.entries) == 0 {
+		return Entry{}, false
+	}
+	return s.entries[len(s.entries)-1], true
+}
+
+// Filter returns the recorded entries matching all the matchers.
+func (s *Sink) Filter(matchers ...Matcher) []Entry {
+	var entries []Entry
+	for _, entry := range s.Entries() {
+		if matchAll(entry, matchers) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Reset forgets the recorded entries.
+func (s *Sink) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pending = nil
+	s.entries = nil
+}
+
+// AssertLogged fails the test unless an entry at the given level has a
+// message containing `substring' and matches all the matchers. It returns the
+// first such entry.
+func (s *Sink) AssertLogged(t testing.TB, level jsonlog.LogLevel, substring string, matchers ...Matcher) Entry {
+	t.Helper()
+	entries := s.Filter(append([]Matcher{Level(level), MessageContains(substring)}, matchers...)...)
+	if len(entries) == 0 {
+		t.Errorf("No %s message containing '%s' matched; logged: %s.", level, substring, s.summary())
+		return Entry{}
+	}
+	return entries[0]
+}
+
+// AssertNotLogged fails the test if an entry at the given level has a
+// message containing `substring' and matches all the matchers.
+func (s *Sink) AssertNotLogged(t testing.TB, level jsonlog.LogLevel, substring string, matchers ...Matcher) {
+	t.Helper()
+	entries := s.Filter(append([]Matcher{Level(level), MessageContains(substring)}, matchers...)...)
+	if len(entries) > 0 {
+		t.Errorf("A %s message containing '%s' was logged: '%s'.", level, substring, entries[0].Message)
+	}
+}
+
+// summary describes the recorded entries for failure messages.
+func (s *Sink) summary() string {
+	entries := s.Entries()
+	if len(entries) == 0 {
+		return "nothing"
+	}
+	var buffer bytes.Buffer
+	for i, entry := range entries {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		buffer.WriteString(entry.Level + " '" + entry.Message + "'")
+	}
+	return buffer.String()
+}
@@ -0,0 +1,98 @@
+package jsonlogtest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/trackit/jsonlog"
+)
+
+// recordingT records the failures of assertions instead of failing the
+// test.
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+// TestSink tests that messages are recorded and can be inspected.
+func TestSink(t *testing.T) {
+	sink := NewSink()
+	logger := sink.Logger().Named("billing")
+	logger.Debug("Starting.", nil)
+	logger.WithError(errors.New("timeout")).Error("Charge failed.", map[string]interface{}{"amount": 12.5, "attempt": 3})
+	entries := sink.Entries()
+	if len(entries) != 2 {
+		t.Errorf("%d entries were recorded instead of 2.", len(entries))
+		return
+	}
+	if entries[0].Level != "debug" || entries[0].Message != "Starting." {
+		t.Errorf("First entry is %s '%s'.", entries[0].Level, entries[0].Message)
+	}
+	last, ok := sink.LastEntry()
+	if !ok || last.Message != "Charge failed." {
+		t.Errorf("Last entry is '%s'.", last.Message)
+	}
+	if value, ok := last.Field("error.message"); !ok || value != "timeout" {
+		t.Errorf("Field error.message is %v.", value)
+	}
+	sink.Reset()
+	if _, ok := sink.LastEntry(); ok {
+		t.Errorf("Reset sink should have no entries.")
+	}
+}
+
+type testAssertExample struct {
+	level     jsonlog.LogLevel
+	substring string
+	matchers  []Matcher
+	failed    bool
+}
+
+// TestAssertLogged tests the assertion helpers and matchers.
+func TestAssertLogged(t *testing.T) {
+	sink := NewSink()
+	sink.Logger().Named("http").Info("Request served.", map[string]interface{}{"status": 200, "path": "/"})
+	examples := []testAssertExample{
+		testAssertExample{jsonlog.LogLevelInfo, "served", nil, false},
+		testAssertExample{jsonlog.LogLevelInfo, "", []Matcher{FieldEquals("data.status", 200)}, false},
+		testAssertExample{jsonlog.LogLevelInfo, "", []Matcher{FieldEquals("data.status", 200.0)}, false},
+		testAssertExample{jsonlog.LogLevelInfo, "", []Matcher{FieldEquals("data.status", "200")}, true},
+		testAssertExample{jsonlog.LogLevelInfo, "", []Matcher{Named("http"), HasField("data.path")}, false},
+		testAssertExample{jsonlog.LogLevelInfo, "", []Matcher{HasField("data.user")}, true},
+		testAssertExample{jsonlog.LogLevelError, "served", nil, true},
+		testAssertExample{jsonlog.LogLevelInfo, "failed", nil, true},
+	}
+	for i, example := range examples {
+		recorder := &recordingT{TB: t}
+		sink.AssertLogged(recorder, example.level, example.substring, example.matchers...)
+		if failed := len(recorder.failures) > 0; failed != example.failed {
+			t.Errorf("Example %d failed %t but should have failed %t: %v.", i, failed, example.failed, recorder.failures)
+		}
+		recorder = &recordingT{TB: t}
+		sink.AssertNotLogged(recorder, example.level, example.substring, example.matchers...)
+		if failed := len(recorder.failures) > 0; failed == example.failed {
+			t.Errorf("Example %d of AssertNotLogged failed %t but should have failed %t.", i, failed, !example.failed)
+		}
+	}
+}
+
+// TestSinkSplitWrites tests that records split across writes are decoded
+// once complete.
+func TestSinkSplitWrites(t *testing.T) {
+	sink := NewSink()
+	sink.Write([]byte(`{"level":"info","mess`))
+	if len(sink.Entries()) != 0 {
+		t.Errorf("An incomplete record should not be recorded.")
+	}
+	sink.Write([]byte(`age":"split"}` + "\n"))
+	if last, ok := sink.LastEntry(); !ok || last.Message != "split" {
+		t.Errorf("The completed record should be recorded, got '%s'.", last.Message)
+	}
+}
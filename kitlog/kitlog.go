@@ -0,0 +1,85 @@
+// Package kitlog adapts jsonlog to the Logger interface of
+// github.com/go-kit/log, so that middlewares built on go-kit can emit through
+// jsonlog. It does not import go-kit: the interface is satisfied
+// structurally.
+package kitlog
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/trackit/jsonlog"
+)
+
+// Logger implements go-kit's log.Logger over a jsonlog.Logger. The keys
+// conventionally used with go-kit are mapped to jsonlog messages:
+//
+//   - "level" sets the level, from values such as those of go-kit's level
+//     package: "debug", "info", "warn" or "error". It defaults to info.
+//   - "msg" or "message" is the message.
+//   - "err" or "error", if its value is an error, is the error.
+//   - "ts" or "time", if its value is a time.Time, is the time.
+//
+// Other pairs are output as the data.
+type Logger struct {
+	logger jsonlog.Logger
+}
+
+// NewLogger creates a Logger writing through `logger'.
+func NewLogger(logger jsonlog.Logger) Logger {
+	return Logger{logger: logger}
+}
+
+// Log logs the key-value pairs as one message. A trailing key without a value
+// gets a nil value, and keys which are not strings are formatted with
+// fmt.Sprint.
+func (k Logger) Log(keyvals ...interface{}) error {
+	logger := k.logger
+	logLevel := jsonlog.LogLevelInfo
+	var message string
+	var data map[string]interface{}
+	for i := 0; i < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			key = fmt.Sprint(keyvals[i])
+		}
+		var value interface{}
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		switch key {
+		case "level":
+			if parsed, ok := parseLevel(value); ok {
+				logLevel = parsed
+				continue
+			}
+		case "msg", "message":
+			message = fmt.Sprint(value)
+			continue
+		case "err", "error":
+			if err, ok := value.(error); ok {
+				logger = logger.WithError(err)
+				continue
+			}
+		case "ts", "time":
+			if t, ok := value.(time.Time); ok {
+				logger = logger.WithClock(func() time.Time { return t })
+				continue
+			}
+		}
+		if data == nil {
+			data = make(map[string]interface{}, len(keyvals)/2)
+		}
+		data[key] = value
+	}
+	if data == nil {
+		return logger.Log(logLevel, message, nil)
+	}
+	return logger.Log(logLevel, message, data)
+}
+
+// parseLevel converts the value of a "level" key to a jsonlog level.
+func parseLevel(value interface{}) (jsonlog.LogLevel, bool) {
+	logLevel, err := jsonlog.ParseLogLevel(fmt.Sprint(value))
+	return logLevel, err == nil
+}
@@ -0,0 +1,49 @@
+package kitlog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trackit/jsonlog"
+	"github.com/trackit/jsonlog/jsonlogtest"
+)
+
+// levelValue mimics the values of go-kit's level package.
+type levelValue string
+
+func (l levelValue) String() string { return string(l) }
+
+// TestLogger tests the mapping of go-kit keys to jsonlog messages.
+func TestLogger(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	logger := NewLogger(sink.Logger())
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	err := logger.Log("level", levelValue("warn"), "ts", at, "msg", "slow request",
+		"err", errors.New("deadline exceeded"), "method", "GET", "status", 504)
+	if err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+	}
+	entry := sink.AssertLogged(t, jsonlog.LogLevelWarning, "slow request",
+		jsonlogtest.FieldEquals("data.method", "GET"),
+		jsonlogtest.FieldEquals("data.status", 504),
+		jsonlogtest.FieldEquals("error.message", "deadline exceeded"),
+		jsonlogtest.FieldEquals("time", "2021-03-04T05:06:07Z"))
+	if _, ok := entry.Field("data.msg"); ok {
+		t.Errorf("The message should not be output in the data.")
+	}
+	logger.Log("event", "started")
+	sink.AssertLogged(t, jsonlog.LogLevelInfo, "", jsonlogtest.FieldEquals("data.event", "started"))
+}
+
+// TestCaller tests that the caller starts at the code using the adapter.
+func TestCaller(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	NewLogger(sink.Logger().WithCaller(true)).Log("msg", "started")
+	entry := sink.AssertLogged(t, jsonlog.LogLevelInfo, "started")
+	if caller, _ := entry.Field("context.caller"); !strings.HasPrefix(fmt.Sprint(caller), "kitlog/kitlog_test.go:") {
+		t.Errorf("Caller '%v' should be in the test.", caller)
+	}
+}
@@ -0,0 +1,114 @@
+package jsonlog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownLogLevel is returned when parsing a name which is not that of a
+// log level.
+var ErrUnknownLogLevel = errors.New("jsonlog: unknown log level")
+
+// logLevelRanks maps registered log levels to their rank. The predefined
+// levels rank ten apart, from 10 for debug to 60 for fatal.
+var logLevelRanks = map[LogLevel]int{}
+
+// RegisterLogLevel defines a custom log level, such as "trace" or "audit",
+// so that it is output by name and can be parsed, and returns it. Levels are
+// ordered by rank rather than by value: the predefined levels rank ten apart,
+// from 10 for LogLevelDebug to 60 for LogLevelFatal, so that a level of rank
+// 5 is below debug and one of rank 45 between error and panic. A Logger
+// outputs the messages whose level ranks at least as high as its own. The
+// predefined levels keep their values; registered ones are given the next
+// values above LogLevelFatal.
+//
+// RegisterLogLevel must be called before logging starts, typically from an
+// init function or a package-level variable declaration. It panics if the
+// name or the rank is already defined.
+//
+//	var LogLevelTrace = jsonlog.RegisterLogLevel("trace", 5)
+func RegisterLogLevel(name string, rank int) LogLevel {
+	if name == "" {
+		panic("jsonlog: log level name is empty")
+	}
+	if _, err := ParseLogLevel(name); err == nil {
+		panic(fmt.Sprintf("jsonlog: log level name %q is already defined", name))
+	}
+	logLevel := LogLevelFatal + 1
+	for existing := range logLevelNames {
+		if existing.rank() == rank {
+			panic(fmt.Sprintf("jsonlog: log level rank %d is already defined as %q", rank, existing))
+		}
+		if existing >= logLevel {
+			logLevel = existing + 1
+		}
+	}
+	logLevelNames[logLevel] = name
+	logLevelRanks[logLevel] = rank
+	encodedLevelNames[name] = appendJSONString(nil, name)
+	return logLevel
+}
+
+// rank returns the rank of the log level, which orders levels. Levels which
+// were neither predefined nor registered rank as errors.
+func (l LogLevel) rank() int {
+	if l <= LogLevelFatal {
+		return 10 * (int(l) + 1)
+	}
+	if rank, ok := logLevelRanks[l]; ok {
+		return rank
+	}
+	return LogLevelError.rank()
+}
+
+// logLevelAliases are the alternative names ParseLogLevel accepts.
+var logLevelAliases = map[string]LogLevel{
+	"warn": LogLevelWarning,
+}
+
+// String returns the name of the log level as output in messages, such as
+// "info".
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return "LogLevel(" + strconv.FormatUint(uint64(l), 10) + ")"
+}
+
+// ParseLogLevel returns the log level with the given name, as output in
+// messages. Case and surrounding spaces are ignored, and "warn" is accepted
+// for "warning".
+func ParseLogLevel(name string) (LogLevel, error) {
+	name = strings.TrimSpace(name)
+	for logLevel, logLevelName := range logLevelNames {
+		if strings.EqualFold(logLevelName, name) {
+			return logLevel, nil
+		}
+	}
+	if logLevel, ok := logLevelAliases[strings.ToLower(name)]; ok {
+		return logLevel, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownLogLevel, name)
+}
+
+// MarshalText returns the name of the log level, so that levels are output by
+// name in JSON and other text formats.
+func (l LogLevel) MarshalText() ([]byte, error) {
+	if _, ok := logLevelNames[l]; !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownLogLevel, uint(l))
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText parses a log level name with ParseLogLevel, so that levels
+// can be read from JSON configuration files or flags.
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	logLevel, err := ParseLogLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = logLevel
+	return nil
+}
@@ -0,0 +1,121 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testParseLogLevelExample struct {
+	name     string
+	expected LogLevel
+	valid    bool
+}
+
+// TestParseLogLevel tests parsing level names.
+func TestParseLogLevel(t *testing.T) {
+	examples := []testParseLogLevelExample{
+		testParseLogLevelExample{"debug", LogLevelDebug, true},
+		testParseLogLevelExample{" Info ", LogLevelInfo, true},
+		testParseLogLevelExample{"WARN", LogLevelWarning, true},
+		testParseLogLevelExample{"warning", LogLevelWarning, true},
+		testParseLogLevelExample{"fatal", LogLevelFatal, true},
+		testParseLogLevelExample{"verbose", 0, false},
+		testParseLogLevelExample{"", 0, false},
+	}
+	for _, example := range examples {
+		logLevel, err := ParseLogLevel(example.name)
+		if !example.valid {
+			if !errors.Is(err, ErrUnknownLogLevel) {
+				t.Errorf("Parsing '%s' should fail with ErrUnknownLogLevel, got %v.", example.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("Parsing '%s' errored with '%s'.", example.name, err.Error())
+		} else if logLevel != example.expected {
+			t.Errorf("Parsing '%s' gave %s instead of %s.", example.name, logLevel, example.expected)
+		}
+	}
+}
+
+// TestLogLevelText tests that levels round-trip through JSON by name.
+func TestLogLevelText(t *testing.T) {
+	config := struct {
+		Level LogLevel `json:"level"`
+	}{LogLevelError}
+	encoded, err := json.Marshal(config)
+	if err != nil {
+		t.Errorf("Encoding errored with '%s'.", err.Error())
+	} else if string(encoded) != `{"level":"error"}` {
+		t.Errorf("Encoding gave %s.", encoded)
+	}
+	if err := json.Unmarshal([]byte(`{"level":"Debug"}`), &config); err != nil {
+		t.Errorf("Decoding errored with '%s'.", err.Error())
+	} else if config.Level != LogLevelDebug {
+		t.Errorf("Decoding gave %s instead of debug.", config.Level)
+	}
+	if err := json.Unmarshal([]byte(`{"level":"loud"}`), &config); err == nil {
+		t.Errorf("Decoding an unknown level should fail.")
+	}
+	if LogLevel(42).String() != "LogLevel(42)" {
+		t.Errorf("Unknown level is named '%s'.", LogLevel(42).String())
+	}
+}
+
+var (
+	testLogLevelTrace = RegisterLogLevel("trace", 5)
+	testLogLevelAudit = RegisterLogLevel("Audit", 45)
+)
+
+// TestRegisterLogLevel tests that custom levels are ordered by rank, output
+// by name and parsed, and that the predefined levels keep their values.
+func TestRegisterLogLevel(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithLogLevel(LogLevelDebug)
+	logger.Log(testLogLevelTrace, "below debug", nil)
+	logger.Log(testLogLevelAudit, "between error and panic", nil)
+	if strings.Contains(buffer.String(), "below debug") {
+		t.Errorf("Output '%s' should not contain the trace message.", buffer.String())
+	}
+	if expected := `{"level":"Audit",`; !strings.HasPrefix(buffer.String(), expected) {
+		t.Errorf("Output '%s' should start with '%s'.", buffer.String(), expected)
+	}
+	buffer.Reset()
+	logger.WithLogLevel(testLogLevelTrace).WithFormatter(LogfmtFormatter{}).Log(testLogLevelTrace, "traced", nil)
+	if !strings.Contains(buffer.String(), "level=trace") {
+		t.Errorf("Output '%s' should contain the trace level.", buffer.String())
+	}
+	if logLevel, err := ParseLogLevel("AUDIT"); err != nil || logLevel != testLogLevelAudit {
+		t.Errorf("Parsing the audit level gave %s, %v.", logLevel, err)
+	}
+	if LogLevelDebug != 0 || LogLevelError != 3 || LogLevelFatal != 5 || testLogLevelTrace <= LogLevelFatal {
+		t.Errorf("Predefined levels should keep their values, and custom ones come after them.")
+	}
+}
+
+type testRegisterLogLevelExample struct {
+	name string
+	rank int
+}
+
+// TestRegisterLogLevelConflicts tests that redefining a level panics.
+func TestRegisterLogLevelConflicts(t *testing.T) {
+	examples := []testRegisterLogLevelExample{
+		testRegisterLogLevelExample{"verbose", 20},
+		testRegisterLogLevelExample{"verbose", 45},
+		testRegisterLogLevelExample{"trace", 7},
+		testRegisterLogLevelExample{"warn", 8},
+		testRegisterLogLevelExample{"", 9},
+	}
+	for _, example := range examples {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Registering '%s' with rank %d should panic.", example.name, example.rank)
+				}
+			}()
+			RegisterLogLevel(example.name, example.rank)
+		}()
+	}
+}
@@ -0,0 +1,122 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// LogfmtFormatter formats messages as logfmt lines, as understood by Loki,
+// promtail and Heroku-style tooling. Context values are output as
+// "context.<key>" and the data is flattened into dot-separated keys under
+// "data", so that a data of {"user":{"id":42}} becomes "data.user.id=42".
+type LogfmtFormatter struct {
+	// TimeLayout is the layout of the time field, as for JSONFormatter.
+	TimeLayout string
+}
+
+// Format appends the logfmt representation of `m' to `buffer'.
+func (f LogfmtFormatter) Format(buffer *bytes.Buffer, m *Message) error {
+	var fields logfmtFields
+	switch t := formatTime(m.Time, f.TimeLayout).(type) {
+	case int64:
+		fields.add("time", strconv.FormatInt(t, 10))
+	case float64:
+		fields.add("time", strconv.FormatFloat(t, 'f', -1, 64))
+	case string:
+		fields.add("time", t)
+	}
+	fields.add("level", m.Level)
+	fields.add("message", m.Message)
+	if m.Logger != "" {
+		fields.add("logger", m.Logger)
+	}
+	if len(m.Context) > 0 {
+		if err := fields.flatten("context", m.Context); err != nil {
+			return err
+		}
+	}
+	if m.Data != nil {
+		if err := fields.flatten("data", m.Data); err != nil {
+			return err
+		}
+	}
+	if m.Error != nil {
+		if err := fields.flatten("error", m.Error); err != nil {
+			return err
+		}
+	}
+	if m.Stack != "" {
+		fields.add("stack", m.Stack)
+	}
+	if m.Repeated > 0 {
+		fields.add("repeated", strconv.Itoa(m.Repeated))
+	}
+	fields.writeTo(buffer)
+	return nil
+}
+
+// logfmtFields accumulates the key/value pairs of a logfmt line in order.
+type logfmtFields struct {
+	keys   []string
+	values []string
+}
+
+// add appends a pair whose value is already a plain string.
+func (f *logfmtFields) add(key, value string) {
+	f.keys = append(f.keys, key)
+	f.values = append(f.values, value)
+}
+
+// flatten appends the pairs for an arbitrary value.
+func (f *logfmtFields) flatten(prefix string, value interface{}) error {
+	return flatten(prefix, value, func(key string, scalar interface{}) {
+		switch v := scalar.(type) {
+		case nil:
+			f.add(key, "null")
+		case bool:
+			f.add(key, strconv.FormatBool(v))
+		case json.Number:
+			f.add(key, v.String())
+		case string:
+			f.add(key, v)
+		}
+	})
+}
+
+// writeTo writes the accumulated pairs as a single logfmt line.
+func (f *logfmtFields) writeTo(buffer *bytes.Buffer) {
+	for i := range f.keys {
+		if i > 0 {
+			buffer.WriteByte(' ')
+		}
+		buffer.WriteString(logfmtKey(f.keys[i]))
+		buffer.WriteByte('=')
+		buffer.WriteString(logfmtValue(f.values[i]))
+	}
+	buffer.WriteByte('\n')
+}
+
+// logfmtKey replaces the characters which are not allowed in a logfmt key.
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue quotes a value if it cannot be written bare.
+func logfmtValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f || r > 0x7e {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
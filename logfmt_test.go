@@ -0,0 +1,88 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type testLogfmtExample struct {
+	logger   string
+	message  string
+	data     interface{}
+	expected []string
+}
+
+// TestLogfmtFormatter tests the logfmt output for messages with and without
+// data, including nested data and values needing quoting.
+func TestLogfmtFormatter(t *testing.T) {
+	examples := []testLogfmtExample{
+		testLogfmtExample{
+			"",
+			"simple",
+			nil,
+			[]string{"level=info", "message=simple"},
+		},
+		testLogfmtExample{
+			"",
+			"with spaces",
+			map[string]interface{}{"foo": "bar baz", "n": 42},
+			[]string{`message="with spaces"`, `data.foo="bar baz"`, "data.n=42"},
+		},
+		testLogfmtExample{
+			"",
+			"nested",
+			map[string]interface{}{"user": map[string]interface{}{"id": 7, "admin": true}},
+			[]string{"data.user.admin=true", "data.user.id=7"},
+		},
+		testLogfmtExample{
+			"",
+			"array",
+			[]string{"a", ""},
+			[]string{"data.0=a", `data.1=""`},
+		},
+		testLogfmtExample{
+			"db",
+			"named",
+			nil,
+			[]string{"message=named logger=db"},
+		},
+	}
+	for _, example := range examples {
+		buffer := new(bytes.Buffer)
+		logger := DefaultLogger.WithWriter(buffer).WithFormatter(LogfmtFormatter{})
+		if example.logger != "" {
+			logger = logger.Named(example.logger)
+		}
+		err := logger.Info(example.message, example.data)
+		if err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+			continue
+		}
+		output := buffer.String()
+		if !strings.HasSuffix(output, "\n") || strings.Count(output, "\n") != 1 {
+			t.Errorf("Output '%s' should be a single line.", output)
+		}
+		for _, e := range example.expected {
+			if !strings.Contains(output, " "+e) && !strings.HasPrefix(output, e) {
+				t.Errorf("Output '%s' should contain '%s'.", output, e)
+			}
+		}
+	}
+}
+
+// TestLogfmtFormatterContext tests that context values are output by the
+// logfmt formatter.
+func TestLogfmtFormatterContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "runKey", "abc=def")
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithFormatter(LogfmtFormatter{})
+	logger = logger.WithContext(ctx).WithContextKey("runKey", "run id")
+	err := logger.Info("log", nil)
+	if err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+	} else if !strings.Contains(buffer.String(), ` context.run_id="abc=def"`) {
+		t.Errorf("Output '%s' should contain the context value.", buffer.String())
+	}
+}
@@ -0,0 +1,189 @@
+package logrus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Entry is a set of fields to log messages with. Entries are immutable: the
+// With methods return new ones.
+type Entry struct {
+	Logger *Logger
+	Data   Fields
+	// Time is the time of the messages. The current time is used if it is
+	// zero.
+	Time time.Time
+	// Context is passed to the underlying jsonlog.Logger, which logs the
+	// values of its context keys.
+	Context context.Context
+}
+
+// NewEntry creates an Entry without fields.
+func NewEntry(logger *Logger) *Entry {
+	return logger.newEntry()
+}
+
+// WithField returns a new Entry with one more field.
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+// WithFields returns a new Entry with more fields.
+func (e *Entry) WithFields(fields Fields) *Entry {
+	data := make(Fields, len(e.Data)+len(fields))
+	for key, value := range e.Data {
+		data[key] = value
+	}
+	for key, value := range fields {
+		data[key] = value
+	}
+	entry := *e
+	entry.Data = data
+	return &entry
+}
+
+// WithError returns a new Entry with an error, under ErrorKey.
+func (e *Entry) WithError(err error) *Entry {
+	return e.WithField(ErrorKey, err)
+}
+
+// WithContext returns a new Entry with a context.
+func (e *Entry) WithContext(ctx context.Context) *Entry {
+	entry := *e
+	entry.Context = ctx
+	return &entry
+}
+
+// WithTime returns a new Entry logging its messages at time `t'.
+func (e *Entry) WithTime(t time.Time) *Entry {
+	entry := *e
+	entry.Time = t
+	return &entry
+}
+
+// log outputs a message through the underlying jsonlog.Logger. The field
+// under ErrorKey, if it is an error, becomes the error of the message.
+func (e *Entry) log(level Level, message string) {
+	if !e.Logger.IsLevelEnabled(level) {
+		return
+	}
+	logger := e.Logger.JSONLogger()
+	if e.Context != nil {
+		logger = logger.WithContext(e.Context)
+	}
+	if !e.Time.IsZero() {
+		logger = logger.WithClock(func() time.Time { return e.Time })
+	}
+	var data interface{}
+	if len(e.Data) > 0 {
+		fields := make(map[string]interface{}, len(e.Data))
+		for key, value := range e.Data {
+			if err, ok := value.(error); ok && key == ErrorKey {
+				logger = logger.WithError(err)
+			} else {
+				fields[key] = value
+			}
+		}
+		if len(fields) > 0 {
+			data = fields
+		}
+	}
+	switch level {
+	case PanicLevel:
+		logger.Panic(message, data)
+	case FatalLevel:
+		logger.Fatal(message, data)
+	default:
+		logger.Log(level.jsonlogLevel(), message, data)
+	}
+}
+
+// Log logs a message at `level'.
+func (e *Entry) Log(level Level, args ...interface{}) {
+	if e.Logger.IsLevelEnabled(level) {
+		e.log(level, fmt.Sprint(args...))
+	}
+}
+
+// Logf logs a formatted message at `level'.
+func (e *Entry) Logf(level Level, format string, args ...interface{}) {
+	if e.Logger.IsLevelEnabled(level) {
+		e.log(level, fmt.Sprintf(format, args...))
+	}
+}
+
+// Logln logs a message at `level', with spaces between all the arguments.
+func (e *Entry) Logln(level Level, args ...interface{}) {
+	if e.Logger.IsLevelEnabled(level) {
+		e.log(level, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+	}
+}
+
+// Trace logs a message at the trace level.
+func (e *Entry) Trace(args ...interface{}) { e.Log(TraceLevel, args...) }
+
+// Debug logs a message at the debug level.
+func (e *Entry) Debug(args ...interface{}) { e.Log(DebugLevel, args...) }
+
+// Info logs a message at the info level.
+func (e *Entry) Info(args ...interface{}) { e.Log(InfoLevel, args...) }
+
+// Print logs a message at the info level.
+func (e *Entry) Print(args ...interface{}) { e.Log(InfoLevel, args...) }
+
+// Warn logs a message at the warning level.
+func (e *Entry) Warn(args ...interface{}) { e.Log(WarnLevel, args...) }
+
+// Warning logs a message at the warning level.
+func (e *Entry) Warning(args ...interface{}) { e.Log(WarnLevel, args...) }
+
+// Error logs a message at the error level.
+func (e *Entry) Error(args ...interface{}) { e.Log(ErrorLevel, args...) }
+
+// Fatal logs a message at the fatal level, then exits the process as
+// configured by the underlying jsonlog.Logger.
+func (e *Entry) Fatal(args ...interface{}) { e.Log(FatalLevel, args...) }
+
+// Panic logs a message at the panic level, then panics.
+func (e *Entry) Panic(args ...interface{}) { e.Log(PanicLevel, args...) }
+
+// Tracef logs a formatted message at the trace level.
+func (e *Entry) Tracef(format string, args ...interface{}) { e.Logf(TraceLevel, format, args...) }
+
+// Debugf logs a formatted message at the debug level.
+func (e *Entry) Debugf(format string, args ...interface{}) { e.Logf(DebugLevel, format, args...) }
+
+// Infof logs a formatted message at the info level.
+func (e *Entry) Infof(format string, args ...interface{}) { e.Logf(InfoLevel, format, args...) }
+
+// Printf logs a formatted message at the info level.
+func (e *Entry) Printf(format string, args ...interface{}) { e.Logf(InfoLevel, format, args...) }
+
+// Warnf logs a formatted message at the warning level.
+func (e *Entry) Warnf(format string, args ...interface{}) { e.Logf(WarnLevel, format, args...) }
+
+// Warningf logs a formatted message at the warning level.
+func (e *Entry) Warningf(format string, args ...interface{}) { e.Logf(WarnLevel, format, args...) }
+
+// Errorf logs a formatted message at the error level.
+func (e *Entry) Errorf(format string, args ...interface{}) { e.Logf(ErrorLevel, format, args...) }
+
+// Fatalf logs a formatted message at the fatal level, then exits.
+func (e *Entry) Fatalf(format string, args ...interface{}) { e.Logf(FatalLevel, format, args...) }
+
+// Panicf logs a formatted message at the panic level, then panics.
+func (e *Entry) Panicf(format string, args ...interface{}) { e.Logf(PanicLevel, format, args...) }
+
+// Infoln logs a message at the info level.
+func (e *Entry) Infoln(args ...interface{}) { e.Logln(InfoLevel, args...) }
+
+// Println logs a message at the info level.
+func (e *Entry) Println(args ...interface{}) { e.Logln(InfoLevel, args...) }
+
+// Warnln logs a message at the warning level.
+func (e *Entry) Warnln(args ...interface{}) { e.Logln(WarnLevel, args...) }
+
+// Errorln logs a message at the error level.
+func (e *Entry) Errorln(args ...interface{}) { e.Logln(ErrorLevel, args...) }
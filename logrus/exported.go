@@ -0,0 +1,113 @@
+package logrus
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// std is the Logger of the package-level functions.
+var std = New()
+
+// StandardLogger returns the Logger of the package-level functions.
+func StandardLogger() *Logger {
+	return std
+}
+
+// SetOutput sets the writer of the standard Logger.
+func SetOutput(w io.Writer) { std.SetOutput(w) }
+
+// SetLevel sets the level of the standard Logger.
+func SetLevel(level Level) { std.SetLevel(level) }
+
+// GetLevel returns the level of the standard Logger.
+func GetLevel() Level { return std.GetLevel() }
+
+// IsLevelEnabled tells whether the standard Logger logs messages of `level'.
+func IsLevelEnabled(level Level) bool { return std.IsLevelEnabled(level) }
+
+// WithField creates an Entry of the standard Logger with one field.
+func WithField(key string, value interface{}) *Entry { return std.WithField(key, value) }
+
+// WithFields creates an Entry of the standard Logger with some fields.
+func WithFields(fields Fields) *Entry { return std.WithFields(fields) }
+
+// WithError creates an Entry of the standard Logger with an error.
+func WithError(err error) *Entry { return std.WithError(err) }
+
+// WithContext creates an Entry of the standard Logger with a context.
+func WithContext(ctx context.Context) *Entry { return std.WithContext(ctx) }
+
+// WithTime creates an Entry of the standard Logger logging at time `t'.
+func WithTime(t time.Time) *Entry { return std.WithTime(t) }
+
+// Trace logs a message at the trace level on the standard Logger.
+func Trace(args ...interface{}) { std.Trace(args...) }
+
+// Debug logs a message at the debug level on the standard Logger.
+func Debug(args ...interface{}) { std.Debug(args...) }
+
+// Info logs a message at the info level on the standard Logger.
+func Info(args ...interface{}) { std.Info(args...) }
+
+// Print logs a message at the info level on the standard Logger.
+func Print(args ...interface{}) { std.Print(args...) }
+
+// Warn logs a message at the warning level on the standard Logger.
+func Warn(args ...interface{}) { std.Warn(args...) }
+
+// Warning logs a message at the warning level on the standard Logger.
+func Warning(args ...interface{}) { std.Warning(args...) }
+
+// Error logs a message at the error level on the standard Logger.
+func Error(args ...interface{}) { std.Error(args...) }
+
+// Fatal logs a message at the fatal level on the standard Logger, then exits.
+func Fatal(args ...interface{}) { std.Fatal(args...) }
+
+// Panic logs a message at the panic level on the standard Logger, then
+// panics.
+func Panic(args ...interface{}) { std.Panic(args...) }
+
+// Tracef logs a formatted message at the trace level on the standard Logger.
+func Tracef(format string, args ...interface{}) { std.Tracef(format, args...) }
+
+// Debugf logs a formatted message at the debug level on the standard Logger.
+func Debugf(format string, args ...interface{}) { std.Debugf(format, args...) }
+
+// Infof logs a formatted message at the info level on the standard Logger.
+func Infof(format string, args ...interface{}) { std.Infof(format, args...) }
+
+// Printf logs a formatted message at the info level on the standard Logger.
+func Printf(format string, args ...interface{}) { std.Printf(format, args...) }
+
+// Warnf logs a formatted message at the warning level on the standard
+// Logger.
+func Warnf(format string, args ...interface{}) { std.Warnf(format, args...) }
+
+// Warningf logs a formatted message at the warning level on the standard
+// Logger.
+func Warningf(format string, args ...interface{}) { std.Warningf(format, args...) }
+
+// Errorf logs a formatted message at the error level on the standard Logger.
+func Errorf(format string, args ...interface{}) { std.Errorf(format, args...) }
+
+// Fatalf logs a formatted message at the fatal level on the standard Logger,
+// then exits.
+func Fatalf(format string, args ...interface{}) { std.Fatalf(format, args...) }
+
+// Panicf logs a formatted message at the panic level on the standard Logger,
+// then panics.
+func Panicf(format string, args ...interface{}) { std.Panicf(format, args...) }
+
+// Infoln logs a message at the info level on the standard Logger.
+func Infoln(args ...interface{}) { std.Infoln(args...) }
+
+// Println logs a message at the info level on the standard Logger.
+func Println(args ...interface{}) { std.Println(args...) }
+
+// Warnln logs a message at the warning level on the standard Logger.
+func Warnln(args ...interface{}) { std.Warnln(args...) }
+
+// Errorln logs a message at the error level on the standard Logger.
+func Errorln(args ...interface{}) { std.Errorln(args...) }
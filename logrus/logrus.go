@@ -0,0 +1,253 @@
+// Package logrus is a subset of the API of github.com/sirupsen/logrus backed
+// by jsonlog, so that code using logrus can move to jsonlog by changing its
+// imports, and be rewritten to the jsonlog API at its own pace. Fields are
+// output as the data of the jsonlog messages and an error set with WithError
+// as their error.
+//
+// Formatters and hooks of logrus are not supported: the output is configured
+// through the underlying jsonlog.Logger.
+package logrus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/trackit/jsonlog"
+)
+
+// ErrorKey is the field of the error set with WithError.
+var ErrorKey = "error"
+
+// Fields are the fields of an Entry.
+type Fields map[string]interface{}
+
+// Level is a logrus log level.
+type Level uint32
+
+// The logrus log levels, from the most to the least severe. TraceLevel
+// messages are output with the jsonlog debug level.
+const (
+	PanicLevel Level = iota
+	FatalLevel
+	ErrorLevel
+	WarnLevel
+	InfoLevel
+	DebugLevel
+	TraceLevel
+)
+
+// AllLevels lists the levels, from the most to the least severe.
+var AllLevels = []Level{PanicLevel, FatalLevel, ErrorLevel, WarnLevel, InfoLevel, DebugLevel, TraceLevel}
+
+// levelNames are the names of the levels, as parsed by ParseLevel.
+var levelNames = []string{"panic", "fatal", "error", "warning", "info", "debug", "trace"}
+
+// String returns the name of the level.
+func (level Level) String() string {
+	if int(level) < len(levelNames) {
+		return levelNames[level]
+	}
+	return "unknown"
+}
+
+// ParseLevel parses the name of a level, such as "info" or "warn".
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(name)
+	if name == "warn" {
+		return WarnLevel, nil
+	}
+	for level, levelName := range levelNames {
+		if name == levelName {
+			return Level(level), nil
+		}
+	}
+	return 0, fmt.Errorf("not a valid logrus Level: %q", name)
+}
+
+// jsonlogLevel is the jsonlog level messages of `level' are logged with.
+func (level Level) jsonlogLevel() jsonlog.LogLevel {
+	switch level {
+	case PanicLevel:
+		return jsonlog.LogLevelPanic
+	case FatalLevel:
+		return jsonlog.LogLevelFatal
+	case ErrorLevel:
+		return jsonlog.LogLevelError
+	case WarnLevel:
+		return jsonlog.LogLevelWarning
+	case InfoLevel:
+		return jsonlog.LogLevelInfo
+	default:
+		return jsonlog.LogLevelDebug
+	}
+}
+
+// Logger logs through a jsonlog.Logger. Its level is that of logrus: the
+// jsonlog.Logger it wraps should accept debug messages.
+//
+// A Logger is safe for concurrent use.
+type Logger struct {
+	mutex  sync.RWMutex
+	logger jsonlog.Logger
+	level  uint32
+}
+
+// New creates a Logger at the info level, writing through
+// jsonlog.DefaultLogger.
+func New() *Logger {
+	return NewWithLogger(jsonlog.DefaultLogger.WithLogLevel(jsonlog.LogLevelDebug))
+}
+
+// NewWithLogger creates a Logger at the info level, writing through `logger'.
+func NewWithLogger(logger jsonlog.Logger) *Logger {
+	return &Logger{
+		logger: logger,
+		level:  uint32(InfoLevel),
+	}
+}
+
+// SetLevel sets the level of the Logger.
+func (l *Logger) SetLevel(level Level) {
+	atomic.StoreUint32(&l.level, uint32(level))
+}
+
+// GetLevel returns the level of the Logger.
+func (l *Logger) GetLevel() Level {
+	return Level(atomic.LoadUint32(&l.level))
+}
+
+// IsLevelEnabled tells whether messages of `level' are logged.
+func (l *Logger) IsLevelEnabled(level Level) bool {
+	return l.GetLevel() >= level
+}
+
+// SetOutput sets the writer of the underlying jsonlog.Logger.
+func (l *Logger) SetOutput(w io.Writer) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.logger = l.logger.WithWriter(w)
+}
+
+// SetJSONLogger replaces the underlying jsonlog.Logger.
+func (l *Logger) SetJSONLogger(logger jsonlog.Logger) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.logger = logger
+}
+
+// JSONLogger returns the underlying jsonlog.Logger.
+func (l *Logger) JSONLogger() jsonlog.Logger {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.logger
+}
+
+// newEntry creates an Entry without fields.
+func (l *Logger) newEntry() *Entry {
+	return &Entry{Logger: l, Data: Fields{}}
+}
+
+// WithField creates an Entry with one field.
+func (l *Logger) WithField(key string, value interface{}) *Entry {
+	return l.newEntry().WithField(key, value)
+}
+
+// WithFields creates an Entry with some fields.
+func (l *Logger) WithFields(fields Fields) *Entry {
+	return l.newEntry().WithFields(fields)
+}
+
+// WithError creates an Entry with an error.
+func (l *Logger) WithError(err error) *Entry {
+	return l.newEntry().WithError(err)
+}
+
+// WithContext creates an Entry with a context.
+func (l *Logger) WithContext(ctx context.Context) *Entry {
+	return l.newEntry().WithContext(ctx)
+}
+
+// WithTime creates an Entry logging its messages at time `t'.
+func (l *Logger) WithTime(t time.Time) *Entry {
+	return l.newEntry().WithTime(t)
+}
+
+// Log logs a message at `level'.
+func (l *Logger) Log(level Level, args ...interface{}) { l.newEntry().Log(level, args...) }
+
+// Trace logs a message at the trace level.
+func (l *Logger) Trace(args ...interface{}) { l.newEntry().Trace(args...) }
+
+// Debug logs a message at the debug level.
+func (l *Logger) Debug(args ...interface{}) { l.newEntry().Debug(args...) }
+
+// Info logs a message at the info level.
+func (l *Logger) Info(args ...interface{}) { l.newEntry().Info(args...) }
+
+// Print logs a message at the info level.
+func (l *Logger) Print(args ...interface{}) { l.newEntry().Print(args...) }
+
+// Warn logs a message at the warning level.
+func (l *Logger) Warn(args ...interface{}) { l.newEntry().Warn(args...) }
+
+// Warning logs a message at the warning level.
+func (l *Logger) Warning(args ...interface{}) { l.newEntry().Warning(args...) }
+
+// Error logs a message at the error level.
+func (l *Logger) Error(args ...interface{}) { l.newEntry().Error(args...) }
+
+// Fatal logs a message at the fatal level, then exits the process as
+// configured by the underlying jsonlog.Logger.
+func (l *Logger) Fatal(args ...interface{}) { l.newEntry().Fatal(args...) }
+
+// Panic logs a message at the panic level, then panics.
+func (l *Logger) Panic(args ...interface{}) { l.newEntry().Panic(args...) }
+
+// Logf logs a formatted message at `level'.
+func (l *Logger) Logf(level Level, format string, args ...interface{}) {
+	l.newEntry().Logf(level, format, args...)
+}
+
+// Tracef logs a formatted message at the trace level.
+func (l *Logger) Tracef(format string, args ...interface{}) { l.newEntry().Tracef(format, args...) }
+
+// Debugf logs a formatted message at the debug level.
+func (l *Logger) Debugf(format string, args ...interface{}) { l.newEntry().Debugf(format, args...) }
+
+// Infof logs a formatted message at the info level.
+func (l *Logger) Infof(format string, args ...interface{}) { l.newEntry().Infof(format, args...) }
+
+// Printf logs a formatted message at the info level.
+func (l *Logger) Printf(format string, args ...interface{}) { l.newEntry().Printf(format, args...) }
+
+// Warnf logs a formatted message at the warning level.
+func (l *Logger) Warnf(format string, args ...interface{}) { l.newEntry().Warnf(format, args...) }
+
+// Warningf logs a formatted message at the warning level.
+func (l *Logger) Warningf(format string, args ...interface{}) { l.newEntry().Warningf(format, args...) }
+
+// Errorf logs a formatted message at the error level.
+func (l *Logger) Errorf(format string, args ...interface{}) { l.newEntry().Errorf(format, args...) }
+
+// Fatalf logs a formatted message at the fatal level, then exits.
+func (l *Logger) Fatalf(format string, args ...interface{}) { l.newEntry().Fatalf(format, args...) }
+
+// Panicf logs a formatted message at the panic level, then panics.
+func (l *Logger) Panicf(format string, args ...interface{}) { l.newEntry().Panicf(format, args...) }
+
+// Infoln logs a message at the info level.
+func (l *Logger) Infoln(args ...interface{}) { l.newEntry().Infoln(args...) }
+
+// Println logs a message at the info level.
+func (l *Logger) Println(args ...interface{}) { l.newEntry().Println(args...) }
+
+// Warnln logs a message at the warning level.
+func (l *Logger) Warnln(args ...interface{}) { l.newEntry().Warnln(args...) }
+
+// Errorln logs a message at the error level.
+func (l *Logger) Errorln(args ...interface{}) { l.newEntry().Errorln(args...) }
@@ -0,0 +1,79 @@
+package logrus
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trackit/jsonlog"
+	"github.com/trackit/jsonlog/jsonlogtest"
+)
+
+// TestEntry tests that fields and errors are output as jsonlog data and
+// errors.
+func TestEntry(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	logger := NewWithLogger(sink.Logger())
+	base := logger.WithField("user", 42)
+	base.WithFields(Fields{"attempt": 2}).WithError(errors.New("timeout")).Errorf("charge %s failed", "ch_1")
+	base.Info("done")
+	entry := sink.AssertLogged(t, jsonlog.LogLevelError, "charge ch_1 failed",
+		jsonlogtest.FieldEquals("data.user", 42),
+		jsonlogtest.FieldEquals("data.attempt", 2),
+		jsonlogtest.FieldEquals("error.message", "timeout"))
+	if _, ok := entry.Field("data.error"); ok {
+		t.Errorf("The error should not be output in the data.")
+	}
+	if _, ok := sink.AssertLogged(t, jsonlog.LogLevelInfo, "done").Field("data.attempt"); ok {
+		t.Errorf("Fields of derived entries should not leak into their parent.")
+	}
+}
+
+// TestLevels tests that the logrus level filters messages and that levels
+// map to jsonlog levels.
+func TestLevels(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	logger := NewWithLogger(sink.Logger())
+	logger.Debug("hidden")
+	logger.SetLevel(TraceLevel)
+	logger.Trace("traced")
+	logger.Warnln("careful", 3)
+	sink.AssertNotLogged(t, jsonlog.LogLevelDebug, "hidden")
+	sink.AssertLogged(t, jsonlog.LogLevelDebug, "traced")
+	sink.AssertLogged(t, jsonlog.LogLevelWarning, "careful 3")
+	if level, err := ParseLevel("WARN"); err != nil || level != WarnLevel {
+		t.Errorf("Parsing WARN returned %v, %v.", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Errorf("Parsing an unknown level should fail.")
+	}
+}
+
+// TestWithTime tests that an entry can set the time of its messages.
+func TestWithTime(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	logger := NewWithLogger(sink.Logger())
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	logger.WithTime(at).Info("past")
+	entry := sink.AssertLogged(t, jsonlog.LogLevelInfo, "past")
+	if value, _ := entry.Field("time"); value != "2020-01-02T03:04:05Z" {
+		t.Errorf("The time should be %s, got %v.", at, value)
+	}
+}
+
+// TestCaller tests that the caller and stack traces start at the code using
+// the adapter.
+func TestCaller(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	logger := NewWithLogger(sink.Logger().WithCaller(true).WithStackTrace(true))
+	logger.WithError(errors.New("timeout")).Error("failed")
+	entry := sink.AssertLogged(t, jsonlog.LogLevelError, "failed")
+	if caller, _ := entry.Field("context.caller"); !strings.HasPrefix(fmt.Sprint(caller), "logrus/logrus_test.go:") {
+		t.Errorf("Caller '%v' should be in the test.", caller)
+	}
+	if stack, _ := entry.Field("stack"); !strings.HasPrefix(fmt.Sprint(stack), "github.com/trackit/jsonlog/logrus.TestCaller\n") {
+		t.Errorf("Stack '%v' should start in the test.", stack)
+	}
+}
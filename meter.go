@@ -0,0 +1,229 @@
+package jsonlog
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// QuotaPolicy decides what a Meter does with writes exceeding its quota.
+type QuotaPolicy uint
+
+const (
+	// QuotaDrop discards the records exceeding the quota.
+	QuotaDrop = QuotaPolicy(iota)
+	// QuotaDelay blocks the writes exceeding the quota until the next
+	// second.
+	QuotaDelay
+)
+
+// meterWindow is the duration over which a Meter enforces its quota.
+const meterWindow = time.Second
+
+// Meter measures the bytes written through the writers it wraps and can
+// enforce a quota in bytes per second on them, to keep a chatty service from
+// blowing an egress budget. The quota is shared by all the writers wrapped by
+// the same Meter.
+//
+// A Meter is safe for concurrent use.
+type Meter struct {
+	quota       int64
+	policy      QuotaPolicy
+	mutex       sync.Mutex
+	windowStart time.Time
+	windowBytes int64
+	stats       MeterStats
+	pricing     PricingModel
+	tagBytes    map[string]int64
+	now         func() time.Time
+	sleep       func(time.Duration)
+}
+
+// MeterStats are the figures collected by a Meter.
+type MeterStats struct {
+	// BytesWritten is the number of bytes passed on to the wrapped writers.
+	BytesWritten int64
+	// BytesDropped is the number of bytes discarded because of the quota.
+	BytesDropped int64
+	// RecordsDropped is the number of writes discarded because of the
+	// quota.
+	RecordsDropped int64
+	// BytesPerSecond is the number of bytes written during the last
+	// complete second.
+	BytesPerSecond int64
+}
+
+// meteredWriter is an io.Writer wrapped by a Meter.
+type meteredWriter struct {
+	meter *Meter
+	tag   string
+	w     io.Writer
+}
+
+// meteredLevelWriter is a LevelWriter wrapped by a Meter.
+type meteredLevelWriter struct {
+	*meteredWriter
+}
+
+// meteredRecordWriter is a RecordWriter wrapped by a Meter.
+type meteredRecordWriter struct {
+	*meteredWriter
+}
+
+// meteredLevelRecordWriter is a writer implementing both LevelWriter and
+// RecordWriter wrapped by a Meter.
+type meteredLevelRecordWriter struct {
+	*meteredWriter
+}
+
+// NewMeter creates a Meter. A zero quota only measures, without limiting.
+func NewMeter(quota int64, policy QuotaPolicy) *Meter {
+	return &Meter{
+		quota:    quota,
+		policy:   policy,
+		tagBytes: map[string]int64{},
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+// Wrap returns an io.Writer metered by `m' which writes to `w'. It can be
+// used as a WriterMiddleware.
+func (m *Meter) Wrap(w io.Writer) io.Writer {
+	return m.WrapTagged("", w)
+}
+
+// WrapTagged is like Wrap, but attributes the bytes written through the
+// returned writer to `tag', such as a logger name or a team, in the cost
+// estimates. The returned writer implements LevelWriter and RecordWriter if
+// `w' does, so that wrapping keeps per-level routing and record framing.
+func (m *Meter) WrapTagged(tag string, w io.Writer) io.Writer {
+	metered := &meteredWriter{m, tag, w}
+	_, isLevelWriter := w.(LevelWriter)
+	_, isRecordWriter := w.(RecordWriter)
+	switch {
+	case isLevelWriter && isRecordWriter:
+		return meteredLevelRecordWriter{metered}
+	case isLevelWriter:
+		return meteredLevelWriter{metered}
+	case isRecordWriter:
+		return meteredRecordWriter{metered}
+	}
+	return metered
+}
+
+// Stats returns the figures collected so far.
+func (m *Meter) Stats() MeterStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.advanceWindow(m.now())
+	return m.stats
+}
+
+// admit tells whether a write of `n' bytes may proceed, accounting for it as
+// dropped otherwise. With QuotaDelay it blocks until the write fits in the
+// quota, sleeping without holding the mutex so that other writers and readers
+// of the figures are not blocked, then checks the quota again.
+func (m *Meter) admit(tag string, n int) bool {
+	m.mutex.Lock()
+	for {
+		now := m.now()
+		m.advanceWindow(now)
+		if m.quota <= 0 || m.windowBytes == 0 || m.windowBytes+int64(n) <= m.quota {
+			break
+		}
+		if m.policy == QuotaDrop {
+			m.stats.BytesDropped += int64(n)
+			m.stats.RecordsDropped++
+			m.mutex.Unlock()
+			return false
+		}
+		delay := m.windowStart.Add(meterWindow).Sub(now)
+		m.mutex.Unlock()
+		m.sleep(delay)
+		m.mutex.Lock()
+	}
+	m.mutex.Unlock()
+	return true
+}
+
+// account records that `n' bytes were written through a writer tagged with
+// `tag'.
+func (m *Meter) account(tag string, n int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.advanceWindow(m.now())
+	m.windowBytes += int64(n)
+	m.stats.BytesWritten += int64(n)
+	m.tagBytes[tag] += int64(n)
+}
+
+// advanceWindow starts a new window if the current one is over.
+func (m *Meter) advanceWindow(now time.Time) {
+	elapsed := now.Sub(m.windowStart)
+	if elapsed < meterWindow {
+		return
+	}
+	if elapsed < 2*meterWindow {
+		m.stats.BytesPerSecond = m.windowBytes
+		m.windowStart = m.windowStart.Add(meterWindow)
+	} else {
+		m.stats.BytesPerSecond = 0
+		m.windowStart = now
+	}
+	m.windowBytes = 0
+}
+
+// Write passes `p' on to the wrapped writer if the quota allows it. Dropped
+// writes are reported as successful so that they are not retried.
+func (w *meteredWriter) Write(p []byte) (int, error) {
+	if !w.meter.admit(w.tag, len(p)) {
+		return len(p), nil
+	}
+	n, err := w.w.Write(p)
+	w.meter.account(w.tag, n)
+	return n, err
+}
+
+// writeLevel passes `p' on to the wrapped LevelWriter if the quota allows it.
+func (w *meteredWriter) writeLevel(logLevel LogLevel, p []byte) (int, error) {
+	if !w.meter.admit(w.tag, len(p)) {
+		return len(p), nil
+	}
+	n, err := w.w.(LevelWriter).WriteLevel(logLevel, p)
+	w.meter.account(w.tag, n)
+	return n, err
+}
+
+// writeRecord passes `record' on to the wrapped RecordWriter if the quota
+// allows it. The record is counted as written only if WriteRecord succeeds.
+func (w *meteredWriter) writeRecord(record []byte) error {
+	if !w.meter.admit(w.tag, len(record)) {
+		return nil
+	}
+	err := w.w.(RecordWriter).WriteRecord(record)
+	if err == nil {
+		w.meter.account(w.tag, len(record))
+	}
+	return err
+}
+
+// WriteLevel implements LevelWriter.
+func (w meteredLevelWriter) WriteLevel(logLevel LogLevel, p []byte) (int, error) {
+	return w.writeLevel(logLevel, p)
+}
+
+// WriteRecord implements RecordWriter.
+func (w meteredRecordWriter) WriteRecord(record []byte) error {
+	return w.writeRecord(record)
+}
+
+// WriteLevel implements LevelWriter.
+func (w meteredLevelRecordWriter) WriteLevel(logLevel LogLevel, p []byte) (int, error) {
+	return w.writeLevel(logLevel, p)
+}
+
+// WriteRecord implements RecordWriter.
+func (w meteredLevelRecordWriter) WriteRecord(record []byte) error {
+	return w.writeRecord(record)
+}
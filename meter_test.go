@@ -0,0 +1,144 @@
+package jsonlog
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestMeter creates a Meter driven by a fake clock.
+func newTestMeter(quota int64, policy QuotaPolicy, now *time.Time) *Meter {
+	meter := NewMeter(quota, policy)
+	meter.now = func() time.Time { return *now }
+	meter.sleep = func(d time.Duration) { *now = now.Add(d) }
+	return meter
+}
+
+// TestMeterDrop tests that writes over the quota are dropped and counted.
+func TestMeterDrop(t *testing.T) {
+	now := time.Now()
+	buffer := new(bytes.Buffer)
+	meter := newTestMeter(10, QuotaDrop, &now)
+	w := meter.Wrap(buffer)
+	for _, s := range []string{"12345", "12345", "1"} {
+		if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+			t.Errorf("Write returned %d, %v but should return %d, nil.", n, err, len(s))
+		}
+	}
+	if buffer.String() != "1234512345" {
+		t.Errorf("Output '%s' should stop at the quota.", buffer.String())
+	}
+	now = now.Add(time.Second)
+	w.Write([]byte("12"))
+	stats := meter.Stats()
+	expected := MeterStats{BytesWritten: 12, BytesDropped: 1, RecordsDropped: 1, BytesPerSecond: 10}
+	if stats != expected {
+		t.Errorf("Stats %+v should be %+v.", stats, expected)
+	}
+}
+
+// TestMeterDelay tests that writes over the quota wait for the next second.
+func TestMeterDelay(t *testing.T) {
+	start := time.Now()
+	now := start
+	buffer := new(bytes.Buffer)
+	meter := newTestMeter(10, QuotaDelay, &now)
+	w := meter.Wrap(buffer)
+	w.Write([]byte("12345678"))
+	w.Write([]byte("12345678"))
+	if buffer.Len() != 16 {
+		t.Errorf("Output '%s' should hold both writes.", buffer.String())
+	}
+	if now.Sub(start) != time.Second {
+		t.Errorf("The second write waited %v but should wait 1s.", now.Sub(start))
+	}
+}
+
+// TestMeterDelayUnlocked tests that a write waiting for the quota does not
+// keep the figures of the Meter from being read.
+func TestMeterDelayUnlocked(t *testing.T) {
+	var clock sync.Mutex
+	now := time.Now()
+	meter := NewMeter(10, QuotaDelay)
+	meter.now = func() time.Time {
+		clock.Lock()
+		defer clock.Unlock()
+		return now
+	}
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	meter.sleep = func(d time.Duration) {
+		close(entered)
+		<-release
+		clock.Lock()
+		now = now.Add(d)
+		clock.Unlock()
+	}
+	w := meter.Wrap(new(bytes.Buffer))
+	w.Write([]byte("12345678"))
+	done := make(chan struct{})
+	go func() {
+		w.Write([]byte("12345678"))
+		close(done)
+	}()
+	<-entered
+	stats := make(chan MeterStats, 1)
+	go func() { stats <- meter.Stats() }()
+	select {
+	case s := <-stats:
+		if s.BytesWritten != 8 {
+			t.Errorf("8 bytes should be written before the delay ends, not %d.", s.BytesWritten)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Reading the stats should not wait for the delayed write.")
+	}
+	close(release)
+	<-done
+	if written := meter.Stats().BytesWritten; written != 16 {
+		t.Errorf("16 bytes should be written after the delay, not %d.", written)
+	}
+}
+
+// testMeterLevelWriter keeps the levels of the records it receives.
+type testMeterLevelWriter struct {
+	levels []LogLevel
+}
+
+func (w *testMeterLevelWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(LogLevelInfo, p)
+}
+
+func (w *testMeterLevelWriter) WriteLevel(logLevel LogLevel, p []byte) (int, error) {
+	w.levels = append(w.levels, logLevel)
+	return len(p), nil
+}
+
+// TestMeterForwarding tests that a metered LevelWriter or RecordWriter still
+// receives records through WriteLevel or WriteRecord.
+func TestMeterForwarding(t *testing.T) {
+	meter := NewMeter(0, QuotaDrop)
+	levelWriter := &testMeterLevelWriter{}
+	DefaultLogger.WithWriter(meter.Wrap(levelWriter)).Warning("warning", nil)
+	if len(levelWriter.levels) != 1 || levelWriter.levels[0] != LogLevelWarning {
+		t.Errorf("Levels %v should be [warning].", levelWriter.levels)
+	}
+	recordWriter := &testRecordWriter{}
+	DefaultLogger.WithWriter(meter.Wrap(recordWriter)).Info("info", nil)
+	if len(recordWriter.writes) != 0 || len(recordWriter.records) != 1 {
+		t.Errorf("There should be 1 record and no writes, got %v and %v.", recordWriter.records, recordWriter.writes)
+	}
+	if written := meter.Stats().BytesWritten; written == 0 {
+		t.Errorf("The bytes written through WriteLevel and WriteRecord should be counted.")
+	}
+}
+
+// TestMeterFailedWrite tests that the bytes a failed write did not write are
+// not counted.
+func TestMeterFailedWrite(t *testing.T) {
+	meter := NewMeter(0, QuotaDrop)
+	meter.Wrap(failingWriter{}).Write([]byte("12345"))
+	if written := meter.Stats().BytesWritten; written != 0 {
+		t.Errorf("%d bytes were counted as written instead of 0.", written)
+	}
+}
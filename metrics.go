@@ -0,0 +1,244 @@
+package jsonlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// dropSampled counts the messages dropped by a Sampler.
+	dropSampled = "sampled"
+	// dropFiltered counts the messages rejected by a filter.
+	dropFiltered = "filtered"
+	// dropHook counts the messages discarded by a Hook.
+	dropHook = "hook"
+)
+
+// Metrics counts the messages output and lost by the Loggers it is bound to
+// with WithMetrics, so that the error-log rate or logging failures can be
+// alerted on. It is an expvar.Var, so it can be published with
+// expvar.Publish, and an http.Handler serving the Prometheus text format.
+// Messages below a Logger's level are not counted.
+//
+// Metrics are safe for concurrent use and can be shared by several Loggers.
+type Metrics struct {
+	mutex        sync.Mutex
+	emitted      map[LogLevel]uint64
+	dropped      map[string]uint64
+	encodeErrors uint64
+	writeErrors  uint64
+	// sinks are the sinks watched with WatchSink, by name.
+	sinks map[string]Pinger
+	// profiler measures the records when profiling is enabled.
+	profiler *recordProfiler
+	// rates count the messages per second, for ErrorRate.
+	rates []rateBucket
+	now   func() time.Time
+}
+
+// MetricsSnapshot holds the values of Metrics at some point in time.
+type MetricsSnapshot struct {
+	// Emitted counts the messages written, per level name.
+	Emitted map[string]uint64 `json:"emitted"`
+	// Dropped counts the messages dropped on purpose, per reason:
+	// "sampled", "filtered" or "hook".
+	Dropped map[string]uint64 `json:"dropped"`
+	// EncodeErrors counts the messages lost because the formatter failed.
+	EncodeErrors uint64 `json:"encodeErrors"`
+	// WriteErrors counts the messages lost because the writer failed.
+	WriteErrors uint64 `json:"writeErrors"`
+	// Sinks is the health of the sinks watched with WatchSink, per name.
+	Sinks map[string]SinkHealth `json:"sinks,omitempty"`
+	// TopMessages are the messages with the largest total volume, when
+	// profiling is enabled with EnableProfiling.
+	TopMessages []MessageProfile `json:"topMessages,omitempty"`
+}
+
+// SinkHealth is the result of the Ping of a sink.
+type SinkHealth struct {
+	Up    bool   `json:"up"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewMetrics creates Metrics with all counters at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		emitted: map[LogLevel]uint64{},
+		dropped: map[string]uint64{},
+		now:     time.Now,
+	}
+}
+
+// WithMetrics returns a new Logger counting its messages in `metrics'. A nil
+// value disables counting.
+func (l Logger) WithMetrics(metrics *Metrics) Logger {
+	l.metrics = metrics
+	return l
+}
+
+// WatchSink adds the health of `sink' to the snapshots, under `name'. The sink
+// is pinged for each snapshot, so that its connection is also restored if it
+// was lost.
+func (m *Metrics) WatchSink(name string, sink Pinger) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.sinks == nil {
+		m.sinks = map[string]Pinger{}
+	}
+	m.sinks[name] = sink
+}
+
+// Snapshot returns the current values of the counters and pings the watched
+// sinks.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	snapshot := m.counters()
+	m.mutex.Lock()
+	profiler := m.profiler
+	sinks := make(map[string]Pinger, len(m.sinks))
+	for name, sink := range m.sinks {
+		sinks[name] = sink
+	}
+	m.mutex.Unlock()
+	if profiler != nil {
+		snapshot.TopMessages = profiler.profiles(profiler.top)
+	}
+	if len(sinks) > 0 {
+		snapshot.Sinks = make(map[string]SinkHealth, len(sinks))
+	}
+	for name, sink := range sinks {
+		if err := sink.Ping(); err != nil {
+			snapshot.Sinks[name] = SinkHealth{Error: err.Error()}
+		} else {
+			snapshot.Sinks[name] = SinkHealth{Up: true}
+		}
+	}
+	return snapshot
+}
+
+// counters returns the current values of the counters.
+func (m *Metrics) counters() MetricsSnapshot {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	snapshot := MetricsSnapshot{
+		Emitted:      make(map[string]uint64, len(m.emitted)),
+		Dropped:      make(map[string]uint64, len(m.dropped)),
+		EncodeErrors: m.encodeErrors,
+		WriteErrors:  m.writeErrors,
+	}
+	for logLevel, count := range m.emitted {
+		snapshot.Emitted[logLevel.String()] = count
+	}
+	for reason, count := range m.dropped {
+		snapshot.Dropped[reason] = count
+	}
+	return snapshot
+}
+
+// String returns the snapshot of the counters as JSON, implementing
+// expvar.Var.
+func (m *Metrics) String() string {
+	encoded, _ := json.Marshal(m.Snapshot())
+	return string(encoded)
+}
+
+// ServeHTTP exposes the counters in the Prometheus text exposition format, so
+// that they can be scraped directly.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	snapshot := m.Snapshot()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# TYPE jsonlog_messages_total counter")
+	for _, level := range sortedKeys(snapshot.Emitted) {
+		fmt.Fprintf(w, "jsonlog_messages_total{level=%q} %d\n", level, snapshot.Emitted[level])
+	}
+	fmt.Fprintln(w, "# TYPE jsonlog_dropped_messages_total counter")
+	for _, reason := range sortedKeys(snapshot.Dropped) {
+		fmt.Fprintf(w, "jsonlog_dropped_messages_total{reason=%q} %d\n", reason, snapshot.Dropped[reason])
+	}
+	fmt.Fprintln(w, "# TYPE jsonlog_encode_errors_total counter")
+	fmt.Fprintf(w, "jsonlog_encode_errors_total %d\n", snapshot.EncodeErrors)
+	fmt.Fprintln(w, "# TYPE jsonlog_write_errors_total counter")
+	fmt.Fprintf(w, "jsonlog_write_errors_total %d\n", snapshot.WriteErrors)
+	if len(snapshot.TopMessages) > 0 {
+		fmt.Fprintln(w, "# TYPE jsonlog_message_bytes_total counter")
+		for _, profile := range snapshot.TopMessages {
+			fmt.Fprintf(w, "jsonlog_message_bytes_total{message=%q} %d\n", profile.Message, profile.TotalBytes)
+		}
+	}
+	if len(snapshot.Sinks) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "# TYPE jsonlog_sink_up gauge")
+	names := make([]string, 0, len(snapshot.Sinks))
+	for name := range snapshot.Sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		up := 0
+		if snapshot.Sinks[name].Up {
+			up = 1
+		}
+		fmt.Fprintf(w, "jsonlog_sink_up{sink=%q} %d\n", name, up)
+	}
+}
+
+// sortedKeys returns the keys of a map of counters in order.
+func sortedKeys(counters map[string]uint64) []string {
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countEmitted counts a message written as a record of `size' bytes, whose
+// text was `text' before a StringDictionary shortened it. Like the other
+// counting methods, it does nothing on nil Metrics.
+func (m *Metrics) countEmitted(message *Message, text string, size int) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.emitted[message.logLevel]++
+	m.countRate(message.logLevel)
+	profiler := m.profiler
+	m.mutex.Unlock()
+	if profiler != nil {
+		profiler.observe(text, size, countFields(message))
+	}
+}
+
+// countDropped counts a message dropped for some reason.
+func (m *Metrics) countDropped(reason string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.dropped[reason]++
+	m.mutex.Unlock()
+}
+
+// countEncodeError counts a message lost because of its formatting.
+func (m *Metrics) countEncodeError() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.encodeErrors++
+	m.mutex.Unlock()
+}
+
+// countWriteError counts a message lost because of its writing.
+func (m *Metrics) countWriteError() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	m.writeErrors++
+	m.mutex.Unlock()
+}
@@ -0,0 +1,96 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingWriter is an io.Writer which always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// TestMetrics tests that emitted, dropped and failed messages are counted.
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	buffer := new(bytes.Buffer)
+	sampler := NewSampler(time.Hour, map[LogLevel]SamplingPolicy{LogLevelWarning: {First: 1}})
+	logger := DefaultLogger.WithWriter(buffer).WithMetrics(metrics).WithSampler(sampler)
+	logger.Debug("below the level", nil)
+	logger.Info("emitted", nil)
+	logger.Error("emitted", nil)
+	logger.Warning("sampled", nil)
+	logger.Warning("sampled", nil)
+	logger.WithFilter(func(LogLevel, string, interface{}) bool { return false }).Info("filtered", nil)
+	logger.WithHook(func(*Message) error { return ErrDiscardMessage }).Info("discarded", nil)
+	logger.Info("bad float", map[string]interface{}{"ratio": math.NaN()})
+	logger.WithWriter(failingWriter{}).Info("write failure", nil)
+	snapshot := metrics.Snapshot()
+	expected := MetricsSnapshot{
+		Emitted:      map[string]uint64{"info": 1, "warning": 1, "error": 1},
+		Dropped:      map[string]uint64{"sampled": 1, "filtered": 1, "hook": 1},
+		EncodeErrors: 1,
+		WriteErrors:  1,
+	}
+	expectedJSON, _ := json.Marshal(expected)
+	if metrics.String() != string(expectedJSON) {
+		t.Errorf("Metrics are %s but should be %s.", metrics.String(), expectedJSON)
+	}
+	if snapshot.Emitted["info"] != 1 {
+		t.Errorf("Snapshot %v should count one info message.", snapshot)
+	}
+}
+
+// TestMetricsServeHTTP tests the Prometheus exposition of the counters.
+func TestMetricsServeHTTP(t *testing.T) {
+	metrics := NewMetrics()
+	logger := DefaultLogger.WithWriter(new(bytes.Buffer)).WithMetrics(metrics)
+	logger.Error("boom", nil)
+	logger.Error("boom", nil)
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, expected := range []string{
+		`jsonlog_messages_total{level="error"} 2`,
+		`jsonlog_write_errors_total 0`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Output '%s' should contain '%s'.", body, expected)
+		}
+	}
+}
+
+// pingRecorder is a Pinger returning a preset error.
+type pingRecorder struct {
+	err error
+}
+
+func (p pingRecorder) Ping() error {
+	return p.err
+}
+
+// TestMetricsWatchSink tests that the health of watched sinks is reported.
+func TestMetricsWatchSink(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.WatchSink("http", pingRecorder{})
+	metrics.WatchSink("syslog", pingRecorder{errors.New("connection refused")})
+	snapshot := metrics.Snapshot()
+	if !snapshot.Sinks["http"].Up || snapshot.Sinks["syslog"].Up || snapshot.Sinks["syslog"].Error != "connection refused" {
+		t.Errorf("Unexpected sink health %v.", snapshot.Sinks)
+	}
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	for _, expected := range []string{`jsonlog_sink_up{sink="http"} 1`, `jsonlog_sink_up{sink="syslog"} 0`} {
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Output '%s' should contain '%s'.", recorder.Body.String(), expected)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package jsonlog
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// appendMsgpack appends the MessagePack encoding of a value decoded from JSON
+// with json.Decoder.UseNumber: nil, bool, string, json.Number,
+// []interface{} or map[string]interface{}. Map keys are sorted so that the
+// output is deterministic.
+func appendMsgpack(dst []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(dst, 0xc0)
+	case bool:
+		if v {
+			return append(dst, 0xc3)
+		}
+		return append(dst, 0xc2)
+	case string:
+		return appendMsgpackString(dst, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendMsgpackInt(dst, i)
+		}
+		f, _ := v.Float64()
+		dst = append(dst, 0xcb)
+		return binary.BigEndian.AppendUint64(dst, math.Float64bits(f))
+	case []interface{}:
+		dst = appendMsgpackHeader(dst, len(v), 0x90, 0xdc)
+		for _, element := range v {
+			dst = appendMsgpack(dst, element)
+		}
+		return dst
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dst = appendMsgpackHeader(dst, len(v), 0x80, 0xde)
+		for _, key := range keys {
+			dst = appendMsgpackString(dst, key)
+			dst = appendMsgpack(dst, v[key])
+		}
+		return dst
+	default:
+		return append(dst, 0xc0)
+	}
+}
+
+// appendMsgpackString appends a MessagePack str.
+func appendMsgpackString(dst []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		dst = append(dst, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		dst = append(dst, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		dst = append(dst, 0xda)
+		dst = binary.BigEndian.AppendUint16(dst, uint16(n))
+	default:
+		dst = append(dst, 0xdb)
+		dst = binary.BigEndian.AppendUint32(dst, uint32(n))
+	}
+	return append(dst, s...)
+}
+
+// appendMsgpackInt appends a MessagePack integer in its shortest form.
+func appendMsgpackInt(dst []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		return append(dst, byte(i))
+	case i < 0 && i >= -32:
+		return append(dst, byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(dst, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		dst = append(dst, 0xd1)
+		return binary.BigEndian.AppendUint16(dst, uint16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		dst = append(dst, 0xd2)
+		return binary.BigEndian.AppendUint32(dst, uint32(i))
+	default:
+		dst = append(dst, 0xd3)
+		return binary.BigEndian.AppendUint64(dst, uint64(i))
+	}
+}
+
+// appendMsgpackHeader appends the header of an array or a map of `n'
+// elements, given the tag of its fixed form and of its 16-bit form.
+func appendMsgpackHeader(dst []byte, n int, fixed, tag16 byte) []byte {
+	switch {
+	case n < 16:
+		return append(dst, fixed|byte(n))
+	case n <= math.MaxUint16:
+		dst = append(dst, tag16)
+		return binary.BigEndian.AppendUint16(dst, uint16(n))
+	default:
+		dst = append(dst, tag16+1)
+		return binary.BigEndian.AppendUint32(dst, uint32(n))
+	}
+}
@@ -0,0 +1,42 @@
+package jsonlog
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testMsgpackExample struct {
+	json     string
+	expected string
+}
+
+var testMsgpackExamples = []testMsgpackExample{
+	{`null`, "c0"},
+	{`true`, "c3"},
+	{`5`, "05"},
+	{`-3`, "fd"},
+	{`-100`, "d09c"},
+	{`300`, "d1012c"},
+	{`1.5`, "cb3ff8000000000000"},
+	{`"ab"`, "a26162"},
+	{`[1,"a"]`, "9201a161"},
+	{`{"b":1,"a":false}`, "82a161c2a16201"},
+}
+
+// TestAppendMsgpack tests the MessagePack encoding of decoded JSON values.
+func TestAppendMsgpack(t *testing.T) {
+	for _, example := range testMsgpackExamples {
+		decoder := json.NewDecoder(strings.NewReader(example.json))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			t.Errorf("Decoding '%s' errored with '%s'.", example.json, err.Error())
+			continue
+		}
+		if encoded := hex.EncodeToString(appendMsgpack(nil, value)); encoded != example.expected {
+			t.Errorf("Encoding '%s' gave %s instead of %s.", example.json, encoded, example.expected)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+//go:build windows
+
+package jsonlog
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Win32 constants of the named pipe API.
+const (
+	pipeAccessInbound         = 0x1
+	fileFlagFirstPipeInstance = 0x80000
+	pipeUnlimitedInstances    = 255
+	pipeBufferSize            = 64 * 1024
+	errorPipeBusy             = syscall.Errno(231)
+	errorPipeConnected        = syscall.Errno(535)
+	namedPipeWaitMilliseconds = 5000
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procCreateNamedPipeW = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = kernel32.NewProc("ConnectNamedPipe")
+	procWaitNamedPipeW   = kernel32.NewProc("WaitNamedPipeW")
+)
+
+// NamedPipeWriter sends records to a NamedPipeReceiver over a Windows named
+// pipe, as UnixWriter does over a Unix socket. The pipe is reopened once if a
+// write fails.
+//
+// A NamedPipeWriter is safe for concurrent use.
+type NamedPipeWriter struct {
+	name  string
+	mutex sync.Mutex
+	pipe  *os.File
+}
+
+// NamedPipeReceiver listens on a Windows named pipe for the records of other
+// local processes, sent with NamedPipeWriter, and forwards them through a
+// Logger as UnixReceiver does.
+type NamedPipeReceiver struct {
+	name   string
+	logger Logger
+	mutex  sync.Mutex
+	pipes  map[*os.File]struct{}
+	closed bool
+	wg     sync.WaitGroup
+	// next is the instance of the pipe created for the next client, if
+	// it was created in advance.
+	next syscall.Handle
+}
+
+// NewNamedPipeWriter opens the named pipe `name', such as
+// `\\.\pipe\myapp-log', on which a NamedPipeReceiver listens.
+func NewNamedPipeWriter(name string) (*NamedPipeWriter, error) {
+	pipe, err := openNamedPipe(name)
+	if err != nil {
+		return nil, err
+	}
+	return &NamedPipeWriter{
+		name: name,
+		pipe: pipe,
+	}, nil
+}
+
+// Write sends one or more records.
+func (w *NamedPipeWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.pipe != nil {
+		if n, err := w.pipe.Write(p); err == nil {
+			return n, nil
+		}
+		w.pipe.Close()
+		w.pipe = nil
+	}
+	pipe, err := openNamedPipe(w.name)
+	if err != nil {
+		return 0, err
+	}
+	w.pipe = pipe
+	return w.pipe.Write(p)
+}
+
+// Close closes the pipe.
+func (w *NamedPipeWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.pipe == nil {
+		return nil
+	}
+	err := w.pipe.Close()
+	w.pipe = nil
+	return err
+}
+
+// NewNamedPipeReceiver creates a NamedPipeReceiver listening on the named
+// pipe `name' and forwarding records through `logger'. It fails if another
+// process already serves a pipe with that name. Call Serve to start
+// receiving.
+func NewNamedPipeReceiver(name string, logger Logger) (*NamedPipeReceiver, error) {
+	handle, err := createPipeInstance(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &NamedPipeReceiver{
+		name:   name,
+		logger: logger,
+		pipes:  map[*os.File]struct{}{},
+		next:   handle,
+	}, nil
+}
+
+// Serve accepts clients and forwards their records until the receiver is
+// closed, in which case it returns nil.
+func (r *NamedPipeReceiver) Serve() error {
+	for {
+		handle, err := r.nextInstance()
+		if err != nil {
+			return err
+		} else if handle == 0 {
+			return nil
+		}
+		err = connectNamedPipe(handle)
+		r.mutex.Lock()
+		closed := r.closed
+		r.mutex.Unlock()
+		if closed {
+			syscall.CloseHandle(handle)
+			return nil
+		}
+		if err != nil {
+			syscall.CloseHandle(handle)
+			return err
+		}
+		pipe := os.NewFile(uintptr(handle), r.name)
+		if !r.track(pipe) {
+			pipe.Close()
+			return nil
+		}
+		r.wg.Add(1)
+		go r.receive(pipe)
+	}
+}
+
+// Close stops accepting clients, closes the connected ones and waits for
+// their received records to be forwarded.
+func (r *NamedPipeReceiver) Close() error {
+	r.mutex.Lock()
+	r.closed = true
+	for pipe := range r.pipes {
+		pipe.Close()
+	}
+	if r.next != 0 {
+		syscall.CloseHandle(r.next)
+		r.next = 0
+	}
+	r.mutex.Unlock()
+	// Connect to the pipe to unblock a pending ConnectNamedPipe.
+	if pipe, err := openNamedPipe(r.name); err == nil {
+		pipe.Close()
+	}
+	r.wg.Wait()
+	return nil
+}
+
+// nextInstance returns the instance of the pipe for the next client, or a
+// zero handle if the receiver is closed.
+func (r *NamedPipeReceiver) nextInstance() (syscall.Handle, error) {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return 0, nil
+	}
+	handle := r.next
+	r.next = 0
+	r.mutex.Unlock()
+	if handle != 0 {
+		return handle, nil
+	}
+	return createPipeInstance(r.name, false)
+}
+
+// track registers a connected pipe, unless the receiver is closed.
+func (r *NamedPipeReceiver) track(pipe *os.File) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return false
+	}
+	r.pipes[pipe] = struct{}{}
+	return true
+}
+
+// receive forwards the records of a client until it disconnects.
+func (r *NamedPipeReceiver) receive(pipe *os.File) {
+	defer r.wg.Done()
+	forwardRecords(r.logger, pipe)
+	r.mutex.Lock()
+	delete(r.pipes, pipe)
+	r.mutex.Unlock()
+	pipe.Close()
+}
+
+// createPipeInstance creates an instance of a named pipe. Creating the first
+// instance fails if the pipe already exists.
+func createPipeInstance(name string, first bool) (syscall.Handle, error) {
+	namePointer, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	openMode := uint32(pipeAccessInbound)
+	if first {
+		openMode |= fileFlagFirstPipeInstance
+	}
+	handle, _, err := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(namePointer)),
+		uintptr(openMode),
+		0,
+		pipeUnlimitedInstances,
+		pipeBufferSize,
+		pipeBufferSize,
+		0,
+		0,
+	)
+	if syscall.Handle(handle) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, err
+	}
+	return syscall.Handle(handle), nil
+}
+
+// connectNamedPipe waits for a client to connect to a pipe instance.
+func connectNamedPipe(handle syscall.Handle) error {
+	ok, _, err := procConnectNamedPipe.Call(uintptr(handle), 0)
+	if ok == 0 && err != errorPipeConnected {
+		return err
+	}
+	return nil
+}
+
+// openNamedPipe opens the client end of a named pipe for writing, waiting
+// for an instance to be available if all are busy.
+func openNamedPipe(name string) (*os.File, error) {
+	namePointer, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		handle, err := syscall.CreateFile(namePointer, syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+		if err == nil {
+			return os.NewFile(uintptr(handle), name), nil
+		}
+		if err != errorPipeBusy || attempt > 0 {
+			return nil, err
+		}
+		procWaitNamedPipeW.Call(uintptr(unsafe.Pointer(namePointer)), namedPipeWaitMilliseconds)
+	}
+}
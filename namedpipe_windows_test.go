@@ -0,0 +1,47 @@
+//go:build windows
+
+package jsonlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNamedPipeReceiver tests forwarding records from a short-lived logger
+// to a receiver.
+func TestNamedPipeReceiver(t *testing.T) {
+	name := fmt.Sprintf(`\\.\pipe\jsonlog-test-%d`, os.Getpid())
+	output := &lockedBuffer{}
+	receiver, err := NewNamedPipeReceiver(name, DefaultLogger.WithWriter(output).Named("agent"))
+	if err != nil {
+		t.Errorf("Listening errored with '%s'.", err.Error())
+		return
+	}
+	if _, err := NewNamedPipeReceiver(name, DefaultLogger); err == nil {
+		t.Errorf("Listening twice on the same pipe should fail.")
+	}
+	served := make(chan error)
+	go func() { served <- receiver.Serve() }()
+	w, err := NewNamedPipeWriter(name)
+	if err != nil {
+		t.Errorf("Connecting errored with '%s'.", err.Error())
+		receiver.Close()
+		return
+	}
+	DefaultLogger.WithWriter(w).Named("cli").Warning("Quota almost reached.", nil)
+	w.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(output.String(), "\n") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	receiver.Close()
+	if err := <-served; err != nil {
+		t.Errorf("Serving errored with '%s'.", err.Error())
+	}
+	if expected := `"message":"Quota almost reached.","logger":"agent.cli"`; !strings.Contains(output.String(), expected) {
+		t.Errorf("Output '%s' should contain '%s'.", output.String(), expected)
+	}
+}
@@ -0,0 +1,352 @@
+package jsonlog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCA is returned when the CA file of a NetworkConfig holds no
+	// certificate.
+	ErrInvalidCA = errors.New("jsonlog: no certificate found in CA file")
+	// ErrUnsupportedProxy is returned for proxies whose scheme is not
+	// http, https or socks5.
+	ErrUnsupportedProxy = errors.New("jsonlog: unsupported proxy scheme")
+	// ErrProxyRefused is returned when a proxy refuses to open a tunnel.
+	ErrProxyRefused = errors.New("jsonlog: proxy refused the connection")
+)
+
+// NetworkConfig is the TLS and proxy configuration shared by the network
+// sinks: HTTPSink, SyslogWriter and GELFWriter over TCP. Its zero value
+// connects directly and in clear text, except for HTTPS endpoints.
+type NetworkConfig struct {
+	// TLS enables TLS over TCP for SyslogWriter and GELFWriter. HTTPSink
+	// uses TLS for HTTPS URLs.
+	TLS bool
+	// CertFile and KeyFile are the PEM files of the client certificate
+	// presented to the server, for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// CAFile is a PEM file of the certificate authorities trusted instead
+	// of those of the system.
+	CAFile string
+	// ServerName overrides the name the server certificate is checked
+	// against.
+	ServerName string
+	// InsecureSkipVerify disables the verification of the server
+	// certificate. It is meant for tests only.
+	InsecureSkipVerify bool
+	// Proxy is the URL of the proxy to connect through: "http://",
+	// "https://" or "socks5://", with optional credentials. When empty,
+	// HTTPSink honors the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+	// variables and TCP sinks honor ALL_PROXY. Proxies do not apply to UDP.
+	Proxy string
+	// Lazy delays the connection of SyslogWriter and GELFWriter to the
+	// first record, so that they can be created while their endpoint is
+	// down. By default they connect when created and fail fast. HTTPSink
+	// always connects on the first batch; call its Ping method to fail
+	// fast.
+	Lazy bool
+}
+
+// Pinger is implemented by sinks which can check that their endpoint is
+// reachable, connecting if needed. Pingers can be watched by Metrics.
+type Pinger interface {
+	Ping() error
+}
+
+// networkDialer opens connections as configured by a NetworkConfig, with its
+// files already loaded.
+type networkDialer struct {
+	tls       *tls.Config
+	useTLS    bool
+	proxy     *url.URL
+	netDialer net.Dialer
+	lazy      bool
+}
+
+// TLSConfig builds the TLS configuration, loading the certificate files.
+func (c NetworkConfig) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, ErrInvalidCA
+		}
+	}
+	return config, nil
+}
+
+// HTTPClient builds an HTTP client using the configuration, with the given
+// timeout.
+func (c NetworkConfig) HTTPClient(timeout time.Duration) (*http.Client, error) {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if c.Proxy != "" {
+		proxy, err := parseProxy(c.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
+
+// connAlive tells whether the peer of a connection has not closed it or
+// refused it, by reading with a short deadline. Any data read is discarded.
+func connAlive(conn net.Conn) bool {
+	conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	defer conn.SetReadDeadline(time.Time{})
+	_, err := conn.Read(make([]byte, 1))
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+	return err == nil
+}
+
+// dialer loads the configuration into a networkDialer.
+func (c NetworkConfig) dialer() (*networkDialer, error) {
+	d := &networkDialer{useTLS: c.TLS, lazy: c.Lazy}
+	if c.TLS {
+		tlsConfig, err := c.TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		d.tls = tlsConfig
+	}
+	proxy := c.Proxy
+	if proxy == "" {
+		proxy = os.Getenv("ALL_PROXY")
+		if proxy == "" {
+			proxy = os.Getenv("all_proxy")
+		}
+	}
+	if proxy != "" {
+		proxyURL, err := parseProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		d.proxy = proxyURL
+	}
+	return d, nil
+}
+
+// parseProxy parses the URL of a proxy and checks its scheme.
+func parseProxy(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+		return proxyURL, nil
+	default:
+		return nil, ErrUnsupportedProxy
+	}
+}
+
+// Dial connects to `address' through the proxy, if any and if `network' is
+// TCP, then wraps the connection in TLS if enabled.
+func (d *networkDialer) Dial(network, address string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if d.proxy != nil && strings.HasPrefix(network, "tcp") {
+		conn, err = d.dialProxy(address)
+	} else {
+		conn, err = d.netDialer.Dial(network, address)
+	}
+	if err != nil || !d.useTLS || !strings.HasPrefix(network, "tcp") {
+		return conn, err
+	}
+	config := d.tls.Clone()
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(address)
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// dialProxy opens a tunnel to `address' through the proxy.
+func (d *networkDialer) dialProxy(address string) (net.Conn, error) {
+	proxyAddress := d.proxy.Host
+	if d.proxy.Port() == "" {
+		port := map[string]string{"http": "80", "https": "443", "socks5": "1080"}[d.proxy.Scheme]
+		proxyAddress = net.JoinHostPort(d.proxy.Hostname(), port)
+	}
+	conn, err := d.netDialer.Dial("tcp", proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	if d.proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxy.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	if d.proxy.Scheme == "socks5" {
+		err = socks5Connect(conn, d.proxy.User, address)
+	} else {
+		err = httpConnect(conn, d.proxy.User, address)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// httpConnect opens a tunnel with an HTTP CONNECT request.
+func httpConnect(conn net.Conn, user *url.Userinfo, address string) error {
+	request := "CONNECT " + address + " HTTP/1.1\r\nHost: " + address + "\r\n"
+	if user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		request += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	if _, err := io.WriteString(conn, request+"\r\n"); err != nil {
+		return err
+	}
+	// The reader must not read past the response, since what follows
+	// belongs to the tunnel; a CONNECT response has no body.
+	response, err := http.ReadResponse(bufio.NewReaderSize(byteReader{conn}, 16), nil)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return ErrProxyRefused
+	}
+	return nil
+}
+
+// byteReader reads one byte at a time, so that a bufio.Reader over it never
+// consumes more than it returns.
+type byteReader struct {
+	r io.Reader
+}
+
+func (b byteReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return b.r.Read(p[:1])
+}
+
+// socks5Connect opens a tunnel with the SOCKS5 protocol, as per RFC 1928,
+// authenticating with a username and password as per RFC 1929 if given.
+func socks5Connect(conn net.Conn, user *url.Userinfo, address string) error {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return err
+	}
+	method := byte(0x00)
+	if user != nil {
+		method = 0x02
+	}
+	if _, err := conn.Write([]byte{0x05, 0x01, method}); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 || reply[1] != method {
+		return ErrProxyRefused
+	}
+	if user != nil {
+		password, _ := user.Password()
+		request := []byte{0x01, byte(len(user.Username()))}
+		request = append(request, user.Username()...)
+		request = append(request, byte(len(password)))
+		request = append(request, password...)
+		if _, err := conn.Write(request); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return ErrProxyRefused
+		}
+	}
+	request := []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+		request = append(append(request, 0x01), ip.To4()...)
+	} else if ip != nil {
+		request = append(append(request, 0x04), ip.To16()...)
+	} else {
+		request = append(append(request, 0x03, byte(len(host))), host...)
+	}
+	request = binary.BigEndian.AppendUint16(request, uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != 0x00 {
+		return ErrProxyRefused
+	}
+	var skip int
+	switch header[3] {
+	case 0x01:
+		skip = net.IPv4len + 2
+	case 0x04:
+		skip = net.IPv6len + 2
+	case 0x03:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return err
+		}
+		skip = int(length[0]) + 2
+	default:
+		return ErrProxyRefused
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip))
+	return err
+}
@@ -0,0 +1,224 @@
+package jsonlog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertificate creates a certificate signed by `parent', or self-signed if
+// it is nil, and writes it with its key to PEM files in `dir'.
+func testCertificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating a key errored with '%s'.", err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Creating a certificate errored with '%s'.", err.Error())
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certificate, key, certFile, keyFile
+}
+
+// acceptOne accepts a single connection and returns what it receives up to
+// the first null byte.
+func acceptOne(listener net.Listener) <-chan string {
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- "accept: " + err.Error()
+			return
+		}
+		defer conn.Close()
+		payload, err := bufio.NewReader(conn).ReadString(0)
+		if err != nil {
+			received <- "read: " + err.Error()
+			return
+		}
+		received <- payload
+	}()
+	return received
+}
+
+// TestNetworkConfigMutualTLS tests sending GELF payloads over mutual TLS.
+func TestNetworkConfigMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caFile, _ := testCertificate(t, dir, "ca", nil, nil)
+	_, _, serverCert, serverKey := testCertificate(t, dir, "server", ca, caKey)
+	_, _, clientCert, clientKey := testCertificate(t, dir, "client", ca, caKey)
+	serverTLS, err := NetworkConfig{CertFile: serverCert, KeyFile: serverKey, CAFile: caFile}.TLSConfig()
+	if err != nil {
+		t.Errorf("Loading the server configuration errored with '%s'.", err.Error())
+		return
+	}
+	serverTLS.ClientCAs = serverTLS.RootCAs
+	serverTLS.ClientAuth = tls.RequireAndVerifyClientCert
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	if err != nil {
+		t.Errorf("Listening errored with '%s'.", err.Error())
+		return
+	}
+	defer listener.Close()
+	received := acceptOne(listener)
+	w, err := NewGELFWriterWithNetwork("tcp", listener.Addr().String(), GELFCompressionNone, NetworkConfig{
+		TLS:      true,
+		CertFile: clientCert,
+		KeyFile:  clientKey,
+		CAFile:   caFile,
+	})
+	if err != nil {
+		t.Errorf("Connecting errored with '%s'.", err.Error())
+		return
+	}
+	defer w.Close()
+	w.Write([]byte("{\"short_message\":\"secure\"}\n"))
+	if payload := <-received; payload != "{\"short_message\":\"secure\"}\x00" {
+		t.Errorf("Received '%s' instead of the payload.", payload)
+	}
+	if _, err := (NetworkConfig{CAFile: clientKey}).TLSConfig(); err != ErrInvalidCA {
+		t.Errorf("A CA file without certificate should be rejected, got %v.", err)
+	}
+}
+
+// serveSOCKS5 serves a single SOCKS5 connection with username and password
+// authentication, relaying it to the requested address.
+func serveSOCKS5(listener net.Listener) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	greeting := make([]byte, 3)
+	io.ReadFull(conn, greeting)
+	if greeting[2] != 0x02 {
+		conn.Write([]byte{0x05, 0xff})
+		return
+	}
+	conn.Write([]byte{0x05, 0x02})
+	length := make([]byte, 2)
+	io.ReadFull(conn, length)
+	io.ReadFull(conn, make([]byte, length[1]))
+	io.ReadFull(conn, length[:1])
+	io.ReadFull(conn, make([]byte, length[0]))
+	conn.Write([]byte{0x01, 0x00})
+	header := make([]byte, 4)
+	io.ReadFull(conn, header)
+	var host string
+	switch header[3] {
+	case 0x01:
+		ip := make([]byte, 4)
+		io.ReadFull(conn, ip)
+		host = net.IP(ip).String()
+	case 0x03:
+		length := make([]byte, 1)
+		io.ReadFull(conn, length)
+		name := make([]byte, length[0])
+		io.ReadFull(conn, name)
+		host = string(name)
+	}
+	port := make([]byte, 2)
+	io.ReadFull(conn, port)
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer target.Close()
+	conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 127, 0, 0, 1, 0, 0})
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+// serveHTTPConnect serves a single HTTP CONNECT request, relaying the tunnel
+// to the requested address.
+func serveHTTPConnect(listener net.Listener) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	request, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || request.Method != http.MethodConnect || request.Header.Get("Proxy-Authorization") == "" {
+		io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		return
+	}
+	target, err := net.Dial("tcp", request.Host)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer target.Close()
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+// TestNetworkConfigProxies tests sending GELF payloads through SOCKS5 and
+// HTTP proxies.
+func TestNetworkConfigProxies(t *testing.T) {
+	for _, scheme := range []string{"socks5", "http"} {
+		target, _ := net.Listen("tcp", "127.0.0.1:0")
+		proxy, _ := net.Listen("tcp", "127.0.0.1:0")
+		received := acceptOne(target)
+		if scheme == "socks5" {
+			go serveSOCKS5(proxy)
+		} else {
+			go serveHTTPConnect(proxy)
+		}
+		w, err := NewGELFWriterWithNetwork("tcp", target.Addr().String(), GELFCompressionNone, NetworkConfig{
+			Proxy: scheme + "://user:secret@" + proxy.Addr().String(),
+		})
+		if err != nil {
+			t.Errorf("Connecting through %s errored with '%s'.", scheme, err.Error())
+		} else {
+			w.Write([]byte("{\"short_message\":\"proxied\"}\n"))
+			if payload := <-received; !strings.Contains(payload, "proxied") {
+				t.Errorf("Received '%s' through %s instead of the payload.", payload, scheme)
+			}
+			w.Close()
+		}
+		target.Close()
+		proxy.Close()
+	}
+	if _, err := NewGELFWriterWithNetwork("tcp", "127.0.0.1:1", GELFCompressionNone, NetworkConfig{Proxy: "ftp://proxy"}); err != ErrUnsupportedProxy {
+		t.Errorf("An FTP proxy should be rejected, got %v.", err)
+	}
+}
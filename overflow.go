@@ -0,0 +1,228 @@
+package jsonlog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// overflowFileSuffix is the extension of the files of a disk overflow.
+const overflowFileSuffix = ".ndjson"
+
+// ErrOverflowFull is returned when a batch cannot fit in an overflow buffer,
+// even after dropping the oldest batches.
+var ErrOverflowFull = errors.New("jsonlog: batch too large for overflow buffer")
+
+// OverflowBuffer keeps the batches a sink could not deliver until they can be
+// sent again, oldest first. When full, implementations drop the oldest
+// batches to make room for new ones.
+//
+// Implementations must be safe for concurrent use.
+type OverflowBuffer interface {
+	// Push adds a batch at the end of the buffer.
+	Push(batch []byte) error
+	// Front returns the oldest batch, or nil if the buffer is empty.
+	Front() ([]byte, error)
+	// Drop removes the oldest batch.
+	Drop() error
+}
+
+// MemoryOverflow is an OverflowBuffer holding batches in memory. They are
+// lost if the process exits.
+type MemoryOverflow struct {
+	maxBytes int
+	mutex    sync.Mutex
+	batches  [][]byte
+	size     int
+}
+
+// DiskOverflow is an OverflowBuffer holding batches in files in a directory,
+// so that they survive a restart of the process. Each batch is a file of
+// NDJSON records.
+type DiskOverflow struct {
+	dir      string
+	maxBytes int64
+	mutex    sync.Mutex
+	// files are the names of the batch files, oldest first.
+	files []string
+	sizes []int64
+	size  int64
+	next  uint64
+}
+
+// NewMemoryOverflow creates a MemoryOverflow holding at most `maxBytes' of
+// batches.
+func NewMemoryOverflow(maxBytes int) *MemoryOverflow {
+	return &MemoryOverflow{maxBytes: maxBytes}
+}
+
+// Push adds a copy of `batch' to the buffer.
+func (o *MemoryOverflow) Push(batch []byte) error {
+	if len(batch) > o.maxBytes {
+		return ErrOverflowFull
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for o.size+len(batch) > o.maxBytes {
+		o.dropLocked()
+	}
+	o.batches = append(o.batches, append([]byte(nil), batch...))
+	o.size += len(batch)
+	return nil
+}
+
+// Front returns the oldest batch.
+func (o *MemoryOverflow) Front() ([]byte, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if len(o.batches) == 0 {
+		return nil, nil
+	}
+	return o.batches[0], nil
+}
+
+// Drop removes the oldest batch.
+func (o *MemoryOverflow) Drop() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.dropLocked()
+	return nil
+}
+
+// Len returns the number of batches in the buffer.
+func (o *MemoryOverflow) Len() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.batches)
+}
+
+// dropLocked removes the oldest batch. The mutex must be held.
+func (o *MemoryOverflow) dropLocked() {
+	if len(o.batches) == 0 {
+		return
+	}
+	o.size -= len(o.batches[0])
+	o.batches[0] = nil
+	o.batches = o.batches[1:]
+}
+
+// NewDiskOverflow creates a DiskOverflow storing at most `maxBytes' of batches
+// in `dir', which is created if needed. Batches left in `dir' by a previous
+// process are kept and sent first.
+func NewDiskOverflow(dir string, maxBytes int64) (*DiskOverflow, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	o := &DiskOverflow{
+		dir:      dir,
+		maxBytes: maxBytes,
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		sequence, err := strconv.ParseUint(strings.TrimSuffix(name, overflowFileSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(name, overflowFileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		o.files = append(o.files, name)
+		o.sizes = append(o.sizes, info.Size())
+		o.size += info.Size()
+		if sequence >= o.next {
+			o.next = sequence + 1
+		}
+	}
+	sort.Sort(overflowFiles{o})
+	return o, nil
+}
+
+// Push writes `batch' to a new file. The file is written under a temporary
+// name and renamed, so that a crash never leaves a partial batch.
+func (o *DiskOverflow) Push(batch []byte) error {
+	if int64(len(batch)) > o.maxBytes {
+		return ErrOverflowFull
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for o.size+int64(len(batch)) > o.maxBytes {
+		if err := o.dropLocked(); err != nil {
+			return err
+		}
+	}
+	name := fmt.Sprintf("%020d%s", o.next, overflowFileSuffix)
+	temporary := filepath.Join(o.dir, "."+name)
+	if err := os.WriteFile(temporary, batch, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(temporary, filepath.Join(o.dir, name)); err != nil {
+		os.Remove(temporary)
+		return err
+	}
+	o.next++
+	o.files = append(o.files, name)
+	o.sizes = append(o.sizes, int64(len(batch)))
+	o.size += int64(len(batch))
+	return nil
+}
+
+// Front reads the oldest batch.
+func (o *DiskOverflow) Front() ([]byte, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if len(o.files) == 0 {
+		return nil, nil
+	}
+	return os.ReadFile(filepath.Join(o.dir, o.files[0]))
+}
+
+// Drop removes the file of the oldest batch.
+func (o *DiskOverflow) Drop() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.dropLocked()
+}
+
+// Len returns the number of batches in the buffer.
+func (o *DiskOverflow) Len() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.files)
+}
+
+// dropLocked removes the file of the oldest batch. The mutex must be held.
+func (o *DiskOverflow) dropLocked() error {
+	if len(o.files) == 0 {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(o.dir, o.files[0])); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	o.size -= o.sizes[0]
+	o.files = o.files[1:]
+	o.sizes = o.sizes[1:]
+	return nil
+}
+
+// overflowFiles sorts the files of a DiskOverflow by name, which is their
+// order of creation.
+type overflowFiles struct {
+	o *DiskOverflow
+}
+
+func (f overflowFiles) Len() int           { return len(f.o.files) }
+func (f overflowFiles) Less(i, j int) bool { return f.o.files[i] < f.o.files[j] }
+func (f overflowFiles) Swap(i, j int) {
+	f.o.files[i], f.o.files[j] = f.o.files[j], f.o.files[i]
+	f.o.sizes[i], f.o.sizes[j] = f.o.sizes[j], f.o.sizes[i]
+}
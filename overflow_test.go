@@ -0,0 +1,61 @@
+package jsonlog
+
+import (
+	"testing"
+)
+
+// TestMemoryOverflow tests that the oldest batches are dropped when the buffer
+// is full.
+func TestMemoryOverflow(t *testing.T) {
+	overflow := NewMemoryOverflow(8)
+	for _, batch := range []string{"abc", "def", "ghi"} {
+		if err := overflow.Push([]byte(batch)); err != nil {
+			t.Errorf("Pushing errored with '%s'.", err.Error())
+		}
+	}
+	if front, _ := overflow.Front(); string(front) != "def" || overflow.Len() != 2 {
+		t.Errorf("Front '%s' should be 'def' with 2 batches.", front)
+	}
+	if err := overflow.Push([]byte("too large")); err != ErrOverflowFull {
+		t.Errorf("Pushing a batch larger than the buffer should fail with ErrOverflowFull, got %v.", err)
+	}
+}
+
+// TestDiskOverflow tests that batches are kept across instances, in order.
+func TestDiskOverflow(t *testing.T) {
+	dir := t.TempDir()
+	overflow, err := NewDiskOverflow(dir, 8)
+	if err != nil {
+		t.Errorf("Creating the overflow errored with '%s'.", err.Error())
+		return
+	}
+	for _, batch := range []string{"abc", "def", "ghi"} {
+		if err := overflow.Push([]byte(batch)); err != nil {
+			t.Errorf("Pushing errored with '%s'.", err.Error())
+		}
+	}
+	reopened, err := NewDiskOverflow(dir, 8)
+	if err != nil {
+		t.Errorf("Reopening the overflow errored with '%s'.", err.Error())
+		return
+	}
+	var batches []string
+	for {
+		batch, err := reopened.Front()
+		if err != nil {
+			t.Errorf("Reading errored with '%s'.", err.Error())
+			return
+		}
+		if batch == nil {
+			break
+		}
+		batches = append(batches, string(batch))
+		reopened.Drop()
+	}
+	if len(batches) != 2 || batches[0] != "def" || batches[1] != "ghi" {
+		t.Errorf("Batches %q should be [def ghi].", batches)
+	}
+	if err := reopened.Push([]byte("jkl")); err != nil || reopened.Len() != 1 {
+		t.Errorf("Pushing after draining should work.")
+	}
+}
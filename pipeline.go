@@ -0,0 +1,189 @@
+package jsonlog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrReorderingRequired is returned when a pipeline with several batches in
+// flight is configured with StrictOrder.
+var ErrReorderingRequired = errors.New("jsonlog: concurrent batches require AllowReordering")
+
+// Ordering is the ordering guarantee of a PipelinedSink.
+type Ordering int
+
+const (
+	// StrictOrder writes batches one at a time, in the order they were
+	// given.
+	StrictOrder Ordering = iota
+	// AllowReordering writes batches concurrently, so that they may
+	// complete in any order.
+	AllowReordering
+)
+
+// PipelineConfig configures a PipelinedSink.
+type PipelineConfig struct {
+	// MaxInFlight is the number of batches written concurrently. It
+	// defaults to 1; above that, Ordering must be AllowReordering.
+	MaxInFlight int
+	// Depth is the number of batches queued while the writes in flight
+	// complete. When the queue is full, Write blocks until a batch is
+	// taken from it. It defaults to 0: each Write waits for a writer to be
+	// free.
+	Depth int
+	// Ordering is the ordering guarantee of the batches.
+	Ordering Ordering
+}
+
+// PipelinedSink writes batches to an underlying writer in the background, so
+// that callers, usually a BatchingSink, do not wait for slow sinks such as an
+// HTTPSink. The configuration trades ordering for throughput explicitly:
+// strict order allows a single batch in flight, while allowing reordering
+// lets several batches be written concurrently. The underlying writer must
+// then be safe for concurrent use. Batches are written with WriteBatch if the
+// underlying writer implements BatchWriter, such as an HTTPSink, so that their
+// records are kept whole.
+//
+// Write errors are reported by the next call to Flush or Close, and the
+// batches which failed are dropped.
+//
+// A PipelinedSink is safe for concurrent use.
+type PipelinedSink struct {
+	w     io.Writer
+	queue chan [][]byte
+	// mutex is held for reading while sending to the queue, and for writing
+	// while closing it.
+	mutex  sync.RWMutex
+	closed bool
+	// pendingMutex guards pending, the number of batches queued or in
+	// flight. drained is signaled when it drops to zero.
+	pendingMutex sync.Mutex
+	pending      int
+	drained      *sync.Cond
+	workers      sync.WaitGroup
+	// errMutex guards err, the first error since the last Flush.
+	errMutex sync.Mutex
+	err      error
+}
+
+// NewPipelinedSink creates a PipelinedSink writing to `w'.
+func NewPipelinedSink(w io.Writer, config PipelineConfig) (*PipelinedSink, error) {
+	if config.MaxInFlight <= 0 {
+		config.MaxInFlight = 1
+	}
+	if config.Depth < 0 {
+		config.Depth = 0
+	}
+	if config.MaxInFlight > 1 && config.Ordering != AllowReordering {
+		return nil, ErrReorderingRequired
+	}
+	s := &PipelinedSink{
+		w:     w,
+		queue: make(chan [][]byte, config.Depth),
+	}
+	s.drained = sync.NewCond(&s.pendingMutex)
+	s.workers.Add(config.MaxInFlight)
+	for i := 0; i < config.MaxInFlight; i++ {
+		go s.work()
+	}
+	return s, nil
+}
+
+// Write queues a copy of `p' as a batch of a single record, without its
+// trailing newline, waiting while the queue is full.
+func (s *PipelinedSink) Write(p []byte) (int, error) {
+	if err := s.WriteRecord(bytes.TrimSuffix(p, []byte("\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteRecord queues a copy of `record' as a batch of its own, implementing
+// RecordWriter.
+func (s *PipelinedSink) WriteRecord(record []byte) error {
+	return s.WriteBatch([][]byte{record})
+}
+
+// WriteBatch queues a copy of `records' as one batch, waiting while the queue
+// is full, implementing BatchWriter.
+func (s *PipelinedSink) WriteBatch(records [][]byte) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.closed {
+		return ErrSinkClosed
+	}
+	batch := make([][]byte, len(records))
+	for i, record := range records {
+		batch[i] = append([]byte(nil), record...)
+	}
+	s.pendingMutex.Lock()
+	s.pending++
+	s.pendingMutex.Unlock()
+	s.queue <- batch
+	return nil
+}
+
+// Flush waits for the queued batches to be written, then flushes the
+// underlying writer if it implements `Flush() error'. It returns the first
+// error since the last call.
+func (s *PipelinedSink) Flush() error {
+	s.pendingMutex.Lock()
+	for s.pending > 0 {
+		s.drained.Wait()
+	}
+	s.pendingMutex.Unlock()
+	err := s.takeErr()
+	if f, ok := s.w.(flusher); ok {
+		if flushErr := f.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	return err
+}
+
+// Close writes the queued batches and stops the pipeline. It does not close
+// the underlying writer. Writing to a closed PipelinedSink fails with
+// ErrSinkClosed.
+func (s *PipelinedSink) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mutex.Unlock()
+	s.workers.Wait()
+	return s.takeErr()
+}
+
+// work writes batches from the queue until it is closed.
+func (s *PipelinedSink) work() {
+	defer s.workers.Done()
+	for batch := range s.queue {
+		if err := writeBatch(s.w, batch); err != nil {
+			s.errMutex.Lock()
+			if s.err == nil {
+				s.err = err
+			}
+			s.errMutex.Unlock()
+		}
+		s.pendingMutex.Lock()
+		s.pending--
+		if s.pending == 0 {
+			s.drained.Broadcast()
+		}
+		s.pendingMutex.Unlock()
+	}
+}
+
+// takeErr returns the first error since the last call.
+func (s *PipelinedSink) takeErr() error {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+	err := s.err
+	s.err = nil
+	return err
+}
@@ -0,0 +1,163 @@
+package jsonlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowWriter records its writes after a delay and the highest number of
+// concurrent writes.
+type slowWriter struct {
+	writeRecorder
+	delay       func(p []byte) time.Duration
+	concurrency sync.Mutex
+	current     int
+	highest     int
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	w.concurrency.Lock()
+	w.current++
+	if w.current > w.highest {
+		w.highest = w.current
+	}
+	w.concurrency.Unlock()
+	time.Sleep(w.delay(p))
+	w.concurrency.Lock()
+	w.current--
+	w.concurrency.Unlock()
+	return w.writeRecorder.Write(p)
+}
+
+// TestPipelinedSinkStrictOrder tests that batches keep their order when
+// written one at a time.
+func TestPipelinedSinkStrictOrder(t *testing.T) {
+	w := &slowWriter{delay: func(p []byte) time.Duration { return time.Duration(10-len(p)) * time.Millisecond }}
+	sink, err := NewPipelinedSink(w, PipelineConfig{Depth: 8})
+	if err != nil {
+		t.Errorf("Creating the sink errored with '%s'.", err.Error())
+		return
+	}
+	for i := 1; i <= 5; i++ {
+		sink.Write([]byte(strconv.Itoa(i)))
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Closing errored with '%s'.", err.Error())
+	}
+	for i, write := range w.writes {
+		if write != strconv.Itoa(i+1)+"\n" {
+			t.Errorf("Batches were written out of order: %v.", w.writes)
+			break
+		}
+	}
+	if w.highest != 1 {
+		t.Errorf("%d batches were in flight at once instead of 1.", w.highest)
+	}
+	if _, err := sink.Write([]byte("late")); err != ErrSinkClosed {
+		t.Errorf("Writing to a closed sink should fail, got %v.", err)
+	}
+}
+
+// TestPipelinedSinkReordering tests that batches are written concurrently
+// when reordering is allowed.
+func TestPipelinedSinkReordering(t *testing.T) {
+	if _, err := NewPipelinedSink(&writeRecorder{}, PipelineConfig{MaxInFlight: 4}); err != ErrReorderingRequired {
+		t.Errorf("Concurrent batches in strict order should be rejected, got %v.", err)
+	}
+	w := &slowWriter{delay: func([]byte) time.Duration { return 20 * time.Millisecond }}
+	sink, _ := NewPipelinedSink(w, PipelineConfig{MaxInFlight: 4, Depth: 4, Ordering: AllowReordering})
+	defer sink.Close()
+	for i := 0; i < 8; i++ {
+		sink.Write([]byte("batch\n"))
+	}
+	if err := sink.Flush(); err != nil {
+		t.Errorf("Flushing errored with '%s'.", err.Error())
+	}
+	if w.records() != 8 {
+		t.Errorf("%d batches were written instead of 8.", w.records())
+	}
+	if w.highest < 2 {
+		t.Errorf("Batches were not written concurrently.")
+	}
+}
+
+// TestPipelinedSinkErrors tests that background errors are reported once by
+// Flush.
+func TestPipelinedSinkErrors(t *testing.T) {
+	sink, _ := NewPipelinedSink(failingWriter{}, PipelineConfig{})
+	defer sink.Close()
+	sink.Write([]byte("lost\n"))
+	if err := sink.Flush(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Flush should report the write error, got %v.", err)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Errorf("The error should be reported once, got '%s'.", err.Error())
+	}
+}
+
+// TestPipelinedSinkConcurrentFlush tests that Flush can be called while
+// other goroutines write, and that every batch is written.
+func TestPipelinedSinkConcurrentFlush(t *testing.T) {
+	w := &writeRecorder{}
+	sink, _ := NewPipelinedSink(w, PipelineConfig{MaxInFlight: 2, Depth: 2, Ordering: AllowReordering})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sink.Write([]byte("batch\n"))
+			}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := sink.Flush(); err != nil {
+					t.Errorf("Flushing errored with '%s'.", err.Error())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := sink.Flush(); err != nil {
+		t.Errorf("Flushing errored with '%s'.", err.Error())
+	}
+	if w.records() != 800 {
+		t.Errorf("%d batches were written instead of 800.", w.records())
+	}
+	sink.Close()
+}
+
+// TestHTTPSinkConcurrentRequests tests that concurrent writes to an HTTPSink
+// are sent as concurrent requests.
+func TestHTTPSinkConcurrentRequests(t *testing.T) {
+	arrived := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		deadline := time.After(time.Second)
+		for len(arrived) < 2 {
+			select {
+			case <-deadline:
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+	httpSink := newTestHTTPSink(t, HTTPSinkConfig{URL: server.URL})
+	sink, _ := NewPipelinedSink(httpSink, PipelineConfig{MaxInFlight: 2, Ordering: AllowReordering})
+	defer sink.Close()
+	sink.Write([]byte("{}\n"))
+	sink.Write([]byte("{}\n"))
+	if err := sink.Flush(); err != nil {
+		t.Errorf("The requests were not concurrent: '%s'.", err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package jsonlog
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// productionSamplingPolicy keeps the first hundred identical messages of a
+// second, then one in a hundred.
+var productionSamplingPolicy = SamplingPolicy{First: 100, Thereafter: 100}
+
+// newPresetLogger returns a Logger writing to `writer' with `formatter' at
+// `logLevel', with the call site of each message and stack traces for errors.
+// Presets are built from scratch rather than from DefaultLogger so that the
+// configuration a program gives DefaultLogger does not leak into them.
+func newPresetLogger(writer io.Writer, formatter Formatter, logLevel LogLevel) Logger {
+	return Logger{
+		writer:     writer,
+		formatter:  formatter,
+		logLevel:   logLevel,
+		context:    context.Background(),
+		stackTrace: true,
+		caller:     true,
+	}
+}
+
+// Production returns a Logger suited to services in production: JSON records
+// on the standard output at the info level, with the call site of each
+// message, stack traces for errors, and sampling of repetitive debug, info
+// and warning messages. Errors are never sampled.
+func Production() Logger {
+	sampler := NewSampler(time.Second, map[LogLevel]SamplingPolicy{
+		LogLevelDebug:   productionSamplingPolicy,
+		LogLevelInfo:    productionSamplingPolicy,
+		LogLevelWarning: productionSamplingPolicy,
+	})
+	return newPresetLogger(os.Stdout, JSONFormatter{}, LogLevelInfo).WithSampler(sampler)
+}
+
+// Development returns a Logger suited to local development: logfmt lines,
+// easier to read than JSON, on the standard error at the debug level, with
+// the call site of each message and stack traces for errors. Nothing is
+// sampled, and failed assertions panic.
+func Development() Logger {
+	return newPresetLogger(os.Stderr, LogfmtFormatter{}, LogLevelDebug).WithAssertPanics(true)
+}
+
+// Testing returns a Logger suited to tests: JSON records on the standard
+// error at the debug level, with the call site of each message and stack
+// traces for errors. Nothing is sampled, so that every message can be
+// checked. To assert on what was logged, write to a jsonlogtest.Sink.
+func Testing() Logger {
+	return newPresetLogger(os.Stderr, JSONFormatter{}, LogLevelDebug)
+}
@@ -0,0 +1,76 @@
+package jsonlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type testPresetExample struct {
+	name     string
+	logger   Logger
+	debug    bool
+	contains string
+}
+
+// TestPresets tests the level, format and caller of the preset Loggers.
+func TestPresets(t *testing.T) {
+	examples := []testPresetExample{
+		{"production", Production(), false, `"caller":"`},
+		{"development", Development(), true, "context.caller="},
+		{"testing", Testing(), true, `"caller":"`},
+	}
+	for _, example := range examples {
+		buffer := new(bytes.Buffer)
+		logger := example.logger.WithWriter(buffer)
+		logger.Debug("debug", nil)
+		if logged := buffer.Len() > 0; logged != example.debug {
+			t.Errorf("The %s preset should log debug messages: %t.", example.name, example.debug)
+		}
+		buffer.Reset()
+		logger.Info("info", nil)
+		if !strings.Contains(buffer.String(), example.contains) {
+			t.Errorf("The %s preset output '%s' should contain '%s'.", example.name, buffer.String(), example.contains)
+		}
+	}
+}
+
+// TestProductionSampling tests that the production preset samples repetitive
+// info messages but not errors.
+func TestProductionSampling(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := Production().WithWriter(buffer)
+	for i := 0; i < 150; i++ {
+		logger.Info("repeated", nil)
+		logger.Error("failure", nil)
+	}
+	if infos := strings.Count(buffer.String(), `"message":"repeated"`); infos != 100 {
+		t.Errorf("%d info messages were logged instead of 100.", infos)
+	}
+	if errors := strings.Count(buffer.String(), `"message":"failure"`); errors != 150 {
+		t.Errorf("%d errors were logged instead of 150.", errors)
+	}
+}
+
+// TestPresetsIgnoreDefaultLogger tests that the preset Loggers do not pick up
+// the configuration of DefaultLogger.
+func TestPresetsIgnoreDefaultLogger(t *testing.T) {
+	previous := DefaultLogger
+	defer func() { DefaultLogger = previous }()
+	hooked := false
+	DefaultLogger = DefaultLogger.
+		WithFormatter(LogfmtFormatter{}).
+		WithLogLevel(LogLevelError).
+		WithHook(func(m *Message) error {
+			hooked = true
+			return nil
+		})
+	buffer := new(bytes.Buffer)
+	Production().WithWriter(buffer).Info("info", nil)
+	if !strings.HasPrefix(buffer.String(), "{") {
+		t.Errorf("The production preset should log JSON, got '%s'.", buffer.String())
+	}
+	if hooked {
+		t.Errorf("The hooks of DefaultLogger should not run for the production preset.")
+	}
+}
@@ -0,0 +1,200 @@
+package jsonlog
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	// profileSamples is the number of recent records whose size and field
+	// count are kept per message to compute percentiles.
+	profileSamples = 256
+	// otherMessages groups the messages beyond the limit of a profiler.
+	otherMessages = "(other)"
+)
+
+// MessageProfile is the size and shape of the records of one message, as
+// measured by the profiler of Metrics. Percentiles are computed over the
+// most recent records of the message.
+type MessageProfile struct {
+	Message    string  `json:"message"`
+	Count      uint64  `json:"count"`
+	TotalBytes uint64  `json:"totalBytes"`
+	AvgBytes   float64 `json:"avgBytes"`
+	P50Bytes   int     `json:"p50Bytes"`
+	P95Bytes   int     `json:"p95Bytes"`
+	P99Bytes   int     `json:"p99Bytes"`
+	MaxBytes   int     `json:"maxBytes"`
+	AvgFields  float64 `json:"avgFields"`
+	MaxFields  int     `json:"maxFields"`
+}
+
+// recordProfiler measures the records written, grouped by message.
+type recordProfiler struct {
+	mutex       sync.Mutex
+	maxMessages int
+	top         int
+	messages    map[string]*messageStats
+}
+
+// messageStats accumulates the figures of the records of a message.
+type messageStats struct {
+	count       uint64
+	totalBytes  uint64
+	totalFields uint64
+	maxBytes    int
+	maxFields   int
+	// sizes are the sizes of the latest records, in a ring.
+	sizes []int
+	next  int
+}
+
+// EnableProfiling makes the Metrics measure the size and number of fields of
+// the records of each message, so that the log statements dominating the
+// volume can be found. Snapshots then hold the `top' messages by total
+// volume. At most `maxMessages' distinct messages are tracked, the following
+// ones being grouped under "(other)": messages should be constant templates,
+// with the variable parts in the data. Profiling costs a walk of the data of
+// each record.
+func (m *Metrics) EnableProfiling(maxMessages, top int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.profiler = &recordProfiler{
+		maxMessages: maxMessages,
+		top:         top,
+		messages:    map[string]*messageStats{},
+	}
+}
+
+// MessageProfiles returns the profiles of all the messages measured since
+// profiling was enabled, by decreasing total volume. It returns nil if
+// profiling is not enabled.
+func (m *Metrics) MessageProfiles() []MessageProfile {
+	m.mutex.Lock()
+	profiler := m.profiler
+	m.mutex.Unlock()
+	if profiler == nil {
+		return nil
+	}
+	return profiler.profiles(0)
+}
+
+// observe records the size and field count of a record.
+func (p *recordProfiler) observe(message string, size, fields int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	stats, ok := p.messages[message]
+	if !ok {
+		if len(p.messages) >= p.maxMessages {
+			message = otherMessages
+			stats = p.messages[message]
+		}
+		if stats == nil {
+			stats = &messageStats{}
+			p.messages[message] = stats
+		}
+	}
+	stats.count++
+	stats.totalBytes += uint64(size)
+	stats.totalFields += uint64(fields)
+	if size > stats.maxBytes {
+		stats.maxBytes = size
+	}
+	if fields > stats.maxFields {
+		stats.maxFields = fields
+	}
+	if len(stats.sizes) < profileSamples {
+		stats.sizes = append(stats.sizes, size)
+	} else {
+		stats.sizes[stats.next] = size
+		stats.next = (stats.next + 1) % profileSamples
+	}
+}
+
+// profiles returns the profiles by decreasing total volume, limited to `top'
+// if it is positive.
+func (p *recordProfiler) profiles(top int) []MessageProfile {
+	p.mutex.Lock()
+	profiles := make([]MessageProfile, 0, len(p.messages))
+	for message, stats := range p.messages {
+		profiles = append(profiles, stats.profile(message))
+	}
+	p.mutex.Unlock()
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].TotalBytes != profiles[j].TotalBytes {
+			return profiles[i].TotalBytes > profiles[j].TotalBytes
+		}
+		return profiles[i].Message < profiles[j].Message
+	})
+	if top > 0 && len(profiles) > top {
+		profiles = profiles[:top]
+	}
+	return profiles
+}
+
+// profile computes the profile of a message.
+func (s *messageStats) profile(message string) MessageProfile {
+	sizes := make([]int, len(s.sizes))
+	copy(sizes, s.sizes)
+	sort.Ints(sizes)
+	return MessageProfile{
+		Message:    message,
+		Count:      s.count,
+		TotalBytes: s.totalBytes,
+		AvgBytes:   float64(s.totalBytes) / float64(s.count),
+		P50Bytes:   percentile(sizes, 50),
+		P95Bytes:   percentile(sizes, 95),
+		P99Bytes:   percentile(sizes, 99),
+		MaxBytes:   s.maxBytes,
+		AvgFields:  float64(s.totalFields) / float64(s.count),
+		MaxFields:  s.maxFields,
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []int, p int) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// countFields counts the scalar fields of the data and the context of a
+// message, nested ones included.
+func countFields(m *Message) int {
+	w := walker{float: func(f float64) interface{} { return f }}
+	fields := 0
+	if m.Data != nil {
+		data, _ := w.walk(m.Data)
+		fields += countScalars(data)
+	}
+	for _, value := range m.Context {
+		converted, _ := w.walk(value)
+		fields += countScalars(converted)
+	}
+	return fields
+}
+
+// countScalars counts the scalars of a value converted by the walker.
+func countScalars(value interface{}) int {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		count := 0
+		for _, e := range v {
+			count += countScalars(e)
+		}
+		return count
+	case []interface{}:
+		count := 0
+		for _, e := range v {
+			count += countScalars(e)
+		}
+		return count
+	default:
+		return 1
+	}
+}
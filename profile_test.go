@@ -0,0 +1,76 @@
+package jsonlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetricsProfiling tests that record sizes and field counts are measured
+// per message and that the largest messages are in the snapshots.
+func TestMetricsProfiling(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.EnableProfiling(2, 1)
+	buffer := new(bytes.Buffer)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	logger := DefaultLogger.WithWriter(buffer).WithMetrics(metrics).WithClock(func() time.Time { return now })
+	logger.Info("small", nil)
+	for i := 0; i < 3; i++ {
+		buffer.Reset()
+		logger.Info("large", map[string]interface{}{
+			"payload": strings.Repeat("x", 100),
+			"nested":  map[string]int{"a": 1, "b": 2},
+		})
+	}
+	size := buffer.Len()
+	logger.Info("untracked", nil)
+	profiles := metrics.MessageProfiles()
+	if len(profiles) != 3 {
+		t.Fatalf("There should be three profiles, got %v.", profiles)
+	}
+	large := profiles[0]
+	if large.Message != "large" || large.Count != 3 || large.TotalBytes != uint64(3*size) {
+		t.Errorf("The first profile should be the large message, got %+v.", large)
+	}
+	if large.P50Bytes != size || large.P99Bytes != size || large.MaxBytes != size || large.AvgFields != 3 || large.MaxFields != 3 {
+		t.Errorf("Profile %+v should have records of %d bytes with 3 fields.", large, size)
+	}
+	if profiles[2].Message != otherMessages && profiles[1].Message != otherMessages {
+		t.Errorf("Messages beyond the limit should be grouped under %q, got %v.", otherMessages, profiles)
+	}
+	snapshot := metrics.Snapshot()
+	if len(snapshot.TopMessages) != 1 || snapshot.TopMessages[0].Message != "large" {
+		t.Errorf("The snapshot should hold the top message only, got %v.", snapshot.TopMessages)
+	}
+	if NewMetrics().MessageProfiles() != nil {
+		t.Errorf("Profiles should be nil when profiling is not enabled.")
+	}
+}
+
+// TestMetricsProfilingDictionary tests that messages are profiled by their
+// text when a string dictionary shortens them.
+func TestMetricsProfilingDictionary(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.EnableProfiling(10, 1)
+	logger := DefaultLogger.WithWriter(new(bytes.Buffer)).WithMetrics(metrics).WithStringDictionary(NewStringDictionary(10))
+	logger.Info("Payment failed.", nil)
+	logger.Info("Payment failed.", nil)
+	profiles := metrics.MessageProfiles()
+	if len(profiles) != 1 || profiles[0].Message != "Payment failed." || profiles[0].Count != 2 {
+		t.Errorf("The message should be profiled by its text, got %+v.", profiles)
+	}
+}
+
+// TestPercentile tests the nearest-rank percentiles.
+func TestPercentile(t *testing.T) {
+	sorted := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for p, expected := range map[int]int{0: 1, 50: 5, 95: 10, 99: 10, 100: 10} {
+		if actual := percentile(sorted, p); actual != expected {
+			t.Errorf("Percentile %d is %d but should be %d.", p, actual, expected)
+		}
+	}
+	if percentile(nil, 50) != 0 {
+		t.Errorf("The percentile of no values should be 0.")
+	}
+}
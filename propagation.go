@@ -0,0 +1,102 @@
+package jsonlog
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPropagationHints bounds the number of goroutines remembered by the
+// propagation diagnostics. The hints are forgotten when it is reached.
+const maxPropagationHints = 4096
+
+// propagationDiagnosticKey is the context key under which records bypassing
+// a context-bound Logger are tagged.
+const propagationDiagnosticKey = "bypassedContextLogger"
+
+var (
+	// propagationDiagnostics is 1 when propagation diagnostics are enabled.
+	propagationDiagnostics int32
+	// propagationHints holds the IDs of the goroutines which had a Logger
+	// in a context.
+	propagationHints      = map[uint64]struct{}{}
+	propagationHintsMutex sync.Mutex
+)
+
+// SetPropagationDiagnostics enables or disables the propagation diagnostics.
+// When enabled, goroutines which stored a Logger in a context or retrieved
+// one from it are remembered, and the records they later emit through
+// DefaultLogger, either with the package-level logging functions or after
+// LoggerFromContextOrDefault fell back to it, are tagged with the caller's
+// file and line under "bypassedContextLogger" in their context. These are
+// the places where the request Logger was available but not used.
+//
+// The diagnostics cost a stack capture per hinted call and are meant for
+// development and tests. A goroutine which had a Logger in a context is only
+// a hint: a goroutine started from it is not known to have one. They are
+// disabled by default.
+func SetPropagationDiagnostics(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&propagationDiagnostics, value)
+	if !enabled {
+		propagationHintsMutex.Lock()
+		propagationHints = map[uint64]struct{}{}
+		propagationHintsMutex.Unlock()
+	}
+}
+
+// hintLoggerAvailable remembers that the current goroutine had a Logger in a
+// context, if propagation diagnostics are enabled.
+func hintLoggerAvailable() {
+	if atomic.LoadInt32(&propagationDiagnostics) == 0 {
+		return
+	}
+	id := goroutineID()
+	propagationHintsMutex.Lock()
+	defer propagationHintsMutex.Unlock()
+	if len(propagationHints) >= maxPropagationHints {
+		propagationHints = map[uint64]struct{}{}
+	}
+	propagationHints[id] = struct{}{}
+}
+
+// checkedDefaultLogger returns DefaultLogger, tagged with the caller of its
+// own caller if propagation diagnostics are enabled and the current
+// goroutine had a Logger in a context.
+func checkedDefaultLogger() Logger {
+	if atomic.LoadInt32(&propagationDiagnostics) == 0 {
+		return DefaultLogger
+	}
+	id := goroutineID()
+	propagationHintsMutex.Lock()
+	_, hinted := propagationHints[id]
+	propagationHintsMutex.Unlock()
+	if !hinted {
+		return DefaultLogger
+	}
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return DefaultLogger
+	}
+	return DefaultLogger.WithStaticFields(map[string]interface{}{
+		propagationDiagnosticKey: file + ":" + strconv.Itoa(line),
+	})
+}
+
+// goroutineID returns the ID of the current goroutine, read from the header
+// of its stack trace.
+func goroutineID() uint64 {
+	var buffer [64]byte
+	header := buffer[:runtime.Stack(buffer[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if end := bytes.IndexByte(header, ' '); end >= 0 {
+		header = header[:end]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
@@ -0,0 +1,63 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestPropagationDiagnostics tests that records emitted through DefaultLogger
+// by a goroutine which had a context-bound Logger are tagged.
+func TestPropagationDiagnostics(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	previous := DefaultLogger
+	DefaultLogger = DefaultLogger.WithWriter(buffer)
+	defer func() {
+		DefaultLogger = previous
+		SetPropagationDiagnostics(false)
+	}()
+	ctx := ContextWithLogger(context.Background(), DefaultLogger.Named("request"))
+	Info("Not tagged while disabled.", nil)
+	SetPropagationDiagnostics(true)
+	Info("Not tagged without a hint.", nil)
+	LoggerFromContext(ctx)
+	Info("Tagged.", nil)
+	Err(errors.New("declined"), "Tagged error.", nil)
+	func() {
+		defer func() { recover() }()
+		Panic("Tagged panic.", nil)
+	}()
+	AssertTrue(false, "Tagged assertion.", nil)
+	AssertNoError(errors.New("corrupt"), "Tagged invariant.", nil)
+	LoggerFromContextOrDefault(context.Background()).Info("Tagged after a fallback.", nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Info("Not tagged in another goroutine.", nil)
+	}()
+	<-done
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		tagged := strings.Contains(line, `"bypassedContextLogger":"`)
+		if expected := strings.Contains(line, `"message":"Tagged`); tagged != expected {
+			t.Errorf("Record '%s' should be tagged: %v.", line, expected)
+		}
+		if tagged && !strings.Contains(line, "propagation_test.go:") {
+			t.Errorf("Record '%s' should name the caller.", line)
+		}
+	}
+}
+
+// TestGoroutineID tests that goroutines get distinct IDs.
+func TestGoroutineID(t *testing.T) {
+	id := goroutineID()
+	if id == 0 {
+		t.Errorf("The goroutine ID should be found.")
+	}
+	other := make(chan uint64)
+	go func() { other <- goroutineID() }()
+	if otherID := <-other; otherID == id || otherID == 0 {
+		t.Errorf("Goroutine IDs %d and %d should differ.", id, otherID)
+	}
+}
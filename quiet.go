@@ -0,0 +1,61 @@
+package jsonlog
+
+import (
+	"context"
+)
+
+// QuietLogger logs like a Logger but its methods return no error, so that
+// call sites need not ignore it explicitly. Failures are still reported to
+// the handler set with OnError and records are still written to the fallback
+// writer, if any.
+type QuietLogger struct {
+	logger Logger
+}
+
+// Quiet returns a QuietLogger logging through the Logger.
+func (l Logger) Quiet() QuietLogger {
+	return QuietLogger{logger: l}
+}
+
+// Logger returns the Logger the QuietLogger logs through.
+func (q QuietLogger) Logger() Logger {
+	return q.logger
+}
+
+// WithError returns a new QuietLogger attaching `err' to its messages, as
+// with Logger.WithError.
+func (q QuietLogger) WithError(err error) QuietLogger {
+	return QuietLogger{logger: q.logger.WithError(err)}
+}
+
+// WithContext returns a new QuietLogger using `ctx', as with
+// Logger.WithContext.
+func (q QuietLogger) WithContext(ctx context.Context) QuietLogger {
+	return QuietLogger{logger: q.logger.WithContext(ctx)}
+}
+
+// Named returns a new QuietLogger with a name nested under the current one,
+// as with Logger.Named.
+func (q QuietLogger) Named(name string) QuietLogger {
+	return QuietLogger{logger: q.logger.Named(name)}
+}
+
+// Log logs with the given level.
+func (q QuietLogger) Log(logLevel LogLevel, str string, data interface{}) {
+	q.logger.Log(logLevel, str, data)
+}
+
+// Debug is a shorthand for logging with level Debug.
+func (q QuietLogger) Debug(str string, data interface{}) { q.logger.Debug(str, data) }
+
+// Info is a shorthand for logging with level Info.
+func (q QuietLogger) Info(str string, data interface{}) { q.logger.Info(str, data) }
+
+// Warning is a shorthand for logging with level Warning.
+func (q QuietLogger) Warning(str string, data interface{}) { q.logger.Warning(str, data) }
+
+// Error is a shorthand for logging with level Error.
+func (q QuietLogger) Error(str string, data interface{}) { q.logger.Error(str, data) }
+
+// Err is a shorthand for logging `err' with level Error.
+func (q QuietLogger) Err(err error, str string, data interface{}) { q.logger.Err(err, str, data) }
@@ -0,0 +1,25 @@
+package jsonlog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestQuietLogger tests that a QuietLogger logs and reports failures to the
+// error handler.
+func TestQuietLogger(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	quiet := DefaultLogger.WithWriter(buffer).Quiet().Named("billing")
+	quiet.WithError(errors.New("timeout")).Error("charge failed", nil)
+	if !strings.Contains(buffer.String(), `"logger":"billing"`) || !strings.Contains(buffer.String(), "timeout") {
+		t.Errorf("Output '%s' should hold the name and the error.", buffer.String())
+	}
+	var reported error
+	failing := DefaultLogger.WithWriter(failingWriter{}).OnError(func(err error, m Message) { reported = err }).Quiet()
+	failing.Info("lost", nil)
+	if reported == nil {
+		t.Errorf("The write failure should have been reported to the handler.")
+	}
+}
@@ -0,0 +1,69 @@
+package jsonlog
+
+import "time"
+
+// MaxErrorRateWindow is the longest window ErrorRate can look back over.
+const MaxErrorRateWindow = 10 * time.Minute
+
+// rateBuckets is the number of one-second buckets kept for ErrorRate.
+const rateBuckets = int(MaxErrorRateWindow / time.Second)
+
+// rateBucket counts the messages written during one second.
+type rateBucket struct {
+	second int64
+	total  uint64
+	errors uint64
+}
+
+// ErrorRate returns the ratio of the messages at the error level or above to
+// all the messages written over the last `window', between 0 and 1, so that
+// health checks can take the error log rate into account. The window is
+// rounded up to the second and capped at MaxErrorRateWindow. It returns 0 if
+// no message was written during the window.
+func (m *Metrics) ErrorRate(window time.Duration) float64 {
+	if window > MaxErrorRateWindow {
+		window = MaxErrorRateWindow
+	}
+	seconds := int64((window + time.Second - 1) / time.Second)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := m.now().Unix()
+	var total, errors uint64
+	for _, bucket := range m.rates {
+		if bucket.second > now-seconds && bucket.second <= now {
+			total += bucket.total
+			errors += bucket.errors
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(errors) / float64(total)
+}
+
+// ErrorRate returns the ratio of error messages to all messages written over
+// the last `window', as counted by the Metrics of the Logger. It returns 0 if
+// the Logger has no Metrics.
+func (l Logger) ErrorRate(window time.Duration) float64 {
+	if l.metrics == nil {
+		return 0
+	}
+	return l.metrics.ErrorRate(window)
+}
+
+// countRate counts a message written in the bucket of the current second.
+// The mutex must be held.
+func (m *Metrics) countRate(logLevel LogLevel) {
+	if m.rates == nil {
+		m.rates = make([]rateBucket, rateBuckets)
+	}
+	now := m.now().Unix()
+	bucket := &m.rates[now%int64(rateBuckets)]
+	if bucket.second != now {
+		*bucket = rateBucket{second: now}
+	}
+	bucket.total++
+	if logLevel.rank() >= LogLevelError.rank() {
+		bucket.errors++
+	}
+}
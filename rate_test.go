@@ -0,0 +1,45 @@
+package jsonlog
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// TestErrorRate tests the ratio of error messages over windows of time.
+func TestErrorRate(t *testing.T) {
+	metrics := NewMetrics()
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	metrics.now = func() time.Time { return now }
+	logger := DefaultLogger.WithWriter(io.Discard).WithMetrics(metrics)
+	if rate := logger.ErrorRate(time.Minute); rate != 0 {
+		t.Errorf("The rate without messages is %f but should be 0.", rate)
+	}
+	logger.Error("old failure", nil)
+	logger.Info("old success", nil)
+	now = now.Add(2 * time.Minute)
+	logger.Info("success", nil)
+	logger.Info("success", nil)
+	logger.Warning("warning", nil)
+	logger.Error("failure", nil)
+	for _, example := range []struct {
+		window   time.Duration
+		expected float64
+	}{
+		{time.Second, 0.25},
+		{time.Minute, 0.25},
+		{3 * time.Minute, 2.0 / 6.0},
+		{time.Hour, 2.0 / 6.0},
+	} {
+		if rate := logger.ErrorRate(example.window); rate != example.expected {
+			t.Errorf("The rate over %s is %f but should be %f.", example.window, rate, example.expected)
+		}
+	}
+	now = now.Add(MaxErrorRateWindow + 3*time.Minute)
+	if rate := logger.ErrorRate(MaxErrorRateWindow); rate != 0 {
+		t.Errorf("Messages older than the window should not count, got %f.", rate)
+	}
+	if rate := DefaultLogger.ErrorRate(time.Minute); rate != 0 {
+		t.Errorf("A Logger without metrics should have a rate of 0, got %f.", rate)
+	}
+}
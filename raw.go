@@ -0,0 +1,57 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidRawJSON is returned by ValidatedRawJSON for data which is not
+// valid JSON.
+var ErrInvalidRawJSON = errors.New("jsonlog: invalid raw JSON")
+
+// RawMessage is an already-encoded JSON value. The default JSONFormatter
+// writes it verbatim, without decoding and encoding it again, so it must be
+// valid JSON; only line breaks are removed so that the record stays on a
+// single line. Other formatters decode it like any other value.
+type RawMessage []byte
+
+// MarshalJSON returns the raw message itself.
+func (r RawMessage) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// RawJSON returns a Data holding already-encoded JSON, such as a webhook
+// body, under `key'. The data is trusted: if it may be invalid, use
+// ValidatedRawJSON instead. More fields can be added with Data.Set.
+//
+//	logger.Info("Webhook received.", jsonlog.RawJSON("body", body).Set("source", "github"))
+func RawJSON(key string, data []byte) Data {
+	return NewData().Set(key, RawMessage(data))
+}
+
+// ValidatedRawJSON is like RawJSON but checks that the data is valid JSON
+// first.
+func ValidatedRawJSON(key string, data []byte) (Data, error) {
+	if !json.Valid(data) {
+		return nil, ErrInvalidRawJSON
+	}
+	return RawJSON(key, data), nil
+}
+
+// appendRawMessage appends a raw message to `buffer', compacting it if it
+// spans several lines.
+func appendRawMessage(buffer *bytes.Buffer, r RawMessage) error {
+	if len(r) == 0 {
+		buffer.WriteString("null")
+		return nil
+	}
+	if bytes.IndexAny(r, "\r\n") < 0 {
+		buffer.Write(r)
+		return nil
+	}
+	return json.Compact(buffer, r)
+}
@@ -0,0 +1,46 @@
+package jsonlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type testRawJSONExample struct {
+	formatter Formatter
+	expected  string
+}
+
+// TestRawJSON tests that raw JSON is output verbatim by the JSON formatter,
+// kept on a single line, and decoded by the other formatters.
+func TestRawJSON(t *testing.T) {
+	body := []byte("{\n  \"action\": \"opened\",\n  \"number\": 12\n}")
+	examples := []testRawJSONExample{
+		testRawJSONExample{JSONFormatter{}, `"data":{"body":{"action":"opened","number":12},"source":"github"}`},
+		testRawJSONExample{JSONFormatter{TimeLayout: TimeLayoutEpochMillis}, `"data":{"body":{"action":"opened","number":12},"source":"github"}`},
+		testRawJSONExample{LogfmtFormatter{}, `data.body.action=opened data.body.number=12 data.source=github`},
+	}
+	for _, example := range examples {
+		buffer := new(bytes.Buffer)
+		logger := DefaultLogger.WithWriter(buffer).WithFormatter(example.formatter)
+		if err := logger.Info("Webhook received.", RawJSON("body", body).Set("source", "github")); err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+		} else if !strings.Contains(buffer.String(), example.expected) {
+			t.Errorf("Output '%s' should contain '%s'.", buffer.String(), example.expected)
+		} else if strings.Count(buffer.String(), "\n") != 1 {
+			t.Errorf("Output '%s' should be a single line.", buffer.String())
+		}
+	}
+}
+
+// TestValidatedRawJSON tests that invalid raw JSON is rejected.
+func TestValidatedRawJSON(t *testing.T) {
+	if _, err := ValidatedRawJSON("body", []byte(`{"truncated":`)); err != ErrInvalidRawJSON {
+		t.Errorf("Invalid JSON should be rejected, got %v.", err)
+	}
+	if data, err := ValidatedRawJSON("body", []byte(`[1,2]`)); err != nil {
+		t.Errorf("Validating errored with '%s'.", err.Error())
+	} else if string(data["body"].(RawMessage)) != `[1,2]` {
+		t.Errorf("Data should hold the raw JSON, got %v.", data)
+	}
+}
@@ -0,0 +1,207 @@
+// Package reader provides read-side tooling for jsonlog output: decoding
+// records back from a stream and extracting fields from them.
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// maxLineSize bounds the size of a single record the Decoder accepts.
+	maxLineSize = 64 * 1024 * 1024
+	// staticRefKey and staticKeysKey are the context keys of the records
+	// of a Logger in delta mode, as defined by jsonlog.WithDeltaRecords.
+	staticRefKey  = "staticRef"
+	staticKeysKey = "staticKeys"
+	// dictionaryKey and dictionaryPrefix are the context key of the
+	// definitions of a jsonlog.StringDictionary and the prefix of its
+	// references.
+	dictionaryKey    = "dictionary"
+	dictionaryPrefix = "@"
+)
+
+// LineError reports a line of the input which could not be decoded. Decoding
+// can go on with the next line.
+type LineError struct {
+	// Line is the number of the line, starting at 1.
+	Line int
+	Err  error
+}
+
+// Error describes the line and the decoding error.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+// Unwrap returns the decoding error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Record is a decoded jsonlog record. Numbers are kept as json.Number so that
+// they are not rounded.
+type Record map[string]interface{}
+
+// Decoder reads newline-delimited jsonlog records from an io.Reader.
+//
+// Records written in delta mode, with jsonlog.WithDeltaRecords, are
+// rehydrated: records referencing static fields output in full earlier in the
+// stream get them back, and the "staticRef" and "staticKeys" context keys are
+// removed. Records whose reference is unknown, because the stream was cut,
+// keep their "staticRef". Likewise, the references of a
+// jsonlog.StringDictionary are expanded in the message and logger name.
+type Decoder struct {
+	scanner *bufio.Scanner
+	line    int
+	// staticFields maps the references of static fields to their values.
+	staticFields map[string]map[string]interface{}
+	// strings maps the references of dictionary strings to their values.
+	strings map[string]string
+}
+
+// NewDecoder creates a Decoder reading from `r'.
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &Decoder{scanner: scanner}
+}
+
+// Decode returns the next record. Blank lines are skipped. A line which cannot
+// be decoded is reported with a *LineError, after which Decode can be called
+// again. It returns io.EOF once the input is exhausted.
+func (d *Decoder) Decode() (Record, error) {
+	line, err := d.nextLine()
+	if err != nil {
+		return nil, err
+	}
+	return d.decodeLine(line)
+}
+
+// nextLine returns the next non-blank line of the input.
+func (d *Decoder) nextLine() ([]byte, error) {
+	for d.scanner.Scan() {
+		d.line++
+		line := d.scanner.Bytes()
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
+		}
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// decodeLine decodes a single line into a Record.
+func (d *Decoder) decodeLine(line []byte) (Record, error) {
+	record, err := decodeRecord(line)
+	if err != nil {
+		return nil, &LineError{Line: d.line, Err: err}
+	}
+	d.rehydrate(record)
+	d.expandStrings(record)
+	return record, nil
+}
+
+// expandStrings learns the dictionary strings a record defines and expands
+// the references of its message and logger name.
+func (d *Decoder) expandStrings(record Record) {
+	if context, ok := record["context"].(map[string]interface{}); ok {
+		if definitions, ok := context[dictionaryKey].(map[string]interface{}); ok {
+			if d.strings == nil {
+				d.strings = map[string]string{}
+			}
+			for ref, value := range definitions {
+				if value, ok := value.(string); ok {
+					d.strings[ref] = value
+				}
+			}
+			delete(context, dictionaryKey)
+			if len(context) == 0 {
+				delete(record, "context")
+			}
+		}
+	}
+	if d.strings == nil {
+		return
+	}
+	for _, key := range []string{"message", "logger"} {
+		value, ok := record[key].(string)
+		if !ok || !strings.HasPrefix(value, dictionaryPrefix) {
+			continue
+		}
+		if strings.HasPrefix(value[1:], dictionaryPrefix) {
+			record[key] = value[1:]
+		} else if expanded, ok := d.strings[value]; ok {
+			record[key] = expanded
+		}
+	}
+}
+
+// rehydrate restores the static fields of a record written in delta mode, or
+// learns them from a record carrying them in full.
+func (d *Decoder) rehydrate(record Record) {
+	context, ok := record["context"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	ref, ok := context[staticRefKey].(string)
+	if !ok {
+		return
+	}
+	if keys, ok := context[staticKeysKey].([]interface{}); ok {
+		fields := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			if key, ok := key.(string); ok {
+				fields[key] = context[key]
+			}
+		}
+		if d.staticFields == nil {
+			d.staticFields = map[string]map[string]interface{}{}
+		}
+		d.staticFields[ref] = fields
+	} else if fields, ok := d.staticFields[ref]; ok {
+		for key, value := range fields {
+			if _, ok := context[key]; !ok {
+				context[key] = value
+			}
+		}
+	} else {
+		return
+	}
+	delete(context, staticRefKey)
+	delete(context, staticKeysKey)
+}
+
+// decodeRecord decodes a single JSON object into a Record.
+func decodeRecord(line []byte) (Record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	record := Record{}
+	if err := decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Lookup finds the value at a dot-separated path such as "data.user.id". The
+// boolean is false if the path does not exist in the record.
+func (r Record) Lookup(path string) (interface{}, bool) {
+	var current interface{} = map[string]interface{}(r)
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
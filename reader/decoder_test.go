@@ -0,0 +1,117 @@
+package reader
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testInput = `{"level":"info","message":"first","data":{"user":{"id":42}}}
+
+{"level":"error","message":"second","context":{"runId":"abc"}}
+`
+
+// TestDecoder tests decoding records and skipping blank lines.
+func TestDecoder(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(testInput))
+	expected := []string{"first", "second"}
+	for _, e := range expected {
+		record, err := decoder.Decode()
+		if err != nil {
+			t.Errorf("Decoding errored with '%s'.", err.Error())
+			return
+		}
+		if record["message"] != e {
+			t.Errorf("Record message '%v' should be '%s'.", record["message"], e)
+		}
+	}
+	if _, err := decoder.Decode(); err != io.EOF {
+		t.Errorf("Decoding past the end returned '%v' but should return io.EOF.", err)
+	}
+}
+
+// TestDecoderError tests that decoding errors report the line number.
+func TestDecoderError(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("{}\nnot json\n"))
+	decoder.Decode()
+	_, err := decoder.Decode()
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("Decoding error '%v' should mention line 2.", err)
+	}
+}
+
+type testLookupExample struct {
+	path  string
+	value interface{}
+	found bool
+}
+
+// TestLookup tests finding values by dot-separated paths.
+func TestLookup(t *testing.T) {
+	examples := []testLookupExample{
+		testLookupExample{"message", "first", true},
+		testLookupExample{"data.user.id", json.Number("42"), true},
+		testLookupExample{"data.user.name", nil, false},
+		testLookupExample{"message.sub", nil, false},
+	}
+	record, _ := NewDecoder(strings.NewReader(testInput)).Decode()
+	for _, example := range examples {
+		value, found := record.Lookup(example.path)
+		if found != example.found || value != example.value {
+			t.Errorf("Lookup of '%s' returned %v, %v but should return %v, %v.", example.path, value, found, example.value, example.found)
+		}
+	}
+}
+
+const testDeltaInput = `{"message":"full","context":{"requestId":"a","service":"billing","staticKeys":["service"],"staticRef":"x1"}}
+{"message":"delta","context":{"requestId":"b","staticRef":"x1"}}
+{"message":"unknown","context":{"staticRef":"x2"}}
+`
+
+// TestDecoderRehydration tests that records in delta mode get their static
+// fields back.
+func TestDecoderRehydration(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(testDeltaInput))
+	expected := []string{
+		`{"requestId":"a","service":"billing"}`,
+		`{"requestId":"b","service":"billing"}`,
+		`{"staticRef":"x2"}`,
+	}
+	for _, e := range expected {
+		record, err := decoder.Decode()
+		if err != nil {
+			t.Errorf("Decoding errored with '%s'.", err.Error())
+			return
+		}
+		context, _ := json.Marshal(record["context"])
+		if string(context) != e {
+			t.Errorf("Context '%s' should be '%s'.", context, e)
+		}
+	}
+}
+
+const testDictionaryInput = `{"message":"@0","logger":"@1","context":{"dictionary":{"@0":"Payment failed.","@1":"billing"}}}
+{"message":"@0","logger":"@1","context":{"requestId":"a"}}
+{"message":"@@mention","logger":"@1"}
+`
+
+// TestDecoderDictionary tests that dictionary references are expanded.
+func TestDecoderDictionary(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(testDictionaryInput))
+	expected := []string{
+		`{"logger":"billing","message":"Payment failed."}`,
+		`{"context":{"requestId":"a"},"logger":"billing","message":"Payment failed."}`,
+		`{"logger":"billing","message":"@mention"}`,
+	}
+	for _, e := range expected {
+		record, err := decoder.Decode()
+		if err != nil {
+			t.Errorf("Decoding errored with '%s'.", err.Error())
+			return
+		}
+		if encoded, _ := json.Marshal(record); string(encoded) != e {
+			t.Errorf("Record '%s' should be '%s'.", encoded, e)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package reader
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// ExportOptions configures ExportCSV.
+type ExportOptions struct {
+	// Fields are the dot-separated paths of the values to export, one
+	// column each.
+	Fields []string
+	// Comma is the column separator; use '\t' for TSV. It defaults to ','.
+	Comma rune
+	// Null is written for fields which are missing or null.
+	Null string
+	// NoHeader disables the header line listing the fields.
+	NoHeader bool
+}
+
+// ExportCSV reads every record from `d' and writes the selected fields to `w'
+// as CSV. Strings, numbers and booleans are written as is; objects and arrays
+// are written as JSON.
+func ExportCSV(w io.Writer, d *Decoder, options ExportOptions) error {
+	writer := csv.NewWriter(w)
+	if options.Comma != 0 {
+		writer.Comma = options.Comma
+	}
+	if !options.NoHeader {
+		if err := writer.Write(options.Fields); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(options.Fields))
+	for {
+		record, err := d.Decode()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		for i, field := range options.Fields {
+			value, _ := record.Lookup(field)
+			if row[i], err = csvValue(value, options.Null); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue converts a decoded JSON value to a CSV cell.
+func csvValue(value interface{}, null string) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return null, nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	default:
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	}
+}
@@ -0,0 +1,34 @@
+package reader
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestExportCSV tests exporting selected fields as CSV and TSV.
+func TestExportCSV(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	err := ExportCSV(buffer, NewDecoder(strings.NewReader(testInput)), ExportOptions{
+		Fields: []string{"level", "data.user", "context.runId"},
+		Null:   "NULL",
+	})
+	expected := "level,data.user,context.runId\ninfo,\"{\"\"id\"\":42}\",NULL\nerror,NULL,abc\n"
+	if err != nil {
+		t.Errorf("Exporting errored with '%s'.", err.Error())
+	} else if buffer.String() != expected {
+		t.Errorf("Output '%s' should be '%s'.", buffer.String(), expected)
+	}
+	buffer.Reset()
+	err = ExportCSV(buffer, NewDecoder(strings.NewReader(testInput)), ExportOptions{
+		Fields:   []string{"message", "level"},
+		Comma:    '\t',
+		NoHeader: true,
+	})
+	expected = "first\tinfo\nsecond\terror\n"
+	if err != nil {
+		t.Errorf("Exporting errored with '%s'.", err.Error())
+	} else if buffer.String() != expected {
+		t.Errorf("Output '%s' should be '%s'.", buffer.String(), expected)
+	}
+}
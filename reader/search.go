@@ -0,0 +1,281 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// searchBufferSize is the initial size of the chunks a Searcher scans.
+const searchBufferSize = 1024 * 1024
+
+// ErrLineTooLong is returned by a Searcher meeting a line longer than the
+// maximum record size.
+var ErrLineTooLong = errors.New("reader: line too long")
+
+// searchMarkers are found in the lines written in delta mode or with a string
+// dictionary, which must be decoded whether or not they contain the literal,
+// so that the records referencing them can be rehydrated.
+var searchMarkers = []string{`"` + staticRefKey + `":`, `"` + dictionaryKey + `":`, `"` + dictionaryPrefix}
+
+// Searcher finds the records containing a literal in a stream of jsonlog
+// records. The input is scanned in large chunks with a Boyer-Moore search on
+// the raw bytes, and only the lines containing the literal are decoded, which
+// makes searching large archives much faster than decoding every record.
+//
+// The literal is matched against the encoded JSON: a literal containing
+// characters which JSON escapes, such as quotes, must be given escaped.
+//
+// Records written in delta mode or with a string dictionary are decoded with
+// a Decoder, so that they are rehydrated, and matched once re-encoded: they
+// are found even when the literal is in their static fields or in a
+// dictionary string.
+type Searcher struct {
+	r       io.Reader
+	literal []byte
+	// patterns are those of the literal, then of the searchMarkers.
+	patterns []searchPattern
+	decoder  *Decoder
+	buffer   []byte
+	start    int
+	end      int
+	eof      bool
+	// lines is the number of lines before start.
+	lines int
+	line  []byte
+}
+
+// searchPattern is a pattern searched by a Searcher, with the index of its
+// next occurrence in the buffer.
+type searchPattern struct {
+	finder *finder
+	// next is the index of the next occurrence at or after the start of
+	// the buffer, or -1 if there is none before its end. It is only valid
+	// while known is true, until the buffer is refilled.
+	next  int
+	known bool
+}
+
+// NewSearcher creates a Searcher reading from `r' and looking for `literal'.
+func NewSearcher(r io.Reader, literal []byte) *Searcher {
+	s := &Searcher{
+		r:       r,
+		literal: literal,
+		decoder: &Decoder{},
+		buffer:  make([]byte, searchBufferSize),
+	}
+	s.patterns = append(s.patterns, searchPattern{finder: newFinder(literal)})
+	for _, marker := range searchMarkers {
+		s.patterns = append(s.patterns, searchPattern{finder: newFinder([]byte(marker))})
+	}
+	return s
+}
+
+// Next returns the next record containing the literal. A line which cannot be
+// decoded is reported with a *LineError, after which Next can be called again
+// to go on with the search. It returns io.EOF once the input is exhausted.
+func (s *Searcher) Next() (Record, error) {
+	for {
+		line, number, err := s.nextLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		s.decoder.line = number
+		record, err := s.decoder.decodeLine(line)
+		if err != nil {
+			return nil, err
+		}
+		if !s.hasMarker(line) {
+			s.line = line
+			return record, nil
+		}
+		var encoded bytes.Buffer
+		encoder := json.NewEncoder(&encoded)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(record); err != nil {
+			return nil, &LineError{Line: number, Err: err}
+		}
+		if rehydrated := bytes.TrimSuffix(encoded.Bytes(), []byte("\n")); bytes.Contains(rehydrated, s.literal) {
+			s.line = rehydrated
+			return record, nil
+		}
+	}
+}
+
+// Line returns the bytes of the last record returned by Next: the raw line,
+// or the re-encoded record, with sorted keys, if it was rehydrated. They are
+// only valid until the next call to Next.
+func (s *Searcher) Line() []byte {
+	return s.line
+}
+
+// hasMarker tells whether a line contains one of the searchMarkers.
+func (s *Searcher) hasMarker(line []byte) bool {
+	for _, marker := range searchMarkers {
+		if bytes.Contains(line, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextLine returns the next line containing the literal or a marker, with its
+// number.
+func (s *Searcher) nextLine() ([]byte, int, error) {
+	for {
+		if s.start == s.end && s.eof {
+			return nil, 0, io.EOF
+		}
+		if match := s.nextMatch(); match >= 0 {
+			s.advance(s.start + bytes.LastIndexByte(s.buffer[s.start:match], '\n') + 1)
+			lineStart, number := s.start, s.lines+1
+			newline := bytes.IndexByte(s.buffer[match:s.end], '\n')
+			if newline >= 0 {
+				s.advance(match + newline + 1)
+				return s.buffer[lineStart : match+newline], number, nil
+			} else if s.eof {
+				s.start = s.end
+				return s.buffer[lineStart:s.end], number, nil
+			}
+		} else if s.eof {
+			return nil, 0, io.EOF
+		} else if newline := bytes.LastIndexByte(s.buffer[s.start:s.end], '\n'); newline >= 0 {
+			s.advance(s.start + newline + 1)
+		}
+		if err := s.fill(); err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// nextMatch returns the index in the buffer of the first occurrence of a
+// pattern after the start, or -1 if there is none. Occurrences are searched
+// again only once passed, so that scanning a chunk stays linear.
+func (s *Searcher) nextMatch() int {
+	match := -1
+	for i := range s.patterns {
+		p := &s.patterns[i]
+		if !p.known || (p.next >= 0 && p.next < s.start) {
+			p.next = p.finder.next(s.buffer[s.start:s.end])
+			if p.next >= 0 {
+				p.next += s.start
+			}
+			p.known = true
+		}
+		if p.next >= 0 && (match < 0 || p.next < match) {
+			match = p.next
+		}
+	}
+	return match
+}
+
+// advance moves the start of the unprocessed data to `start', counting the
+// lines passed.
+func (s *Searcher) advance(start int) {
+	s.lines += bytes.Count(s.buffer[s.start:start], []byte("\n"))
+	s.start = start
+}
+
+// fill moves the unprocessed data to the beginning of the buffer and reads
+// more input after it, growing the buffer if a line does not fit.
+func (s *Searcher) fill() error {
+	for i := range s.patterns {
+		s.patterns[i].known = false
+	}
+	copy(s.buffer, s.buffer[s.start:s.end])
+	s.end -= s.start
+	s.start = 0
+	if s.end == len(s.buffer) {
+		if len(s.buffer) >= maxLineSize {
+			return ErrLineTooLong
+		}
+		grown := make([]byte, 2*len(s.buffer))
+		copy(grown, s.buffer[:s.end])
+		s.buffer = grown
+	}
+	n, err := s.r.Read(s.buffer[s.end:])
+	s.end += n
+	if err == io.EOF {
+		s.eof = true
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// finder searches for a pattern using the Boyer-Moore algorithm.
+type finder struct {
+	pattern []byte
+	// badCharSkip maps a byte to how far the pattern can be shifted
+	// when that byte is found in the text at the pattern's last position.
+	badCharSkip [256]int
+	// goodSuffixSkip maps an index of the pattern to how far it can be
+	// shifted when a mismatch happens at that index after the suffix
+	// following it matched.
+	goodSuffixSkip []int
+}
+
+// newFinder builds the skip tables for `pattern'.
+func newFinder(pattern []byte) *finder {
+	f := &finder{
+		pattern:        pattern,
+		goodSuffixSkip: make([]int, len(pattern)),
+	}
+	last := len(pattern) - 1
+	for i := range f.badCharSkip {
+		f.badCharSkip[i] = len(pattern)
+	}
+	for i := 0; i < last; i++ {
+		f.badCharSkip[pattern[i]] = last - i
+	}
+	lastPrefix := last
+	for i := last; i >= 0; i-- {
+		if bytes.HasPrefix(pattern, pattern[i+1:]) {
+			lastPrefix = i + 1
+		}
+		f.goodSuffixSkip[i] = lastPrefix + last - i
+	}
+	for i := 0; i < last; i++ {
+		suffixLength := commonSuffixLength(pattern, pattern[1:i+1])
+		if pattern[i-suffixLength] != pattern[last-suffixLength] {
+			f.goodSuffixSkip[last-suffixLength] = suffixLength + last - i
+		}
+	}
+	return f
+}
+
+// next returns the index of the first occurrence of the pattern in `text', or
+// -1 if there is none.
+func (f *finder) next(text []byte) int {
+	i := len(f.pattern) - 1
+	for i < len(text) {
+		j := len(f.pattern) - 1
+		for j >= 0 && text[i] == f.pattern[j] {
+			i--
+			j--
+		}
+		if j < 0 {
+			return i + 1
+		}
+		skip := f.badCharSkip[text[i]]
+		if f.goodSuffixSkip[j] > skip {
+			skip = f.goodSuffixSkip[j]
+		}
+		i += skip
+	}
+	return -1
+}
+
+// commonSuffixLength returns the length of the longest common suffix of `a'
+// and `b'.
+func commonSuffixLength(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[len(a)-1-i] == b[len(b)-1-i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,114 @@
+package reader
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// TestFinder tests the Boyer-Moore search against bytes.Index on random
+// inputs over a small alphabet, which makes partial matches frequent.
+func TestFinder(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	randomBytes := func(n int) []byte {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = "abc"[random.Intn(3)]
+		}
+		return b
+	}
+	for i := 0; i < 2000; i++ {
+		pattern := randomBytes(1 + random.Intn(6))
+		text := randomBytes(random.Intn(40))
+		if got, expected := newFinder(pattern).next(text), bytes.Index(text, pattern); got != expected {
+			t.Errorf("Searching '%s' in '%s' returned %d but should return %d.", pattern, text, got, expected)
+		}
+	}
+}
+
+const testSearchInput = `{"message":"a","data":{"requestId":"r-1"}}
+{"message":"b","data":{"requestId":"r-2"}}
+
+{"message":"c","data":{"requestId":"r-1"}}
+{"message":"d","data":{"requestId":"r-10"}}`
+
+type testSearcherExample struct {
+	literal  string
+	expected []string
+}
+
+// TestSearcher tests finding records, including with a buffer small enough
+// that lines and matches straddle chunks.
+func TestSearcher(t *testing.T) {
+	examples := []testSearcherExample{
+		testSearcherExample{`"r-1"`, []string{"a", "c"}},
+		testSearcherExample{`r-1`, []string{"a", "c", "d"}},
+		testSearcherExample{`requestId`, []string{"a", "b", "c", "d"}},
+		testSearcherExample{`r-3`, nil},
+	}
+	for _, bufferSize := range []int{searchBufferSize, 7} {
+		for _, example := range examples {
+			searcher := NewSearcher(strings.NewReader(testSearchInput), []byte(example.literal))
+			searcher.buffer = make([]byte, bufferSize)
+			var found []string
+			for {
+				record, err := searcher.Next()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Errorf("Searching errored with '%s'.", err.Error())
+					break
+				}
+				if !bytes.Contains(searcher.Line(), []byte(example.literal)) {
+					t.Errorf("Line '%s' should contain '%s'.", searcher.Line(), example.literal)
+				}
+				found = append(found, record["message"].(string))
+			}
+			if strings.Join(found, ",") != strings.Join(example.expected, ",") {
+				t.Errorf("Searching '%s' with a %d bytes buffer found %v but should find %v.", example.literal, bufferSize, found, example.expected)
+			}
+		}
+	}
+}
+
+const testSearchRehydrationInput = `{"message":"@0","context":{"dictionary":{"@0":"Payment failed."},"staticRef":"x1","staticKeys":["service"],"service":"billing"}}
+{"message":"Refund issued.","context":{"staticRef":"x1"}}
+{"message":"Payment failed.","data":{"requestId":
+{"message":"@0","context":{"staticRef":"x1","requestId":"r-1"}}
+`
+
+// TestSearcherRehydration tests that records written in delta mode or with a
+// string dictionary are found and returned rehydrated, and that the search
+// goes on after a line which cannot be decoded.
+func TestSearcherRehydration(t *testing.T) {
+	searcher := NewSearcher(strings.NewReader(testSearchRehydrationInput), []byte(`"Payment failed."`))
+	var found []string
+	var lineErrors []int
+	for {
+		_, err := searcher.Next()
+		var lineErr *LineError
+		if err == io.EOF {
+			break
+		} else if errors.As(err, &lineErr) {
+			lineErrors = append(lineErrors, lineErr.Line)
+			continue
+		} else if err != nil {
+			t.Errorf("Searching errored with '%s'.", err.Error())
+			break
+		}
+		found = append(found, string(searcher.Line()))
+	}
+	expected := []string{
+		`{"context":{"service":"billing"},"message":"Payment failed."}`,
+		`{"context":{"requestId":"r-1","service":"billing"},"message":"Payment failed."}`,
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Searching found %q but should find %q.", found, expected)
+	}
+	if len(lineErrors) != 1 || lineErrors[0] != 3 {
+		t.Errorf("The undecodable line should be reported as line 3, got %v.", lineErrors)
+	}
+}
@@ -0,0 +1,75 @@
+package jsonlog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"os"
+)
+
+// replayBufferSize is the size of the buffer records are read into by
+// Replay. Longer records are assembled in a separate buffer.
+const replayBufferSize = 64 << 10
+
+// Replay copies the records read from `src', such as an archive, to `dst',
+// and returns the number of bytes read.
+//
+// Files and network connections receive the stream as it is, through
+// io.Copy: the io.WriterTo of `src' or the io.ReaderFrom of `dst' is used,
+// which on Linux copies between files and sockets with sendfile or splice
+// without going through user space. Other writers implementing
+// io.ReaderFrom, such as UnixWriter, are trusted to do the same. Every other
+// writer receives exactly one record per call, to WriteRecord for a
+// RecordWriter and to Write otherwise, so that sinks treating each Write as
+// a record or a batch, such as BatchingSink or HTTPSink, never receive a
+// partial record. Records are passed from a reused buffer, without
+// allocations.
+func Replay(dst io.Writer, src io.Reader) (int64, error) {
+	switch dst.(type) {
+	case *os.File, net.Conn:
+		return io.Copy(dst, src)
+	}
+	if readerFrom, ok := dst.(io.ReaderFrom); ok {
+		return readerFrom.ReadFrom(src)
+	}
+	return copyRecords(dst, src)
+}
+
+// copyRecords writes the lines of `src' to `dst', one per Write, or one per
+// WriteRecord without their newline if `dst' is a RecordWriter. A last line
+// without a newline is written as it is.
+func copyRecords(dst io.Writer, src io.Reader) (int64, error) {
+	recordWriter, _ := dst.(RecordWriter)
+	reader := bufio.NewReaderSize(src, replayBufferSize)
+	var read int64
+	var long []byte
+	for {
+		line, err := reader.ReadSlice('\n')
+		read += int64(len(line))
+		if err == bufio.ErrBufferFull {
+			long = append(long, line...)
+			continue
+		}
+		if len(long) > 0 {
+			line = append(long, line...)
+			long = long[:0]
+		}
+		if len(line) > 0 {
+			var writeErr error
+			if recordWriter != nil {
+				writeErr = recordWriter.WriteRecord(bytes.TrimSuffix(line, []byte("\n")))
+			} else {
+				_, writeErr = dst.Write(line)
+			}
+			if writeErr != nil {
+				return read, writeErr
+			}
+		}
+		if err == io.EOF {
+			return read, nil
+		} else if err != nil {
+			return read, err
+		}
+	}
+}
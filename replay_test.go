@@ -0,0 +1,104 @@
+package jsonlog
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chunkWriter keeps each Write as a separate record.
+type chunkWriter struct {
+	records []string
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.records = append(w.records, string(p))
+	return len(p), nil
+}
+
+// TestReplayRecords tests that writers which are not streams get one record
+// per Write, including records longer than the read buffer.
+func TestReplayRecords(t *testing.T) {
+	long := `{"message":"` + strings.Repeat("x", 2*replayBufferSize) + `"}` + "\n"
+	archive := `{"message":"first"}` + "\n" + long + `{"message":"last"}`
+	w := &chunkWriter{}
+	n, err := Replay(w, strings.NewReader(archive))
+	if err != nil {
+		t.Errorf("Replaying errored with '%s'.", err.Error())
+	}
+	if n != int64(len(archive)) {
+		t.Errorf("Replay read %d bytes but should have read %d.", n, len(archive))
+	}
+	expected := []string{`{"message":"first"}` + "\n", long, `{"message":"last"}`}
+	if len(w.records) != len(expected) {
+		t.Fatalf("There should be %d records, got %d.", len(expected), len(w.records))
+	}
+	for i := range expected {
+		if w.records[i] != expected[i] {
+			t.Errorf("Record %d has %d bytes but should have %d.", i, len(w.records[i]), len(expected[i]))
+		}
+	}
+	if _, err := Replay(failingWriter{}, strings.NewReader(archive)); err == nil {
+		t.Errorf("Replaying to a failing writer should error.")
+	}
+}
+
+// TestReplayStream tests that files and UnixWriters receive the archive as a
+// stream.
+func TestReplayStream(t *testing.T) {
+	directory := t.TempDir()
+	archive := strings.Repeat(`{"level":"info","message":"replayed"}`+"\n", 1000)
+	source := filepath.Join(directory, "archive.log")
+	if err := os.WriteFile(source, []byte(archive), 0644); err != nil {
+		t.Fatalf("Writing the archive errored with '%s'.", err.Error())
+	}
+	path := filepath.Join(directory, "jsonlog.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Listening errored with '%s'.", err.Error())
+	}
+	defer listener.Close()
+	received := make(chan string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		var buffer bytes.Buffer
+		io.Copy(&buffer, conn)
+		received <- buffer.String()
+	}()
+	w, err := NewUnixWriter(path)
+	if err != nil {
+		t.Fatalf("Connecting errored with '%s'.", err.Error())
+	}
+	f, err := os.Open(source)
+	if err != nil {
+		t.Fatalf("Opening the archive errored with '%s'.", err.Error())
+	}
+	defer f.Close()
+	if n, err := Replay(w, f); err != nil || n != int64(len(archive)) {
+		t.Errorf("Replay copied %d bytes with error %v, expected %d.", n, err, len(archive))
+	}
+	w.Close()
+	if output := <-received; output != archive {
+		t.Errorf("The receiver got %d bytes but should have got %d.", len(output), len(archive))
+	}
+	destination, err := os.Create(filepath.Join(directory, "copy.log"))
+	if err != nil {
+		t.Fatalf("Creating the copy errored with '%s'.", err.Error())
+	}
+	defer destination.Close()
+	f.Seek(0, io.SeekStart)
+	if _, err := Replay(destination, f); err != nil {
+		t.Errorf("Replaying to a file errored with '%s'.", err.Error())
+	}
+	if copied, _ := os.ReadFile(destination.Name()); string(copied) != archive {
+		t.Errorf("The copy has %d bytes but should have %d.", len(copied), len(archive))
+	}
+}
@@ -0,0 +1,94 @@
+package jsonlog
+
+import (
+	"sync"
+	"time"
+)
+
+// SamplingPolicy describes how a Sampler thins out identical messages of one
+// log level: within each tick the first `First' messages are logged, then
+// only every `Thereafter'-th one. A zero `Thereafter' drops every message past
+// the first `First'.
+type SamplingPolicy struct {
+	First      int
+	Thereafter int
+}
+
+// Sampler limits the rate of repetitive messages so that an error storm does
+// not flood the sinks. Messages are considered identical when they have the
+// same log level and the same `str'. The first message logged after some were
+// dropped carries the number of dropped messages in its "repeated" field.
+//
+// A Sampler is safe for concurrent use and can be shared by several Loggers.
+type Sampler struct {
+	tick        time.Duration
+	policies    map[LogLevel]SamplingPolicy
+	mutex       sync.Mutex
+	windowStart time.Time
+	counters    map[samplerKey]*samplerCounter
+}
+
+// samplerKey identifies a family of identical messages.
+type samplerKey struct {
+	logLevel LogLevel
+	message  string
+}
+
+// samplerCounter tracks a family of identical messages within the current
+// tick.
+type samplerCounter struct {
+	seen    int
+	dropped int
+}
+
+// NewSampler creates a Sampler using the given policies for each log level.
+// Log levels absent from `policies' are never sampled.
+func NewSampler(tick time.Duration, policies map[LogLevel]SamplingPolicy) *Sampler {
+	return &Sampler{
+		tick:     tick,
+		policies: policies,
+		counters: map[samplerKey]*samplerCounter{},
+	}
+}
+
+// sample decides whether a message should be logged. When it should, it also
+// returns how many identical messages were dropped since the last one logged.
+func (s *Sampler) sample(logLevel LogLevel, str string, now time.Time) (bool, int) {
+	policy, ok := s.policies[logLevel]
+	if !ok {
+		return true, 0
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if now.Sub(s.windowStart) >= s.tick {
+		s.resetWindow(now)
+	}
+	key := samplerKey{logLevel, str}
+	counter := s.counters[key]
+	if counter == nil {
+		counter = &samplerCounter{}
+		s.counters[key] = counter
+	}
+	counter.seen++
+	if counter.seen <= policy.First ||
+		(policy.Thereafter > 0 && (counter.seen-policy.First)%policy.Thereafter == 0) {
+		dropped := counter.dropped
+		counter.dropped = 0
+		return true, dropped
+	}
+	counter.dropped++
+	return false, 0
+}
+
+// resetWindow starts a new tick. Counters are forgotten, except for the
+// number of dropped messages which has yet to be reported.
+func (s *Sampler) resetWindow(now time.Time) {
+	s.windowStart = now
+	for key, counter := range s.counters {
+		if counter.dropped == 0 {
+			delete(s.counters, key)
+		} else {
+			counter.seen = 0
+		}
+	}
+}
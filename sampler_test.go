@@ -0,0 +1,33 @@
+package jsonlog
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSampler tests that a Sampler logs the first messages of a tick, then
+// every Thereafter-th, and reports the dropped count.
+func TestSampler(t *testing.T) {
+	sampler := NewSampler(time.Second, map[LogLevel]SamplingPolicy{
+		LogLevelError: SamplingPolicy{First: 2, Thereafter: 3},
+	})
+	now := time.Now()
+	expected := []bool{true, true, false, false, true, false, false, true}
+	for i, e := range expected {
+		sampled, repeated := sampler.sample(LogLevelError, "storm", now)
+		if sampled != e {
+			t.Errorf("Message %d sampled is %v but should be %v.", i, sampled, e)
+		}
+		if sampled && i > 2 && repeated != 2 {
+			t.Errorf("Message %d repeated count is %d but should be 2.", i, repeated)
+		}
+	}
+	if sampled, _ := sampler.sample(LogLevelInfo, "storm", now); !sampled {
+		t.Errorf("Levels without a policy should not be sampled.")
+	}
+	sampler.sample(LogLevelError, "storm", now)
+	sampled, repeated := sampler.sample(LogLevelError, "storm", now.Add(time.Second))
+	if !sampled || repeated != 1 {
+		t.Errorf("First message of a new tick should be logged with a repeated count of 1, got %v and %d.", sampled, repeated)
+	}
+}
@@ -0,0 +1,174 @@
+package jsonlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// ErrShutdownTimeout is the error of a sink which did not drain in time.
+var ErrShutdownTimeout = errors.New("jsonlog: sink shutdown timed out")
+
+// SinkStage is the position of a sink in the shutdown order. Sinks which
+// feed others are drained first, so that what they hold still reaches the
+// final destination.
+type SinkStage int
+
+const (
+	// AutoStage chooses the stage from the type of the sink. Unknown types
+	// are drained with network sinks, last.
+	AutoStage SinkStage = iota
+	// MemoryStage is for in-memory queues, such as BatchingSink,
+	// PipelinedSink and WriterChain.
+	MemoryStage
+	// DiskStage is for files and on-disk queues.
+	DiskStage
+	// NetworkStage is for network sinks, such as HTTPSink, SyslogWriter,
+	// GELFWriter and UnixWriter.
+	NetworkStage
+)
+
+// String returns the name of the stage.
+func (s SinkStage) String() string {
+	switch s {
+	case MemoryStage:
+		return "memory"
+	case DiskStage:
+		return "disk"
+	case NetworkStage:
+		return "network"
+	default:
+		return "auto"
+	}
+}
+
+// ShutdownSink is a sink to drain on shutdown.
+type ShutdownSink struct {
+	// Name identifies the sink in the report.
+	Name string
+	// Sink is flushed or synced, then closed if it implements io.Closer.
+	// An *os.File is only synced, since it is often the standard output.
+	Sink io.Writer
+	// Stage is the position of the sink in the shutdown order.
+	Stage SinkStage
+	// Timeout bounds the time given to the sink, on top of the deadline
+	// of the context. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// SinkShutdownResult is the outcome of the shutdown of a sink.
+type SinkShutdownResult struct {
+	Name  string
+	Stage SinkStage
+	// Flushed is true if the sink was drained and closed without error.
+	Flushed bool
+	// Abandoned is true if the sink did not drain in time. Whatever it
+	// still held is lost, and it may still be draining in the background.
+	Abandoned bool
+	// Err is the error of the flush or the close, or ErrShutdownTimeout.
+	Err      error
+	Duration time.Duration
+}
+
+// ShutdownReport tells which sinks were flushed and which were abandoned, in
+// the order they were shut down.
+type ShutdownReport struct {
+	Sinks []SinkShutdownResult
+}
+
+// Err returns the first error of the shutdown, if any.
+func (r ShutdownReport) Err() error {
+	for _, sink := range r.Sinks {
+		if sink.Err != nil {
+			return sink.Err
+		}
+	}
+	return nil
+}
+
+// Abandoned returns the names of the sinks which did not drain in time.
+func (r ShutdownReport) Abandoned() []string {
+	var names []string
+	for _, sink := range r.Sinks {
+		if sink.Abandoned {
+			names = append(names, sink.Name)
+		}
+	}
+	return names
+}
+
+// Shutdown drains and closes the sinks stage by stage: in-memory queues,
+// then disk queues, then network sinks, so that records buffered upstream
+// are handed down before the sinks below them are closed. Within a stage,
+// sinks are shut down in the order given. A sink which exceeds its timeout
+// or the deadline of `ctx' is abandoned and the shutdown moves on.
+func Shutdown(ctx context.Context, sinks ...ShutdownSink) ShutdownReport {
+	ordered := make([]ShutdownSink, len(sinks))
+	copy(ordered, sinks)
+	for i := range ordered {
+		if ordered[i].Stage == AutoStage {
+			ordered[i].Stage = sinkStage(ordered[i].Sink)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Stage < ordered[j].Stage
+	})
+	report := ShutdownReport{Sinks: make([]SinkShutdownResult, 0, len(ordered))}
+	for _, sink := range ordered {
+		report.Sinks = append(report.Sinks, shutdownSink(ctx, sink))
+	}
+	return report
+}
+
+// shutdownSink drains a single sink within its timeout.
+func shutdownSink(ctx context.Context, sink ShutdownSink) SinkShutdownResult {
+	result := SinkShutdownResult{Name: sink.Name, Stage: sink.Stage}
+	start := time.Now()
+	if sink.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sink.Timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- drainSink(sink.Sink)
+	}()
+	select {
+	case result.Err = <-done:
+		result.Flushed = result.Err == nil
+	case <-ctx.Done():
+		result.Abandoned = true
+		result.Err = ErrShutdownTimeout
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// drainSink flushes or syncs `w', then closes it unless it is a file.
+func drainSink(w io.Writer) error {
+	err := syncWriter(w)
+	if _, ok := w.(*os.File); ok {
+		return err
+	}
+	if c, ok := w.(io.Closer); ok {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// sinkStage chooses the stage of a sink from its type.
+func sinkStage(w io.Writer) SinkStage {
+	switch w.(type) {
+	case *BatchingSink, *PipelinedSink, *WriterChain:
+		return MemoryStage
+	case *os.File:
+		return DiskStage
+	default:
+		return NetworkStage
+	}
+}
@@ -0,0 +1,67 @@
+package jsonlog
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// blockingSink never finishes closing.
+type blockingSink struct {
+	writeRecorder
+}
+
+func (b *blockingSink) Close() error {
+	select {}
+}
+
+// TestShutdownOrder tests that upstream queues are drained into the network
+// sink before it is closed.
+func TestShutdownOrder(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+	httpSink := newTestHTTPSink(t, HTTPSinkConfig{URL: server.URL})
+	pipeline, _ := NewPipelinedSink(httpSink, PipelineConfig{Depth: 4})
+	batches := NewBatchingSink(pipeline, 0, 0)
+	batches.Write([]byte("{\"message\":\"buffered\"}\n"))
+	report := Shutdown(context.Background(),
+		ShutdownSink{Name: "http", Sink: httpSink},
+		ShutdownSink{Name: "batches", Sink: batches},
+		ShutdownSink{Name: "pipeline", Sink: pipeline, Stage: MemoryStage},
+		ShutdownSink{Name: "stdout", Sink: os.Stdout},
+	)
+	if err := report.Err(); err != nil {
+		t.Errorf("Shutting down errored with '%s'.", err.Error())
+	}
+	expected := []string{"batches", "pipeline", "stdout", "http"}
+	for i, result := range report.Sinks {
+		if result.Name != expected[i] || !result.Flushed {
+			t.Errorf("Sink %d: expected %s to be flushed, got %+v.", i, expected[i], result)
+		}
+	}
+	if len(c.bodies) != 1 || c.bodies[0] != "{\"message\":\"buffered\"}\n" {
+		t.Errorf("The buffered record was not delivered: %v.", c.bodies)
+	}
+}
+
+// TestShutdownTimeout tests that a sink which does not drain in time is
+// abandoned without blocking the others.
+func TestShutdownTimeout(t *testing.T) {
+	last := &writeRecorder{}
+	report := Shutdown(context.Background(),
+		ShutdownSink{Name: "stuck", Sink: &blockingSink{}, Stage: MemoryStage, Timeout: 10 * time.Millisecond},
+		ShutdownSink{Name: "last", Sink: last},
+	)
+	if abandoned := report.Abandoned(); len(abandoned) != 1 || abandoned[0] != "stuck" {
+		t.Errorf("Expected the stuck sink to be abandoned, got %v.", abandoned)
+	}
+	if report.Err() != ErrShutdownTimeout {
+		t.Errorf("Expected a timeout error, got %v.", report.Err())
+	}
+	if !report.Sinks[1].Flushed {
+		t.Errorf("The last sink should have been flushed.")
+	}
+}
@@ -0,0 +1,121 @@
+package jsonlog
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+)
+
+// WithStaticFields returns a new Logger whose messages carry the given
+// fields in their context, in addition to those already set. The fields are
+// copied; context values extracted with WithContextKey take precedence over
+// static fields with the same key.
+func (l Logger) WithStaticFields(fields map[string]interface{}) Logger {
+	staticFields := make(map[string]interface{}, len(l.staticFields)+len(fields))
+	for key, value := range l.staticFields {
+		staticFields[key] = value
+	}
+	for key, value := range fields {
+		staticFields[key] = value
+	}
+	l.staticFields = staticFields
+	l.staticRef = staticFieldsRef(staticFields)
+	return l
+}
+
+// WithFieldsFromStruct returns a new Logger whose messages carry the fields
+// of the struct `v', or of the struct `v' points to, in their context, as
+// WithStaticFields does. The struct is converted once, following its json
+// tags like encoding/json does, so that later changes to it are not seen.
+// WithFieldsFromStruct panics if `v' is not a struct, or is cyclic.
+//
+//	type buildInfo struct {
+//		Service string `json:"service"`
+//		Commit  string `json:"commit,omitempty"`
+//	}
+//	logger = logger.WithFieldsFromStruct(buildInfo{"billing", commit})
+func (l Logger) WithFieldsFromStruct(v interface{}) Logger {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("jsonlog: WithFieldsFromStruct needs a struct, got %T", v))
+	}
+	fields := map[string]interface{}{}
+	w := walker{float: func(f float64) interface{} { return f }}
+	if err := guardWalk(func() { w.walkStruct(value, fields) }); err != nil {
+		panic(fmt.Sprintf("jsonlog: WithFieldsFromStruct cannot convert %T: %s", v, err.Error()))
+	}
+	return l.WithStaticFields(fields)
+}
+
+// WithProcessInfo returns a new Logger whose messages carry the host name and
+// the process ID under the "host" and "pid" context keys. Both are read once,
+// when WithProcessInfo is called.
+func (l Logger) WithProcessInfo() Logger {
+	host, _ := os.Hostname()
+	return l.WithStaticFields(map[string]interface{}{
+		"host": host,
+		"pid":  os.Getpid(),
+	})
+}
+
+// WithService returns a new Logger whose messages carry the name and version
+// of the service under the "service" and "version" context keys.
+func (l Logger) WithService(name, version string) Logger {
+	return l.WithStaticFields(map[string]interface{}{
+		"service": name,
+		"version": version,
+	})
+}
+
+// WithGoVersion returns a new Logger whose messages carry the version of the
+// Go runtime under the "goVersion" context key.
+func (l Logger) WithGoVersion() Logger {
+	return l.WithStaticFields(map[string]interface{}{
+		"goVersion": runtime.Version(),
+	})
+}
+
+// addStaticFields adds the Logger's static fields, with their reference in
+// delta mode, its correlation ID and a record ID to the context of a
+// message.
+func (l Logger) addStaticFields(m *Message) {
+	if len(l.staticFields) == 0 && l.correlationID == "" && !l.recordIDs {
+		return
+	}
+	if m.Context == nil {
+		m.Context = make(map[string]interface{}, len(l.staticFields)+2)
+	}
+	if l.delta == nil || l.staticRef == "" {
+		l.setStaticFields(m)
+	} else {
+		// Whether the fields are output in full is decided when the
+		// message is written.
+		m.Context[StaticRefKey] = l.staticRef
+		m.staticKeys = l.setStaticFields(m)
+	}
+	if l.correlationID != "" {
+		m.Context[correlationIDKey] = l.correlationID
+	}
+	if l.recordIDs {
+		m.Context[recordIDKey] = l.newRecordID()
+	}
+}
+
+// setStaticFields sets the static fields in the context of a message, unless
+// it already has values for their keys. It returns the keys it set.
+func (l Logger) setStaticFields(m *Message) []string {
+	var keys []string
+	for key, value := range l.staticFields {
+		if _, ok := m.Context[key]; !ok {
+			m.Context[key] = value
+			if l.delta != nil {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
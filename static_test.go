@@ -0,0 +1,74 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// TestStaticFields tests that process and service information is added to
+// the context of every message.
+func TestStaticFields(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	ctx := context.WithValue(context.Background(), "version", "from-context")
+	base := DefaultLogger.WithWriter(buffer).WithProcessInfo().WithService("billing", "1.4.2").WithGoVersion()
+	base.Info("started", nil)
+	base.WithContext(ctx).WithContextKey("version", "version").Info("overridden", nil)
+	host, _ := os.Hostname()
+	decoder := json.NewDecoder(buffer)
+	var first, second Message
+	if err := decoder.Decode(&first); err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		return
+	}
+	decoder.Decode(&second)
+	expected := map[string]interface{}{
+		"host":      host,
+		"pid":       float64(os.Getpid()),
+		"service":   "billing",
+		"version":   "1.4.2",
+		"goVersion": runtime.Version(),
+	}
+	for key, value := range expected {
+		if first.Context[key] != value {
+			t.Errorf("Context %s is %v instead of %v.", key, first.Context[key], value)
+		}
+	}
+	if second.Context["version"] != "from-context" {
+		t.Errorf("Context values should take precedence, got %v.", second.Context["version"])
+	}
+}
+
+// testAppMetadata is a block of static metadata with json tags.
+type testAppMetadata struct {
+	Service string `json:"service"`
+	Region  string `json:"region,omitempty"`
+	Build   struct {
+		Commit string `json:"commit"`
+	} `json:"build"`
+	Internal string `json:"-"`
+	secret   string
+}
+
+// TestWithFieldsFromStruct tests binding the fields of a tagged struct.
+func TestWithFieldsFromStruct(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	metadata := &testAppMetadata{Service: "billing", Internal: "hidden", secret: "hidden"}
+	metadata.Build.Commit = "abc123"
+	logger := DefaultLogger.WithWriter(buffer).WithFieldsFromStruct(metadata)
+	metadata.Service = "changed"
+	logger.Info("started", nil)
+	expected := `"context":{"build":{"commit":"abc123"},"service":"billing"}`
+	if !bytes.Contains(buffer.Bytes(), []byte(expected)) {
+		t.Errorf("Output '%s' should contain '%s'.", buffer.String(), expected)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("WithFieldsFromStruct should panic on a map.")
+		}
+	}()
+	DefaultLogger.WithFieldsFromStruct(map[string]string{})
+}
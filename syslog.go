@@ -0,0 +1,248 @@
+package jsonlog
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogFacility is a syslog facility code, as defined by RFC 5424.
+type SyslogFacility int
+
+// SyslogSeverity is a syslog severity code, as defined by RFC 5424.
+type SyslogSeverity int
+
+const (
+	SyslogFacilityUser   = SyslogFacility(1)
+	SyslogFacilityDaemon = SyslogFacility(3)
+)
+
+const (
+	SyslogFacilityLocal0 = SyslogFacility(16 + iota)
+	SyslogFacilityLocal1
+	SyslogFacilityLocal2
+	SyslogFacilityLocal3
+	SyslogFacilityLocal4
+	SyslogFacilityLocal5
+	SyslogFacilityLocal6
+	SyslogFacilityLocal7
+)
+
+const (
+	SyslogSeverityEmergency = SyslogSeverity(iota)
+	SyslogSeverityAlert
+	SyslogSeverityCritical
+	SyslogSeverityError
+	SyslogSeverityWarning
+	SyslogSeverityNotice
+	SyslogSeverityInformational
+	SyslogSeverityDebug
+)
+
+var (
+	// syslogSeverities maps predefined log levels to syslog severities.
+	syslogSeverities = map[LogLevel]SyslogSeverity{
+		LogLevelDebug:   SyslogSeverityDebug,
+		LogLevelInfo:    SyslogSeverityInformational,
+		LogLevelWarning: SyslogSeverityWarning,
+		LogLevelError:   SyslogSeverityError,
+		LogLevelPanic:   SyslogSeverityCritical,
+		LogLevelFatal:   SyslogSeverityAlert,
+	}
+
+	// localSyslogAddresses are the usual paths of the local syslog socket.
+	localSyslogAddresses = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+	// ErrNoLocalSyslog is returned when no local syslog socket could be
+	// found.
+	ErrNoLocalSyslog = errors.New("jsonlog: no local syslog socket found")
+)
+
+// SyslogWriter sends records to a local or remote syslog daemon, formatted
+// according to RFC 5424. The JSON record is sent as the MSG part of the syslog
+// message and the log level is mapped to the syslog severity. If a write
+// fails, the connection is reestablished and the write retried once.
+//
+// A SyslogWriter is safe for concurrent use.
+type SyslogWriter struct {
+	network  string
+	address  string
+	facility SyslogFacility
+	appName  string
+	hostname string
+	pid      string
+	mutex    sync.Mutex
+	conn     net.Conn
+	// connNetwork is the network of `conn', which decides the framing.
+	connNetwork string
+	dialer      *networkDialer
+}
+
+// NewSyslogWriter connects to a syslog daemon. An empty `network' connects to
+// the local daemon through its Unix socket; otherwise `network' and `address'
+// are passed to net.Dial, e.g. "udp" and "logs.example.com:514". Messages over
+// TCP are framed with octet counting as per RFC 6587.
+func NewSyslogWriter(network, address, appName string, facility SyslogFacility) (*SyslogWriter, error) {
+	return NewSyslogWriterWithNetwork(network, address, appName, facility, NetworkConfig{})
+}
+
+// NewSyslogWriterWithNetwork is like NewSyslogWriter, connecting to a remote
+// daemon over TCP as configured by `config', for example with TLS as per RFC
+// 5425.
+func NewSyslogWriterWithNetwork(network, address, appName string, facility SyslogFacility, config NetworkConfig) (*SyslogWriter, error) {
+	dialer, err := config.dialer()
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	w := &SyslogWriter{
+		network:  network,
+		address:  address,
+		facility: facility,
+		appName:  syslogHeaderField(appName),
+		hostname: syslogHeaderField(hostname),
+		pid:      strconv.Itoa(os.Getpid()),
+		dialer:   dialer,
+	}
+	if dialer.lazy {
+		return w, nil
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write sends a record with the informational severity.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(LogLevelInfo, p)
+}
+
+// WriteLevel sends a record with the severity matching `logLevel'. Levels
+// without a predefined mapping are sent with the notice severity.
+func (w *SyslogWriter) WriteLevel(logLevel LogLevel, p []byte) (int, error) {
+	severity, ok := syslogSeverities[logLevel]
+	if !ok {
+		severity = SyslogSeverityNotice
+	}
+	message := w.format(severity, time.Now(), bytes.TrimRight(p, "\n"))
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn != nil {
+		if _, err := w.conn.Write(w.frame(message)); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return 0, err
+	}
+	if _, err := w.conn.Write(w.frame(message)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Ping checks the connection to the syslog daemon, reconnecting if it was
+// closed or never established.
+func (w *SyslogWriter) Ping() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn != nil {
+		if connAlive(w.conn) {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return w.connect()
+}
+
+// Close closes the connection to the syslog daemon.
+func (w *SyslogWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// connect establishes the connection to the syslog daemon.
+func (w *SyslogWriter) connect() error {
+	if w.network != "" {
+		conn, err := w.dialer.Dial(w.network, w.address)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		w.connNetwork = w.network
+		return nil
+	}
+	for _, address := range localSyslogAddresses {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, address); err == nil {
+				w.conn = conn
+				w.connNetwork = network
+				return nil
+			}
+		}
+	}
+	return ErrNoLocalSyslog
+}
+
+// format builds an RFC 5424 syslog message.
+func (w *SyslogWriter) format(severity SyslogSeverity, now time.Time, msg []byte) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteByte('<')
+	buffer.WriteString(strconv.Itoa(int(w.facility)*8 + int(severity)))
+	buffer.WriteString(">1 ")
+	buffer.WriteString(now.Format("2006-01-02T15:04:05.000000Z07:00"))
+	buffer.WriteByte(' ')
+	buffer.WriteString(w.hostname)
+	buffer.WriteByte(' ')
+	buffer.WriteString(w.appName)
+	buffer.WriteByte(' ')
+	buffer.WriteString(w.pid)
+	buffer.WriteString(" - - ")
+	buffer.Write(msg)
+	return buffer.Bytes()
+}
+
+// frame prepares a message for the transport of the current connection:
+// datagrams are sent as is, TCP streams use octet counting and local stream
+// sockets a trailing newline.
+func (w *SyslogWriter) frame(message []byte) []byte {
+	if strings.HasPrefix(w.connNetwork, "tcp") {
+		return append([]byte(strconv.Itoa(len(message))+" "), message...)
+	} else if w.connNetwork == "unix" {
+		return append(message, '\n')
+	}
+	return message
+}
+
+// syslogHeaderField makes a string usable as an RFC 5424 header field, which
+// must be printable ASCII without spaces, or "-" when empty.
+func syslogHeaderField(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package jsonlog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestSyslogWriterUDP tests that records are sent as RFC 5424 datagrams with
+// the severity matching their level.
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on UDP: %s", err.Error())
+	}
+	defer conn.Close()
+	writer, err := NewSyslogWriter("udp", conn.LocalAddr().String(), "my app", SyslogFacilityLocal0)
+	if err != nil {
+		t.Errorf("Creating the syslog writer errored with '%s'.", err.Error())
+		return
+	}
+	defer writer.Close()
+	logger := DefaultLogger.WithWriter(writer)
+	if err := logger.Warning("disk almost full", nil); err != nil {
+		t.Errorf("Logging errored with '%s'.", err.Error())
+		return
+	}
+	buffer := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buffer)
+	if err != nil {
+		t.Errorf("Reading the datagram errored with '%s'.", err.Error())
+		return
+	}
+	datagram := string(buffer[:n])
+	if !strings.HasPrefix(datagram, "<132>1 ") {
+		t.Errorf("Datagram '%s' should start with priority 132 and version 1.", datagram)
+	}
+	fields := strings.SplitN(datagram, " ", 8)
+	if len(fields) != 8 || fields[3] != "my_app" || fields[5] != "-" || fields[6] != "-" {
+		t.Errorf("Datagram '%s' has a malformed header.", datagram)
+	} else if !strings.HasPrefix(fields[7], `{"level":"warning"`) || strings.HasSuffix(fields[7], "\n") {
+		t.Errorf("Datagram message '%s' should be the JSON record.", fields[7])
+	}
+}
+
+// TestSyslogWriterTCP tests that records sent over TCP use octet counting.
+func TestSyslogWriterTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on TCP: %s", err.Error())
+	}
+	defer listener.Close()
+	writer, err := NewSyslogWriter("tcp", listener.Addr().String(), "app", SyslogFacilityUser)
+	if err != nil {
+		t.Errorf("Creating the syslog writer errored with '%s'.", err.Error())
+		return
+	}
+	defer writer.Close()
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Errorf("Accepting errored with '%s'.", err.Error())
+		return
+	}
+	defer conn.Close()
+	DefaultLogger.WithWriter(writer).Error("failure", nil)
+	reader := bufio.NewReader(conn)
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		t.Errorf("Reading the frame length errored with '%s'.", err.Error())
+		return
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		t.Errorf("Frame length '%s' is not a number.", length)
+		return
+	}
+	message := make([]byte, n)
+	if _, err := io.ReadFull(reader, message); err != nil {
+		t.Errorf("Reading the frame errored with '%s'.", err.Error())
+	} else if !strings.HasPrefix(string(message), "<11>1 ") || !strings.HasSuffix(string(message), "}") {
+		t.Errorf("Message '%s' should have priority 11 and end with the JSON record.", message)
+	}
+}
+
+// TestSyslogWriterLazy tests that a lazy writer can be created while the
+// daemon is down, and that Ping restores a lost connection.
+func TestSyslogWriterLazy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on TCP: %s", err.Error())
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	if _, err := NewSyslogWriter("tcp", address, "app", SyslogFacilityUser); err == nil {
+		t.Errorf("An eager writer should fail to connect to a closed port.")
+	}
+	writer, err := NewSyslogWriterWithNetwork("tcp", address, "app", SyslogFacilityUser, NetworkConfig{Lazy: true})
+	if err != nil {
+		t.Errorf("Creating a lazy writer errored with '%s'.", err.Error())
+		return
+	}
+	defer writer.Close()
+	if err := writer.Ping(); err == nil {
+		t.Errorf("Ping should fail while the daemon is down.")
+	}
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skipf("Cannot listen on %s again: %s", address, err.Error())
+	}
+	defer listener.Close()
+	if err := writer.Ping(); err != nil {
+		t.Errorf("Ping errored with '%s'.", err.Error())
+	}
+	conn, _ := listener.Accept()
+	conn.Close()
+	if err := writer.Ping(); err != nil {
+		t.Errorf("Ping should reconnect, but errored with '%s'.", err.Error())
+	}
+	conn, _ = listener.Accept()
+	conn.Close()
+}
@@ -0,0 +1,47 @@
+package jsonlog
+
+import (
+	"time"
+)
+
+const (
+	// TimeLayoutEpochMillis is a special time layout for formatters which
+	// outputs times as the number of milliseconds since the Unix epoch.
+	TimeLayoutEpochMillis = "epochmillis"
+	// TimeLayoutEpochSeconds is a special time layout for formatters which
+	// outputs times as the number of seconds since the Unix epoch, with a
+	// fractional part.
+	TimeLayoutEpochSeconds = "epochseconds"
+)
+
+// WithClock returns a new Logger taking the time of its messages from `clock'
+// instead of time.Now, for example to produce deterministic output in tests.
+// A nil clock restores time.Now.
+func (l Logger) WithClock(clock func() time.Time) Logger {
+	l.clock = clock
+	return l
+}
+
+// WithTimeLocation returns a new Logger whose messages have their time in the
+// given time zone, such as time.UTC. A nil location keeps the clock's zone.
+func (l Logger) WithTimeLocation(location *time.Location) Logger {
+	l.location = location
+	return l
+}
+
+// formatTime formats a time for output according to a formatter's time
+// layout. Layouts are those of time.Format, plus TimeLayoutEpochMillis and
+// TimeLayoutEpochSeconds which yield numbers. An empty layout means
+// time.RFC3339Nano.
+func formatTime(t time.Time, layout string) interface{} {
+	switch layout {
+	case "":
+		return t.Format(time.RFC3339Nano)
+	case TimeLayoutEpochMillis:
+		return t.UnixNano() / int64(time.Millisecond)
+	case TimeLayoutEpochSeconds:
+		return float64(t.UnixNano()) / float64(time.Second)
+	default:
+		return t.Format(layout)
+	}
+}
@@ -0,0 +1,53 @@
+package jsonlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testTimeLayoutExample struct {
+	formatter Formatter
+	expected  string
+}
+
+// TestClockAndTimeLayouts tests deterministic output with an injected clock,
+// a time zone and the supported time layouts.
+func TestClockAndTimeLayouts(t *testing.T) {
+	instant := time.Date(2017, 10, 5, 19, 7, 58, 115000000, time.UTC)
+	paris := time.FixedZone("CEST", 2*60*60)
+	examples := []testTimeLayoutExample{
+		testTimeLayoutExample{
+			JSONFormatter{},
+			`{"level":"info","time":"2017-10-05T21:07:58.115+02:00","message":"tick"}` + "\n",
+		},
+		testTimeLayoutExample{
+			JSONFormatter{TimeLayout: TimeLayoutEpochMillis},
+			`"time":1507230478115`,
+		},
+		testTimeLayoutExample{
+			JSONFormatter{TimeLayout: TimeLayoutEpochSeconds},
+			`"time":1507230478.115`,
+		},
+		testTimeLayoutExample{
+			LogfmtFormatter{TimeLayout: "2006-01-02 15:04"},
+			`time="2017-10-05 21:07"`,
+		},
+		testTimeLayoutExample{
+			LogfmtFormatter{TimeLayout: TimeLayoutEpochSeconds},
+			`time=1507230478.115 `,
+		},
+	}
+	for _, example := range examples {
+		buffer := new(bytes.Buffer)
+		logger := DefaultLogger.WithWriter(buffer).WithFormatter(example.formatter)
+		logger = logger.WithClock(func() time.Time { return instant }).WithTimeLocation(paris)
+		err := logger.Info("tick", nil)
+		if err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+		} else if !strings.Contains(buffer.String(), example.expected) {
+			t.Errorf("Output '%s' should contain '%s'.", buffer.String(), example.expected)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package jsonlog
+
+import (
+	"reflect"
+	"time"
+)
+
+const (
+	// elapsedKey is the data key of the duration logged by Timed, in
+	// milliseconds.
+	elapsedKey = "elapsed" + durationSuffix
+	// timedValueKey is the data key of data which is not a map, when
+	// logged by Timed.
+	timedValueKey = "value"
+)
+
+// Timed starts timing an operation and returns a function logging `str' at
+// the given level when called, with the time elapsed in between as a number
+// of milliseconds under "elapsed_ms" in the data. Map data is copied with the
+// field added; other non-nil data is output under "value".
+//
+//	done := logger.Timed(jsonlog.LogLevelInfo, "db.query", map[string]interface{}{"table": "users"})
+//	defer done()
+func (l Logger) Timed(logLevel LogLevel, str string, data interface{}) func() error {
+	fields := timedFields(data)
+	start := l.now()
+	return func() error {
+		elapsed := l.now().Sub(start)
+		completed := make(map[string]interface{}, len(fields)+1)
+		for key, value := range fields {
+			completed[key] = value
+		}
+		completed[elapsedKey] = float64(elapsed) / float64(time.Millisecond)
+		return l.Log(logLevel, str, completed)
+	}
+}
+
+// StartTimed is like Timed, but also logs `str' at the debug level right
+// away, with the same data.
+func (l Logger) StartTimed(logLevel LogLevel, str string, data interface{}) func() error {
+	done := l.Timed(logLevel, str, data)
+	l.Debug(str, timedFields(data))
+	return done
+}
+
+// timedFields copies data into a map the elapsed time can be added to.
+func timedFields(data interface{}) map[string]interface{} {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return map[string]interface{}{timedValueKey: data}
+	}
+	fields := make(map[string]interface{}, v.Len())
+	iterator := v.MapRange()
+	for iterator.Next() {
+		fields[iterator.Key().String()] = iterator.Value().Interface()
+	}
+	return fields
+}
@@ -0,0 +1,54 @@
+package jsonlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testTimedExample struct {
+	data     interface{}
+	expected string
+}
+
+// TestTimed tests that the completion message carries the elapsed time and
+// the data.
+func TestTimed(t *testing.T) {
+	examples := []testTimedExample{
+		testTimedExample{nil, `"data":{"elapsed_ms":1500.25}`},
+		testTimedExample{map[string]string{"table": "users"}, `"data":{"elapsed_ms":1500.25,"table":"users"}`},
+		testTimedExample{NewData().Set("rows", 3), `"data":{"elapsed_ms":1500.25,"rows":3}`},
+		testTimedExample{[]int{1, 2}, `"data":{"elapsed_ms":1500.25,"value":[1,2]}`},
+	}
+	for _, example := range examples {
+		buffer := new(bytes.Buffer)
+		instant := time.Date(2017, 10, 5, 19, 7, 58, 0, time.UTC)
+		logger := DefaultLogger.WithWriter(buffer).WithClock(func() time.Time { return instant })
+		done := logger.Timed(LogLevelInfo, "db.query", example.data)
+		instant = instant.Add(1500250 * time.Microsecond)
+		if buffer.Len() != 0 {
+			t.Errorf("Nothing should be logged before completion, got '%s'.", buffer.String())
+		}
+		if err := done(); err != nil {
+			t.Errorf("Logging errored with '%s'.", err.Error())
+		} else if !strings.Contains(buffer.String(), example.expected) {
+			t.Errorf("Output '%s' should contain '%s'.", buffer.String(), example.expected)
+		}
+	}
+}
+
+// TestStartTimed tests that the start message is logged at the debug level.
+func TestStartTimed(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithLogLevel(LogLevelDebug)
+	done := logger.StartTimed(LogLevelWarning, "migration", map[string]interface{}{"version": 12})
+	if !strings.HasPrefix(buffer.String(), `{"level":"debug"`) || !strings.Contains(buffer.String(), `"data":{"version":12}`) {
+		t.Errorf("Output '%s' should hold the start message.", buffer.String())
+	}
+	buffer.Reset()
+	done()
+	if !strings.HasPrefix(buffer.String(), `{"level":"warning"`) || !strings.Contains(buffer.String(), `"elapsed_ms":`) {
+		t.Errorf("Output '%s' should hold the completion message.", buffer.String())
+	}
+}
@@ -0,0 +1,106 @@
+package jsonlog
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// durationSuffix is appended to the names of fields holding durations.
+	durationSuffix = "_ms"
+	// byteSizeSuffix is appended to the names of fields holding byte
+	// counts.
+	byteSizeSuffix = "_bytes"
+)
+
+// ByteSize is a number of bytes. Log byte counts with this type and the
+// UnitSuffixes hook to have them consistently named and output.
+type ByteSize int64
+
+// unitRename is a context key UnitSuffixes renames, with its converted value.
+type unitRename struct {
+	key    string
+	newKey string
+	value  interface{}
+}
+
+// UnitSuffixes is a Hook enforcing unit-suffixed field names in data and
+// context values: a time.Duration under "duration" is output as a number of
+// milliseconds under "duration_ms", and a ByteSize under "size" as a number of
+// bytes under "size_bytes". Keys which already have the suffix are kept, and
+// so are values whose suffixed key is already taken, so that none is lost.
+// Nested maps with string keys are processed too; the caller's maps are
+// never modified.
+func UnitSuffixes(m *Message) error {
+	var renames []unitRename
+	for key, value := range m.Context {
+		if newKey, newValue, ok := withUnitSuffix(key, value); ok {
+			if _, taken := m.Context[newKey]; newKey == key || !taken {
+				renames = append(renames, unitRename{key, newKey, newValue})
+			}
+		}
+	}
+	for _, rename := range renames {
+		delete(m.Context, rename.key)
+		m.Context[rename.newKey] = rename.value
+	}
+	if data, ok := applyUnitSuffixes(reflect.ValueOf(m.Data)); ok {
+		m.Data = data
+	}
+	return nil
+}
+
+// applyUnitSuffixes returns a copy of a map with string keys where typed
+// values were converted, or false if nothing needed converting.
+func applyUnitSuffixes(value reflect.Value) (map[string]interface{}, bool) {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Map || value.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	converted := make(map[string]interface{}, value.Len())
+	changed := false
+	iterator := value.MapRange()
+	for iterator.Next() {
+		key := iterator.Key().String()
+		element := iterator.Value().Interface()
+		if newKey, newValue, ok := withUnitSuffix(key, element); ok && (newKey == key || !hasMapKey(value, newKey)) {
+			converted[newKey] = newValue
+			changed = true
+		} else if nested, ok := applyUnitSuffixes(iterator.Value()); ok {
+			converted[key] = nested
+			changed = true
+		} else {
+			converted[key] = element
+		}
+	}
+	return converted, changed
+}
+
+// hasMapKey tells whether a map with string keys holds `key'.
+func hasMapKey(value reflect.Value, key string) bool {
+	return value.MapIndex(reflect.ValueOf(key).Convert(value.Type().Key())).IsValid()
+}
+
+// withUnitSuffix converts a typed value to a number and suffixes its key with
+// the unit. The boolean is false if the value has no known unit.
+func withUnitSuffix(key string, value interface{}) (string, interface{}, bool) {
+	switch v := value.(type) {
+	case time.Duration:
+		return suffixed(key, durationSuffix), float64(v) / float64(time.Millisecond), true
+	case ByteSize:
+		return suffixed(key, byteSizeSuffix), int64(v), true
+	default:
+		return key, value, false
+	}
+}
+
+// suffixed appends a suffix to a key unless it already ends with it.
+func suffixed(key, suffix string) string {
+	if strings.HasSuffix(key, suffix) {
+		return key
+	}
+	return key + suffix
+}
@@ -0,0 +1,73 @@
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestUnitSuffixes tests that durations and byte sizes are converted and
+// their keys suffixed, including in nested maps.
+func TestUnitSuffixes(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := DefaultLogger.WithWriter(buffer).WithHook(UnitSuffixes)
+	data := map[string]interface{}{
+		"duration":   1500 * time.Microsecond,
+		"elapsed_ms": 2 * time.Second,
+		"upload": map[string]interface{}{
+			"size": ByteSize(2048),
+		},
+		"name": "report.pdf",
+	}
+	logger.Info("uploaded", data)
+	if _, ok := data["duration"].(time.Duration); !ok {
+		t.Errorf("The caller's data should not be modified.")
+	}
+	output := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	err := json.Unmarshal(buffer.Bytes(), &output)
+	if err != nil {
+		t.Errorf("Parsing output JSON errored with '%s'.", err.Error())
+		return
+	}
+	expected := map[string]interface{}{
+		"duration_ms": 1.5,
+		"elapsed_ms":  float64(2000),
+		"name":        "report.pdf",
+	}
+	for k, v := range expected {
+		if output.Data[k] != v {
+			t.Errorf("Output data '%s' is %v but should be %v.", k, output.Data[k], v)
+		}
+	}
+	upload, _ := output.Data["upload"].(map[string]interface{})
+	if upload["size_bytes"] != float64(2048) {
+		t.Errorf("Nested output data %v should hold size_bytes.", upload)
+	}
+}
+
+// TestUnitSuffixesTakenKey tests that a value whose suffixed key is already
+// taken is kept under its own key instead of overwriting the other one.
+func TestUnitSuffixesTakenKey(t *testing.T) {
+	m := Message{
+		Context: map[string]interface{}{
+			"duration":    2 * time.Second,
+			"duration_ms": float64(5),
+		},
+		Data: map[string]interface{}{
+			"size":       ByteSize(10),
+			"size_bytes": int64(20),
+		},
+	}
+	if err := UnitSuffixes(&m); err != nil {
+		t.Errorf("UnitSuffixes errored with '%s'.", err.Error())
+	}
+	if m.Context["duration_ms"] != float64(5) || m.Context["duration"] != 2*time.Second {
+		t.Errorf("Context %v should keep both durations.", m.Context)
+	}
+	if data, ok := m.Data.(map[string]interface{}); ok && (data["size_bytes"] != int64(20) || data["size"] != ByteSize(10)) {
+		t.Errorf("Data %v should keep both sizes.", data)
+	}
+}
@@ -0,0 +1,197 @@
+package jsonlog
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+// UnixWriter sends records to a UnixReceiver over a Unix socket, so that
+// short-lived processes can hand their logs to a long-lived agent which
+// delivers them. The connection is reestablished once if a write fails.
+//
+// A UnixWriter is safe for concurrent use.
+type UnixWriter struct {
+	path  string
+	mutex sync.Mutex
+	conn  net.Conn
+}
+
+// UnixReceiver listens on a Unix socket for the records of other local
+// processes, sent with UnixWriter, and forwards them through a Logger as
+// PassThroughWriter does: records keep their level, time and fields, and go
+// through the Logger's hooks, formatter and writer. Lines which are not
+// records are logged as info messages.
+type UnixReceiver struct {
+	logger   Logger
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewUnixWriter connects to the UnixReceiver listening at `path'.
+func NewUnixWriter(path string) (*UnixWriter, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &UnixWriter{
+		path: path,
+		conn: conn,
+	}, nil
+}
+
+// Write sends one or more records.
+func (w *UnixWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn != nil {
+		if n, err := w.conn.Write(p); err == nil {
+			return n, nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	conn, err := net.Dial("unix", w.path)
+	if err != nil {
+		return 0, err
+	}
+	w.conn = conn
+	return w.conn.Write(p)
+}
+
+// ReadFrom sends the records read from `r' over the connection, which lets
+// Replay copy an archive to the socket without going through user space. The
+// connection is reestablished beforehand if it was closed, but not during
+// the copy, since the receiver may have got part of the data.
+func (w *UnixWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn == nil {
+		conn, err := net.Dial("unix", w.path)
+		if err != nil {
+			return 0, err
+		}
+		w.conn = conn
+	}
+	n, err := io.Copy(w.conn, r)
+	if err != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	return n, err
+}
+
+// Ping checks the connection to the receiver, reconnecting if it was closed.
+func (w *UnixWriter) Ping() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn != nil {
+		if connAlive(w.conn) {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	conn, err := net.Dial("unix", w.path)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Close closes the connection to the receiver.
+func (w *UnixWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// NewUnixReceiver creates a UnixReceiver listening at `path' and forwarding
+// records through `logger'. The socket file must not exist; it is removed
+// when the receiver is closed. Call Serve to start receiving.
+func NewUnixReceiver(path string, logger Logger) (*UnixReceiver, error) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &UnixReceiver{
+		logger:   logger,
+		listener: listener,
+		conns:    map[net.Conn]struct{}{},
+	}, nil
+}
+
+// Serve accepts connections and forwards their records until the receiver is
+// closed, in which case it returns nil.
+func (r *UnixReceiver) Serve() error {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			r.mutex.Lock()
+			closed := r.closed
+			r.mutex.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		if !r.track(conn) {
+			conn.Close()
+			return nil
+		}
+		r.wg.Add(1)
+		go r.receive(conn)
+	}
+}
+
+// Close stops accepting connections, closes the open ones and waits for
+// their received records to be forwarded.
+func (r *UnixReceiver) Close() error {
+	r.mutex.Lock()
+	r.closed = true
+	err := r.listener.Close()
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.mutex.Unlock()
+	r.wg.Wait()
+	return err
+}
+
+// track registers an open connection, unless the receiver is closed.
+func (r *UnixReceiver) track(conn net.Conn) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return false
+	}
+	r.conns[conn] = struct{}{}
+	return true
+}
+
+// receive forwards the records of a connection until it is closed.
+func (r *UnixReceiver) receive(conn net.Conn) {
+	defer r.wg.Done()
+	forwardRecords(r.logger, conn)
+	r.mutex.Lock()
+	delete(r.conns, conn)
+	r.mutex.Unlock()
+	conn.Close()
+}
+
+// forwardRecords forwards the records read from `r' through `logger' until
+// the end of the input, as receivers do.
+func forwardRecords(logger Logger, r io.Reader) {
+	w := logger.PassThroughWriter(LogLevelInfo)
+	io.Copy(w, r)
+	w.Close()
+}
@@ -0,0 +1,70 @@
+package jsonlog
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestUnixReceiver tests forwarding records from a short-lived logger to a
+// receiver.
+func TestUnixReceiver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jsonlog.sock")
+	output := &lockedBuffer{}
+	receiver, err := NewUnixReceiver(path, DefaultLogger.WithWriter(output).Named("agent"))
+	if err != nil {
+		t.Errorf("Listening errored with '%s'.", err.Error())
+		return
+	}
+	served := make(chan error)
+	go func() { served <- receiver.Serve() }()
+	w, err := NewUnixWriter(path)
+	if err != nil {
+		t.Errorf("Connecting errored with '%s'.", err.Error())
+		return
+	}
+	cli := DefaultLogger.WithWriter(w).Named("cli")
+	cli.Warning("Quota almost reached.", map[string]interface{}{"used": 95})
+	w.Write([]byte("not a record\n"))
+	w.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Count(output.String(), "\n") < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := receiver.Close(); err != nil {
+		t.Errorf("Closing errored with '%s'.", err.Error())
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serving errored with '%s'.", err.Error())
+	}
+	for _, expected := range []string{
+		`"level":"warning","time":`,
+		`"message":"Quota almost reached.","logger":"agent.cli","data":{"used":95}`,
+		`"message":"not a record","logger":"agent"`,
+	} {
+		if !strings.Contains(output.String(), expected) {
+			t.Errorf("Output '%s' should contain '%s'.", output.String(), expected)
+		}
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
@@ -0,0 +1,310 @@
+package jsonlog
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// maxWalkDepth is the deepest nesting the walker converts. Deeper values are
+// most likely cyclic, and would otherwise overflow the stack.
+const maxWalkDepth = 1000
+
+// errWalkTooDeep is the error of the conversion of a value nested more than
+// maxWalkDepth levels deep.
+var errWalkTooDeep = errors.New("jsonlog: value too deeply nested or cyclic")
+
+// walker converts arbitrary values into trees made only of
+// map[string]interface{}, []interface{} and scalars, following the rules of
+// encoding/json. It lets the formatters recover values encoding/json rejects.
+type walker struct {
+	// float replaces the floats which are not finite.
+	float func(f float64) interface{}
+	// depth is the nesting of the value being converted.
+	depth int
+}
+
+// walk returns the converted copy of `value'. The value is never modified.
+// It fails with errWalkTooDeep on cyclic values.
+func (w walker) walk(value interface{}) (interface{}, error) {
+	var converted interface{}
+	err := guardWalk(func() { converted = w.walkValue(reflect.ValueOf(value)) })
+	return converted, err
+}
+
+// guardWalk runs a conversion, turning the panic raised by walkValue on too
+// deep a value into errWalkTooDeep.
+func guardWalk(convert func()) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if recovered != errWalkTooDeep {
+				panic(recovered)
+			}
+			err = errWalkTooDeep
+		}
+	}()
+	convert()
+	return nil
+}
+
+// walkValue converts a reflected value. It panics with errWalkTooDeep past
+// maxWalkDepth levels, which guardWalk recovers.
+func (w walker) walkValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	w.depth++
+	if w.depth > maxWalkDepth {
+		panic(errWalkTooDeep)
+	}
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return w.walkValue(v.Elem())
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return w.float(f)
+		}
+		return v.Interface()
+	case reflect.Map:
+		return w.walkMap(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		return w.walkList(v)
+	case reflect.Array:
+		return w.walkList(v)
+	case reflect.Struct:
+		object := map[string]interface{}{}
+		w.walkStruct(v, object)
+		return object
+	default:
+		return v.Interface()
+	}
+}
+
+// walkMap converts a map. Keys which encoding/json cannot use are converted
+// with formatMapKey.
+func (w walker) walkMap(v reflect.Value) interface{} {
+	if v.IsNil() {
+		return nil
+	}
+	object := make(map[string]interface{}, v.Len())
+	iterator := v.MapRange()
+	for iterator.Next() {
+		key, ok := mapKeyString(iterator.Key())
+		if !ok {
+			key = formatMapKey(iterator.Key())
+		}
+		object[key] = w.walkValue(iterator.Value())
+	}
+	return object
+}
+
+// walkList converts a slice or an array.
+func (w walker) walkList(v reflect.Value) []interface{} {
+	list := make([]interface{}, v.Len())
+	for i := range list {
+		list[i] = w.walkValue(v.Index(i))
+	}
+	return list
+}
+
+// walkStruct sets the fields of a struct in `object', honoring the json
+// tags. Fields of embedded structs are promoted unless a field of the outer
+// struct has the same name.
+func (w walker) walkStruct(v reflect.Value, object map[string]interface{}) {
+	var embedded []reflect.Value
+	for _, field := range cachedStructFields(v.Type()) {
+		value := v.Field(field.index)
+		if field.embedded {
+			if value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			embedded = append(embedded, value)
+			continue
+		}
+		if field.omitEmpty && isEmptyValue(value) {
+			continue
+		}
+		object[field.name] = w.walkValue(value)
+	}
+	for _, value := range embedded {
+		promoted := map[string]interface{}{}
+		w.walkStruct(value, promoted)
+		for name, fieldValue := range promoted {
+			if _, ok := object[name]; !ok {
+				object[name] = fieldValue
+			}
+		}
+	}
+}
+
+// structField is the layout of an output struct field.
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+	// embedded is true for embedded structs whose fields are promoted.
+	embedded bool
+}
+
+// structFieldsCache maps struct types to their []structField. Each
+// instantiation of a generic type is a distinct reflect.Type, so entries
+// never need invalidating.
+var structFieldsCache sync.Map
+
+// cachedStructFields returns the output fields of a struct type, computing
+// them on first use.
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields, _ := structFieldsCache.LoadOrStore(t, structFields(t))
+	return fields.([]structField)
+}
+
+// structFields computes the output fields of a struct type.
+func structFields(t reflect.Type) []structField {
+	fields := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, omitEmpty, ok := jsonFieldName(t.Field(i))
+		if ok {
+			fields = append(fields, structField{
+				index:     i,
+				name:      name,
+				omitEmpty: omitEmpty,
+				embedded:  name == "",
+			})
+		}
+	}
+	return fields
+}
+
+// jsonFieldName returns the key of a struct field as encoding/json would
+// output it, and whether the field is output at all. The name is empty for
+// embedded structs whose fields are promoted.
+func jsonFieldName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, options := tag, ""
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		name, options = tag[:i], tag[i+1:]
+	}
+	omitEmpty := false
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	if field.Anonymous && name == "" {
+		t := field.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			return "", omitEmpty, true
+		}
+	}
+	if field.PkgPath != "" {
+		return "", false, false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, omitEmpty, true
+}
+
+// mapKeyString converts a map key to a string as encoding/json does.
+func mapKeyString(key reflect.Value) (string, bool) {
+	if key.Kind() == reflect.String {
+		return key.String(), true
+	}
+	if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), err == nil
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), true
+	default:
+		return "", false
+	}
+}
+
+// formatMapKey converts a map key encoding/json rejects to a string: keys of
+// interface type are converted as their dynamic value would be, nil ones
+// becoming "null", floats are formatted like strconv.FormatFloat with the
+// 'g' format, booleans as "true" or "false", and other keys, such as
+// structs, with fmt.Sprint, which uses their String method if they have one.
+// Distinct keys may give the same string, in which case one of their values
+// is kept.
+func formatMapKey(key reflect.Value) string {
+	if key.Kind() == reflect.Interface {
+		if key.IsNil() {
+			return "null"
+		}
+		key = key.Elem()
+		if s, ok := mapKeyString(key); ok {
+			return s
+		}
+	}
+	switch key.Kind() {
+	case reflect.Float32:
+		return strconv.FormatFloat(key.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(key.Float(), 'g', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(key.Bool())
+	default:
+		return fmt.Sprint(key.Interface())
+	}
+}
+
+// isEmptyValue tells whether a value is omitted by the omitempty option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,141 @@
+package jsonlog
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testWalkInner struct {
+	ID    int    `json:"id"`
+	Shown string `json:"shown"`
+}
+
+type testWalkOuter struct {
+	testWalkInner
+	*testWalkPointer
+	Shown   string            `json:"shown"`
+	Skipped string            `json:"-"`
+	Empty   []int             `json:"empty,omitempty"`
+	Renamed map[int]float64   `json:"renamed"`
+	Bytes   []byte            `json:"bytes"`
+	When    time.Time         `json:"when"`
+	Nested  *testWalkInner    `json:"nested"`
+	Array   [2]bool           `json:"array"`
+	Plain   map[string]string `json:",omitempty"`
+	private int
+}
+
+type testWalkPointer struct {
+	Extra string `json:"extra"`
+}
+
+type testWalkGeneric[T any] struct {
+	Value T `json:"value"`
+	Other T `json:"other,omitempty"`
+}
+
+// TestWalker tests that the walker converts values the way encoding/json
+// sees them. Key order aside, the output must be the same.
+func TestWalker(t *testing.T) {
+	examples := []interface{}{
+		nil,
+		42,
+		"text",
+		[]interface{}{1, "two", nil},
+		testWalkGeneric[int]{Value: 1},
+		testWalkGeneric[string]{Value: "one", Other: "two"},
+		[]testWalkGeneric[bool]{{Value: true}, {Other: true}},
+		testWalkOuter{
+			testWalkInner:   testWalkInner{ID: 1, Shown: "inner"},
+			testWalkPointer: &testWalkPointer{Extra: "promoted"},
+			Shown:           "outer",
+			Skipped:         "skipped",
+			Renamed:         map[int]float64{3: 1.5},
+			Bytes:           []byte("raw"),
+			When:            time.Date(2017, 10, 5, 0, 0, 0, 0, time.UTC),
+			Array:           [2]bool{true, false},
+			private:         1,
+		},
+	}
+	for _, example := range examples {
+		var expected, got interface{}
+		encoded, _ := json.Marshal(example)
+		json.Unmarshal(encoded, &expected)
+		converted, err := walker{}.walk(example)
+		if err != nil {
+			t.Errorf("Walking errored with '%s'.", err.Error())
+			continue
+		}
+		walked, err := json.Marshal(converted)
+		if err != nil {
+			t.Errorf("Encoding errored with '%s'.", err.Error())
+			continue
+		}
+		json.Unmarshal(walked, &got)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Walking gave %s but should give %s.", walked, encoded)
+		}
+	}
+}
+
+type testMapKey struct {
+	X, Y int
+}
+
+// TestFormatMapKey tests the conversion of the map keys encoding/json
+// rejects.
+func TestFormatMapKey(t *testing.T) {
+	examples := map[string]interface{}{
+		"1.5":   1.5,
+		"0.1":   float32(0.1),
+		"1e+21": 1e21,
+		"true":  true,
+		"{1 2}": testMapKey{1, 2},
+		"[1 2]": [2]int{1, 2},
+	}
+	for expected, key := range examples {
+		if actual := formatMapKey(reflect.ValueOf(key)); actual != expected {
+			t.Errorf("Key %#v is formatted as '%s' but should be '%s'.", key, actual, expected)
+		}
+	}
+	keys := map[interface{}]int{nil: 0, 7: 1, "name": 2, 2.5: 3}
+	converted, _ := walker{}.walk(keys)
+	walked, err := json.Marshal(converted)
+	if err != nil {
+		t.Errorf("Marshaling errored with '%s'.", err.Error())
+	} else if expected := `{"2.5":3,"7":1,"name":2,"null":0}`; string(walked) != expected {
+		t.Errorf("Map %v is walked as %s but should be %s.", keys, walked, expected)
+	}
+}
+
+type testWalkCycle struct {
+	Ratio float64        `json:"ratio"`
+	Next  *testWalkCycle `json:"next"`
+}
+
+// TestWalkerCycle tests that cyclic values make the walker fail instead of
+// overflowing the stack, and that logging them fails with the error of
+// encoding/json whatever the float policy.
+func TestWalkerCycle(t *testing.T) {
+	cycle := &testWalkCycle{Ratio: math.NaN()}
+	cycle.Next = cycle
+	if _, err := (walker{float: FloatNull.replace}).walk(cycle); err != errWalkTooDeep {
+		t.Errorf("Walking a cyclic value should fail with errWalkTooDeep, got %v.", err)
+	}
+	for _, policy := range []FloatPolicy{FloatError, FloatNull} {
+		err := DefaultLogger.WithWriter(io.Discard).WithFloatPolicy(policy).Info("cycle", cycle)
+		if err == nil || err == errWalkTooDeep {
+			t.Errorf("Logging a cyclic value should fail with the error of encoding/json, got %v.", err)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("WithFieldsFromStruct should panic on a cyclic struct.")
+		}
+	}()
+	DefaultLogger.WithFieldsFromStruct(cycle)
+}
@@ -0,0 +1,81 @@
+package jsonlog
+
+import (
+	"bytes"
+	"io"
+)
+
+// LevelWriter is implemented by writers which need to know the log level of
+// the records written to them, such as SyslogWriter. A Logger whose writer
+// implements LevelWriter calls WriteLevel instead of Write.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(logLevel LogLevel, p []byte) (int, error)
+}
+
+// RecordWriter is implemented by sinks which handle records one by one, such
+// as GELFWriter. A Logger whose writer implements RecordWriter, and not
+// LevelWriter, calls WriteRecord instead of Write, with exactly one record
+// and without its trailing newline, so that the sink need not split or trim
+// what it receives. Replay does the same. The record must not be retained
+// after WriteRecord returns.
+type RecordWriter interface {
+	io.Writer
+	WriteRecord(record []byte) error
+}
+
+// writeRecord writes a single formatted record to `w'.
+func writeRecord(w io.Writer, logLevel LogLevel, record []byte) error {
+	var err error
+	if levelWriter, ok := w.(LevelWriter); ok {
+		_, err = levelWriter.WriteLevel(logLevel, record)
+	} else if recordWriter, ok := w.(RecordWriter); ok {
+		err = recordWriter.WriteRecord(bytes.TrimSuffix(record, []byte("\n")))
+	} else {
+		_, err = w.Write(record)
+	}
+	return err
+}
+
+// BatchWriter is implemented by sinks which handle batches of records, such
+// as PipelinedSink and HTTPSink. A BatchingSink or a PipelinedSink whose
+// writer implements BatchWriter calls WriteBatch instead of Write, with the
+// records of a batch without their trailing newline, so that the sink need
+// not split what it receives. The records must not be retained after
+// WriteBatch returns.
+type BatchWriter interface {
+	io.Writer
+	WriteBatch(records [][]byte) error
+}
+
+// writeBatch writes a batch of records, without their newline, to `w': with
+// WriteBatch for a BatchWriter, with WriteRecord for each record for a
+// RecordWriter, and as a single Write of newline-delimited records otherwise.
+func writeBatch(w io.Writer, records [][]byte) error {
+	if batchWriter, ok := w.(BatchWriter); ok {
+		return batchWriter.WriteBatch(records)
+	}
+	if recordWriter, ok := w.(RecordWriter); ok {
+		for _, record := range records {
+			if err := recordWriter.WriteRecord(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	_, err := w.Write(joinRecords(records))
+	return err
+}
+
+// joinRecords returns the records as newline-delimited JSON.
+func joinRecords(records [][]byte) []byte {
+	size := 0
+	for _, record := range records {
+		size += len(record) + 1
+	}
+	batch := make([]byte, 0, size)
+	for _, record := range records {
+		batch = append(append(batch, record...), '\n')
+	}
+	return batch
+}
@@ -0,0 +1,46 @@
+package jsonlog
+
+import (
+	"strings"
+	"testing"
+)
+
+// testRecordWriter keeps what it receives through each method.
+type testRecordWriter struct {
+	writes  []string
+	records []string
+}
+
+func (w *testRecordWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func (w *testRecordWriter) WriteRecord(record []byte) error {
+	w.records = append(w.records, string(record))
+	return nil
+}
+
+// TestRecordWriter tests that Loggers and Replay pass single records without
+// their newline to RecordWriters.
+func TestRecordWriter(t *testing.T) {
+	w := &testRecordWriter{}
+	logger := DefaultLogger.WithWriter(w)
+	logger.Info("first", nil)
+	logger.Info("second", nil)
+	if len(w.writes) != 0 || len(w.records) != 2 {
+		t.Fatalf("There should be 2 records and no writes, got %v and %v.", w.records, w.writes)
+	}
+	for _, record := range w.records {
+		if strings.Contains(record, "\n") || !strings.HasPrefix(record, "{") || !strings.HasSuffix(record, "}") {
+			t.Errorf("Record '%s' should be a single object without newline.", record)
+		}
+	}
+	w = &testRecordWriter{}
+	if _, err := Replay(w, strings.NewReader("{\"a\":1}\n{\"b\":2}\n")); err != nil {
+		t.Errorf("Replaying errored with '%s'.", err.Error())
+	}
+	if len(w.writes) != 0 || strings.Join(w.records, "|") != `{"a":1}|{"b":2}` {
+		t.Errorf("Replay should have written 2 records, got %v and %v.", w.records, w.writes)
+	}
+}
@@ -0,0 +1,260 @@
+// Package zap is a subset of the API of the SugaredLogger of go.uber.org/zap
+// backed by jsonlog, so that code using zap's sugared logger can move to
+// jsonlog by changing its imports, and be rewritten to the jsonlog API at its
+// own pace. The key-value pairs of the w methods and of With are output as
+// the data of the jsonlog messages, except an error under the "error" key,
+// which becomes their error.
+//
+// Levels, encoders and sinks are configured through the underlying
+// jsonlog.Logger. Strongly typed zap fields are not supported.
+package zap
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/trackit/jsonlog"
+)
+
+// errorKey is the key of the value which becomes the error of messages.
+const errorKey = "error"
+
+// SugaredLogger logs through a jsonlog.Logger with the API of zap's
+// SugaredLogger. DPanic messages are logged with the error level and never
+// panic.
+//
+// A SugaredLogger is immutable and safe for concurrent use.
+type SugaredLogger struct {
+	logger jsonlog.Logger
+	// pairs are the key-value pairs added with With.
+	pairs []interface{}
+}
+
+var (
+	// globalsMutex guards global.
+	globalsMutex sync.RWMutex
+	// global is the SugaredLogger returned by S.
+	global = NewSugaredLogger(jsonlog.DefaultLogger)
+)
+
+// NewSugaredLogger creates a SugaredLogger writing through `logger'.
+func NewSugaredLogger(logger jsonlog.Logger) *SugaredLogger {
+	return &SugaredLogger{logger: logger}
+}
+
+// S returns the global SugaredLogger, which writes through
+// jsonlog.DefaultLogger unless replaced with ReplaceGlobals.
+func S() *SugaredLogger {
+	globalsMutex.RLock()
+	defer globalsMutex.RUnlock()
+	return global
+}
+
+// ReplaceGlobals replaces the global SugaredLogger and returns a function
+// restoring the previous one.
+func ReplaceGlobals(logger *SugaredLogger) func() {
+	globalsMutex.Lock()
+	defer globalsMutex.Unlock()
+	previous := global
+	global = logger
+	return func() { ReplaceGlobals(previous) }
+}
+
+// JSONLogger returns the underlying jsonlog.Logger.
+func (s *SugaredLogger) JSONLogger() jsonlog.Logger {
+	return s.logger
+}
+
+// With returns a new SugaredLogger adding key-value pairs to all its
+// messages.
+func (s *SugaredLogger) With(keysAndValues ...interface{}) *SugaredLogger {
+	pairs := make([]interface{}, 0, len(s.pairs)+len(keysAndValues))
+	pairs = append(append(pairs, s.pairs...), keysAndValues...)
+	return &SugaredLogger{logger: s.logger, pairs: pairs}
+}
+
+// Named returns a new SugaredLogger with a name nested under the current one,
+// as with jsonlog.Logger.Named.
+func (s *SugaredLogger) Named(name string) *SugaredLogger {
+	return &SugaredLogger{logger: s.logger.Named(name), pairs: s.pairs}
+}
+
+// Sync flushes the writer of the underlying jsonlog.Logger.
+func (s *SugaredLogger) Sync() error {
+	return s.logger.Sync()
+}
+
+// log outputs a message with the pairs of the SugaredLogger and
+// `keysAndValues'. Keys which are not strings are formatted with fmt.Sprint
+// and a trailing key without a value gets a nil value.
+func (s *SugaredLogger) log(logLevel jsonlog.LogLevel, message string, keysAndValues []interface{}) {
+	logger := s.logger
+	var data map[string]interface{}
+	for _, pairs := range [][]interface{}{s.pairs, keysAndValues} {
+		for i := 0; i < len(pairs); i += 2 {
+			key, ok := pairs[i].(string)
+			if !ok {
+				key = fmt.Sprint(pairs[i])
+			}
+			var value interface{}
+			if i+1 < len(pairs) {
+				value = pairs[i+1]
+			}
+			if err, ok := value.(error); ok && key == errorKey {
+				logger = logger.WithError(err)
+				continue
+			}
+			if data == nil {
+				data = make(map[string]interface{}, (len(s.pairs)+len(keysAndValues))/2)
+			}
+			data[key] = value
+		}
+	}
+	var dataArg interface{}
+	if data != nil {
+		dataArg = data
+	}
+	switch logLevel {
+	case jsonlog.LogLevelPanic:
+		logger.Panic(message, dataArg)
+	case jsonlog.LogLevelFatal:
+		logger.Fatal(message, dataArg)
+	default:
+		logger.Log(logLevel, message, dataArg)
+	}
+}
+
+// sprintln formats its arguments as fmt.Sprintln does, without the newline.
+func sprintln(args []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// Debug logs a message at the debug level, formatted with fmt.Sprint.
+func (s *SugaredLogger) Debug(args ...interface{}) {
+	s.log(jsonlog.LogLevelDebug, fmt.Sprint(args...), nil)
+}
+
+// Info logs a message at the info level, formatted with fmt.Sprint.
+func (s *SugaredLogger) Info(args ...interface{}) {
+	s.log(jsonlog.LogLevelInfo, fmt.Sprint(args...), nil)
+}
+
+// Warn logs a message at the warning level, formatted with fmt.Sprint.
+func (s *SugaredLogger) Warn(args ...interface{}) {
+	s.log(jsonlog.LogLevelWarning, fmt.Sprint(args...), nil)
+}
+
+// Error logs a message at the error level, formatted with fmt.Sprint.
+func (s *SugaredLogger) Error(args ...interface{}) {
+	s.log(jsonlog.LogLevelError, fmt.Sprint(args...), nil)
+}
+
+// DPanic logs a message at the error level, formatted with fmt.Sprint.
+func (s *SugaredLogger) DPanic(args ...interface{}) {
+	s.log(jsonlog.LogLevelError, fmt.Sprint(args...), nil)
+}
+
+// Panic logs a message at the panic level, formatted with fmt.Sprint, then
+// panics.
+func (s *SugaredLogger) Panic(args ...interface{}) {
+	s.log(jsonlog.LogLevelPanic, fmt.Sprint(args...), nil)
+}
+
+// Fatal logs a message at the fatal level, formatted with fmt.Sprint, then
+// exits the process as configured by the underlying jsonlog.Logger.
+func (s *SugaredLogger) Fatal(args ...interface{}) {
+	s.log(jsonlog.LogLevelFatal, fmt.Sprint(args...), nil)
+}
+
+// Debugf logs a message at the debug level, formatted with fmt.Sprintf.
+func (s *SugaredLogger) Debugf(template string, args ...interface{}) {
+	s.log(jsonlog.LogLevelDebug, fmt.Sprintf(template, args...), nil)
+}
+
+// Infof logs a message at the info level, formatted with fmt.Sprintf.
+func (s *SugaredLogger) Infof(template string, args ...interface{}) {
+	s.log(jsonlog.LogLevelInfo, fmt.Sprintf(template, args...), nil)
+}
+
+// Warnf logs a message at the warning level, formatted with fmt.Sprintf.
+func (s *SugaredLogger) Warnf(template string, args ...interface{}) {
+	s.log(jsonlog.LogLevelWarning, fmt.Sprintf(template, args...), nil)
+}
+
+// Errorf logs a message at the error level, formatted with fmt.Sprintf.
+func (s *SugaredLogger) Errorf(template string, args ...interface{}) {
+	s.log(jsonlog.LogLevelError, fmt.Sprintf(template, args...), nil)
+}
+
+// DPanicf logs a message at the error level, formatted with fmt.Sprintf.
+func (s *SugaredLogger) DPanicf(template string, args ...interface{}) {
+	s.log(jsonlog.LogLevelError, fmt.Sprintf(template, args...), nil)
+}
+
+// Panicf logs a message at the panic level, formatted with fmt.Sprintf, then
+// panics.
+func (s *SugaredLogger) Panicf(template string, args ...interface{}) {
+	s.log(jsonlog.LogLevelPanic, fmt.Sprintf(template, args...), nil)
+}
+
+// Fatalf logs a message at the fatal level, formatted with fmt.Sprintf, then
+// exits.
+func (s *SugaredLogger) Fatalf(template string, args ...interface{}) {
+	s.log(jsonlog.LogLevelFatal, fmt.Sprintf(template, args...), nil)
+}
+
+// Debugw logs a message at the debug level with key-value pairs.
+func (s *SugaredLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	s.log(jsonlog.LogLevelDebug, msg, keysAndValues)
+}
+
+// Infow logs a message at the info level with key-value pairs.
+func (s *SugaredLogger) Infow(msg string, keysAndValues ...interface{}) {
+	s.log(jsonlog.LogLevelInfo, msg, keysAndValues)
+}
+
+// Warnw logs a message at the warning level with key-value pairs.
+func (s *SugaredLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	s.log(jsonlog.LogLevelWarning, msg, keysAndValues)
+}
+
+// Errorw logs a message at the error level with key-value pairs.
+func (s *SugaredLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	s.log(jsonlog.LogLevelError, msg, keysAndValues)
+}
+
+// DPanicw logs a message at the error level with key-value pairs.
+func (s *SugaredLogger) DPanicw(msg string, keysAndValues ...interface{}) {
+	s.log(jsonlog.LogLevelError, msg, keysAndValues)
+}
+
+// Panicw logs a message at the panic level with key-value pairs, then panics.
+func (s *SugaredLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	s.log(jsonlog.LogLevelPanic, msg, keysAndValues)
+}
+
+// Fatalw logs a message at the fatal level with key-value pairs, then exits.
+func (s *SugaredLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	s.log(jsonlog.LogLevelFatal, msg, keysAndValues)
+}
+
+// Debugln logs a message at the debug level, formatted with fmt.Sprintln.
+func (s *SugaredLogger) Debugln(args ...interface{}) {
+	s.log(jsonlog.LogLevelDebug, sprintln(args), nil)
+}
+
+// Infoln logs a message at the info level, formatted with fmt.Sprintln.
+func (s *SugaredLogger) Infoln(args ...interface{}) {
+	s.log(jsonlog.LogLevelInfo, sprintln(args), nil)
+}
+
+// Warnln logs a message at the warning level, formatted with fmt.Sprintln.
+func (s *SugaredLogger) Warnln(args ...interface{}) {
+	s.log(jsonlog.LogLevelWarning, sprintln(args), nil)
+}
+
+// Errorln logs a message at the error level, formatted with fmt.Sprintln.
+func (s *SugaredLogger) Errorln(args ...interface{}) {
+	s.log(jsonlog.LogLevelError, sprintln(args), nil)
+}
@@ -0,0 +1,54 @@
+package zap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/trackit/jsonlog"
+	"github.com/trackit/jsonlog/jsonlogtest"
+)
+
+// TestSugaredLogger tests that key-value pairs are output as jsonlog data and
+// errors.
+func TestSugaredLogger(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	logger := NewSugaredLogger(sink.Logger()).Named("billing").With("user", 42)
+	logger.Errorw("charge failed", "attempt", 2, "error", errors.New("timeout"))
+	logger.Infof("charged %d cents", 1250)
+	logger.Warnw("dangling", 7, "seven", "orphan")
+	sink.AssertLogged(t, jsonlog.LogLevelError, "charge failed",
+		jsonlogtest.Named("billing"),
+		jsonlogtest.FieldEquals("data.user", 42),
+		jsonlogtest.FieldEquals("data.attempt", 2),
+		jsonlogtest.FieldEquals("error.message", "timeout"))
+	sink.AssertLogged(t, jsonlog.LogLevelInfo, "charged 1250 cents", jsonlogtest.FieldEquals("data.user", 42))
+	sink.AssertLogged(t, jsonlog.LogLevelWarning, "dangling",
+		jsonlogtest.FieldEquals("data.7", "seven"),
+		jsonlogtest.FieldEquals("data.orphan", nil))
+}
+
+// TestReplaceGlobals tests that the global SugaredLogger can be replaced and
+// restored.
+func TestReplaceGlobals(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	previous := S()
+	restore := ReplaceGlobals(NewSugaredLogger(sink.Logger()))
+	S().Debugln("global", "logger")
+	restore()
+	sink.AssertLogged(t, jsonlog.LogLevelDebug, "global logger")
+	if S() != previous {
+		t.Errorf("The global SugaredLogger was not restored.")
+	}
+}
+
+// TestCaller tests that the caller starts at the code using the adapter.
+func TestCaller(t *testing.T) {
+	sink := jsonlogtest.NewSink()
+	NewSugaredLogger(sink.Logger().WithCaller(true)).Infow("charged", "cents", 1250)
+	entry := sink.AssertLogged(t, jsonlog.LogLevelInfo, "charged")
+	if caller, _ := entry.Field("context.caller"); !strings.HasPrefix(fmt.Sprint(caller), "zap/sugar_test.go:") {
+		t.Errorf("Caller '%v' should be in the test.", caller)
+	}
+}